@@ -9,15 +9,13 @@ import (
 	"path"
 	"reflect"
 	"regexp"
-	"strings"
 
-	"github.com/creasty/defaults"
 	"github.com/sirupsen/logrus"
 
 	"github.com/forta-network/forta-node/config"
 	"gopkg.in/yaml.v3"
 
-	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-multierror"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 )
@@ -227,7 +225,7 @@ func initConfig() {
 		logrus.WithError(err).Fatal("failed to read config")
 	}
 
-	if err := defaults.Set(&cfg); err != nil {
+	if err := config.ApplyDefaults(&cfg); err != nil {
 		panic(err)
 	}
 
@@ -255,27 +253,20 @@ func resolveEnvVarsHookFunc(from reflect.Kind, to reflect.Kind, data interface{}
 }
 
 func validateConfig() error {
-	validate := validator.New()
-
-	// Use the YAML names while validating the struct.
-	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
-		name := strings.SplitN(fld.Tag.Get("yaml"), ",", 2)[0]
-		if name == "-" {
-			return ""
-		}
-		return name
-	})
-
-	if err := validate.Struct(&cfg); err != nil {
-		validationErrs := err.(validator.ValidationErrors)
-		fmt.Fprintln(os.Stderr, "The config file has invalid or missing fields:")
-		for _, validationErr := range validationErrs {
-			fmt.Fprintf(os.Stderr, "  - %s\n", validationErr.Namespace()[7:])
-		}
+	err := cfg.Validate()
+	if err == nil {
+		return nil
+	}
+	fmt.Fprintln(os.Stderr, "The config file has invalid or missing fields:")
+	var validationErrs *multierror.Error
+	if !errors.As(err, &validationErrs) {
+		fmt.Fprintf(os.Stderr, "  - %v\n", err)
 		return errors.New("invalid config file")
 	}
-
-	return nil
+	for _, validationErr := range validationErrs.Errors {
+		fmt.Fprintf(os.Stderr, "  - %v\n", validationErr)
+	}
+	return errors.New("invalid config file")
 }
 
 func withValidConfig(handler func(*cobra.Command, []string) error) func(*cobra.Command, []string) error {
@@ -29,13 +29,13 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 		return nil, err
 	}
 
-	return []services.Service{
+	return services.LegacyServices(
 		health.NewService(
 			ctx, "", healthutils.DefaultHealthServerErrHandler,
 			health.CheckerFrom(summarizeReports, inspector),
 		),
 		inspector,
-	}, nil
+	), nil
 }
 
 func summarizeReports(reports health.Reports) *health.Report {
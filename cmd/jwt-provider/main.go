@@ -20,13 +20,13 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 		return nil, err
 	}
 
-	return []services.Service{
+	return services.LegacyServices(
 		health.NewService(
 			ctx, "", healthutils.DefaultHealthServerErrHandler,
 			health.CheckerFrom(nil, jwtProvider),
 		),
 		jwtProvider,
-	}, nil
+	), nil
 }
 
 func Run() {
@@ -32,27 +32,29 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 		log.Warn("running in development mode")
 	}
 
-	return []services.Service{
+	return services.LegacyServices(
 		runner.NewRunner(ctx, cfg, imgStore, dockerClient, globalDockerClient),
-	}, nil
+	), nil
 }
 
 // Run runs the runner.
 func Run(cfg config.Config) {
-	ctx, cancel := services.InitMainContext()
-	defer cancel()
+	mainCtx := services.InitMainContext()
+	defer mainCtx.Cancel()
 
 	logger := log.WithField("process", "runner")
 	logger.Info("starting")
 	defer logger.Info("exiting")
 
-	serviceList, err := initServices(ctx, cfg)
+	serviceList, err := initServices(mainCtx.Context(), cfg)
 	if err != nil {
 		logger.WithError(err).Error("could not initialize services")
 		return
 	}
 
-	err = services.StartServices(ctx, cancel, log.NewEntry(log.StandardLogger()), serviceList)
+	err = services.StartServicesWithOptions(
+		mainCtx, log.NewEntry(log.StandardLogger()), serviceList, services.OptionsFromConfig(cfg),
+	)
 	if err == services.ErrExitTriggered {
 		logger.Info("exiting successfully after internal trigger")
 		os.Exit(0)
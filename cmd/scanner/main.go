@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/forta-network/forta-core-go/domain"
+	registrydomain "github.com/forta-network/forta-core-go/domain/registry"
 	"github.com/forta-network/forta-core-go/protocol/settings"
 	"github.com/forta-network/forta-node/services/publisher"
 	log "github.com/sirupsen/logrus"
@@ -211,7 +212,7 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 		blockFeed.Start()
 	}
 
-	svcs := []services.Service{
+	svcs := []services.LegacyService{
 		health.NewService(ctx, "", healthutils.DefaultHealthServerErrHandler, health.CheckerFrom(
 			summarizeReports,
 			ethClient, traceClient, blockFeed, txStream, txAnalyzer, blockAnalyzer, agentPool, registryService,
@@ -229,8 +230,20 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 	if !cfg.Registry.Disable {
 		svcs = append(svcs, registryService)
 	}
+	serviceList := services.LegacyServices(svcs...)
 
-	return svcs, nil
+	if !cfg.Registry.Disable && !cfg.LocalModeConfig.Enable && cfg.ENSConfig.RefreshIntervalSeconds > 0 {
+		contractRefresher, err := registry.NewContractRefresherFromConfig(ctx, cfg, func(_, _ registrydomain.RegistryContracts) {
+			log.Info("registry contracts changed - triggering exit to restart")
+			services.TriggerExit(0)
+		})
+		if err != nil {
+			return nil, err
+		}
+		serviceList = append(serviceList, contractRefresher)
+	}
+
+	return serviceList, nil
 }
 
 func summarizeReports(reports health.Reports) *health.Report {
@@ -34,13 +34,13 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 	if err != nil {
 		return nil, err
 	}
-	return []services.Service{
+	return services.LegacyServices(
 		health.NewService(
 			ctx, "", healthutils.DefaultHealthServerErrHandler,
 			health.CheckerFrom(summarizeReports, svc),
 		),
 		svc,
-	}, nil
+	), nil
 }
 
 func summarizeReports(reports health.Reports) *health.Report {
@@ -99,13 +99,13 @@ func initServices(ctx context.Context, cfg config.Config) ([]services.Service, e
 		developmentMode, cfg.AutoUpdate.TrackPrereleases, updateDelay, 0,
 	)
 
-	return []services.Service{
+	return services.LegacyServices(
 		health.NewService(
 			ctx, "", healthutils.DefaultHealthServerErrHandler,
 			health.CheckerFrom(summarizeReports, updaterService),
 		),
 		updaterService,
-	}, nil
+	), nil
 }
 
 func summarizeReports(reports health.Reports) *health.Report {
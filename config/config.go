@@ -1,11 +1,11 @@
 package config
 
 import (
-	"errors"
+	"bytes"
 	"os"
 	"path"
+	"strings"
 
-	"github.com/creasty/defaults"
 	"github.com/forta-network/forta-core-go/protocol/settings"
 )
 
@@ -40,6 +40,18 @@ type LogConfig struct {
 	Level       string `yaml:"level" json:"level" default:"info" `
 	MaxLogSize  string `yaml:"maxLogSize" json:"maxLogSize" default:"50m" `
 	MaxLogFiles int    `yaml:"maxLogFiles" json:"maxLogFiles" default:"10" `
+	Format      string `yaml:"format" json:"format" validate:"omitempty,oneof=text json"` // the default of the command if empty
+	// ServiceLevels override the level for the entries of the services, by service name.
+	ServiceLevels map[string]string `yaml:"serviceLevels" json:"serviceLevels"`
+	// Async makes the entries be written in the background so that logging does not block.
+	Async AsyncLogConfig `yaml:"async" json:"async"`
+}
+
+type AsyncLogConfig struct {
+	Enable bool `yaml:"enable" json:"enable"`
+	// BufferSize is how many entries can wait to be written. The entries are dropped when the buffer is full.
+	BufferSize                 int `yaml:"bufferSize" json:"bufferSize" default:"1000" validate:"omitempty,min=1"`
+	DropSummaryIntervalSeconds int `yaml:"dropSummaryIntervalSeconds" json:"dropSummaryIntervalSeconds" default:"10" validate:"omitempty,min=1"`
 }
 
 type RegistryConfig struct {
@@ -49,7 +61,7 @@ type RegistryConfig struct {
 	Username             string        `yaml:"username" json:"username"`
 	Password             string        `yaml:"password" json:"password"`
 	Disable              bool          `yaml:"disable" json:"disable"` // for testing situations
-	CheckIntervalSeconds int           `yaml:"checkIntervalSeconds" json:"checkIntervalSeconds" default:"15"`
+	CheckIntervalSeconds int           `yaml:"checkIntervalSeconds" json:"checkIntervalSeconds" default:"15" validate:"omitempty,min=1"`
 }
 
 type IPFSConfig struct {
@@ -79,11 +91,37 @@ type ResourcesConfig struct {
 	AgentMaxCPUs       float64 `yaml:"agentMaxCpus" json:"agentMaxCpus" validate:"omitempty,gt=0"`
 }
 
+type ENSRetryConfig struct {
+	MaxRetries          *int `yaml:"maxRetries" json:"maxRetries" default:"3" validate:"omitempty,min=0"` // no retries if zero
+	BaseIntervalSeconds int  `yaml:"baseIntervalSeconds" json:"baseIntervalSeconds" default:"1" validate:"omitempty,min=1"`
+	MaxIntervalSeconds  int  `yaml:"maxIntervalSeconds" json:"maxIntervalSeconds" default:"30" validate:"omitempty,min=1"`
+}
+
+type ContractsConfig struct {
+	Dispatch           string `yaml:"dispatch" json:"dispatch" validate:"omitempty,eth_addr"`
+	AgentRegistry      string `yaml:"agentRegistry" json:"agentRegistry" validate:"omitempty,eth_addr"`
+	ScannerRegistry    string `yaml:"scannerRegistry" json:"scannerRegistry" validate:"omitempty,eth_addr"`
+	ScannerNodeVersion string `yaml:"scannerNodeVersion" json:"scannerNodeVersion" validate:"omitempty,eth_addr"`
+	FortaStaking       string `yaml:"fortaStaking" json:"fortaStaking" validate:"omitempty,eth_addr"`
+	Forta              string `yaml:"forta" json:"forta" validate:"omitempty,eth_addr"`
+}
+
 type ENSConfig struct {
-	DefaultContract bool          `yaml:"defaultContract" json:"defaultContract" default:"false" `
-	ContractAddress string        `yaml:"contractAddress" json:"contractAddress" validate:"omitempty,eth_addr" default:"0x08f42fcc52a9C2F391bF507C4E8688D0b53e1bd7"`
-	JsonRpc         JsonRpcConfig `yaml:"jsonRpc" json:"jsonRpc" default:"{\"url\": \"https://polygon-rpc.com\"}" `
-	Override        bool          `yaml:"override" json:"override" default:"false"`
+	DefaultContract        bool            `yaml:"defaultContract" json:"defaultContract" default:"false" `
+	ContractAddress        string          `yaml:"contractAddress" json:"contractAddress" validate:"omitempty,eth_addr" default:"0x08f42fcc52a9C2F391bF507C4E8688D0b53e1bd7"`
+	JsonRpc                JsonRpcConfig   `yaml:"jsonRpc" json:"jsonRpc" default:"{\"url\": \"https://polygon-rpc.com\"}" `
+	Override               bool            `yaml:"override" json:"override" default:"false"`
+	Retry                  ENSRetryConfig  `yaml:"retry" json:"retry"`
+	JsonRpcUrls            []string        `yaml:"jsonRpcUrls" json:"jsonRpcUrls" validate:"omitempty,dive,url"` // registry JSON-RPC URL if empty
+	EndpointTimeoutSeconds int             `yaml:"endpointTimeoutSeconds" json:"endpointTimeoutSeconds" default:"30" validate:"omitempty,min=1"`
+	ResolveTimeoutSeconds  int             `yaml:"resolveTimeoutSeconds" json:"resolveTimeoutSeconds" default:"120" validate:"omitempty,min=1"`
+	RefreshIntervalSeconds int             `yaml:"refreshIntervalSeconds" json:"refreshIntervalSeconds" validate:"omitempty,min=60"`    // disabled if zero
+	RefreshJitterPercent   int             `yaml:"refreshJitterPercent" json:"refreshJitterPercent" validate:"omitempty,min=0,max=100"` // varies the interval by up to this much
+	Contracts              ContractsConfig `yaml:"contracts" json:"contracts"`                                                          // ENS is not used if all are set
+	AllowUnresolved        bool            `yaml:"allowUnresolved" json:"allowUnresolved"`                                              // for offline development
+	ChainID                int             `yaml:"chainId" json:"chainId" validate:"omitempty,min=1"`                                   // endpoints are not checked if zero
+	// OptionalContracts can fail to resolve without stopping the node, e.g. scannerNodeVersion.
+	OptionalContracts []string `yaml:"optionalContracts" json:"optionalContracts" validate:"omitempty,dive,oneof=agentRegistry scannerRegistry scannerNodeVersion fortaStaking forta"`
 }
 
 type TelemetryConfig struct {
@@ -131,6 +169,60 @@ type InspectionConfig struct {
 	InspectAtStartup  bool `yaml:"inspectAtStartup" json:"inspectAtStartup" default:"true"`
 }
 
+type RestartConfig struct {
+	MaxAttempts         *int `yaml:"maxAttempts" json:"maxAttempts" default:"3" validate:"omitempty,min=0"` // no restarts if zero
+	BaseIntervalSeconds int  `yaml:"baseIntervalSeconds" json:"baseIntervalSeconds" default:"1" validate:"omitempty,min=1"`
+	MaxIntervalSeconds  int  `yaml:"maxIntervalSeconds" json:"maxIntervalSeconds" default:"30" validate:"omitempty,min=1"`
+}
+
+type ProbeServerConfig struct {
+	Enable  bool   `yaml:"enable" json:"enable"`
+	Address string `yaml:"address" json:"address" default:":8091" validate:"omitempty,hostname_port"`
+}
+
+type MetricsServerConfig struct {
+	Enable  bool   `yaml:"enable" json:"enable"`
+	Address string `yaml:"address" json:"address" default:":8092" validate:"omitempty,hostname_port"`
+}
+
+// ReadinessProbeConfig is an HTTP readiness check for a service which does not report it.
+type ReadinessProbeConfig struct {
+	URL            string `yaml:"url" json:"url" validate:"url"`
+	TimeoutSeconds int    `yaml:"timeoutSeconds" json:"timeoutSeconds" default:"5" validate:"omitempty,min=1"`
+}
+
+type PprofServerConfig struct {
+	Enable  bool   `yaml:"enable" json:"enable"`
+	Address string `yaml:"address" json:"address" default:"127.0.0.1:6060" validate:"omitempty,hostname_port"`
+}
+
+type ServicesConfig struct {
+	StartTimeoutSeconds            int                 `yaml:"startTimeoutSeconds" json:"startTimeoutSeconds" default:"600" validate:"omitempty,min=1"`
+	StartupDeadlineSeconds         int                 `yaml:"startupDeadlineSeconds" json:"startupDeadlineSeconds" validate:"omitempty,min=1"` // no deadline if zero
+	Restart                        RestartConfig       `yaml:"restart" json:"restart"`
+	HealthCheckIntervalSeconds     int                 `yaml:"healthCheckIntervalSeconds" json:"healthCheckIntervalSeconds" default:"15" validate:"omitempty,min=1"`
+	HealthGracePeriodSeconds       int                 `yaml:"healthGracePeriodSeconds" json:"healthGracePeriodSeconds" default:"60" validate:"omitempty,min=1"`
+	CollectStartErrors             bool                `yaml:"collectStartErrors" json:"collectStartErrors"`
+	StartParallelism               int                 `yaml:"startParallelism" json:"startParallelism" default:"1" validate:"omitempty,min=1"`
+	StartGroupLimits               map[string]int      `yaml:"startGroupLimits" json:"startGroupLimits" validate:"omitempty,dive,min=1"` // by the start group of the services
+	DrainPeriodSeconds             int                 `yaml:"drainPeriodSeconds" json:"drainPeriodSeconds" validate:"omitempty,min=0"`
+	StopTimeoutSeconds             int                 `yaml:"stopTimeoutSeconds" json:"stopTimeoutSeconds" default:"30" validate:"omitempty,min=1"`
+	ShutdownTimeoutSeconds         int                 `yaml:"shutdownTimeoutSeconds" json:"shutdownTimeoutSeconds" default:"120" validate:"omitempty,min=1"`
+	ShutdownCallbackTimeoutSeconds int                 `yaml:"shutdownCallbackTimeoutSeconds" json:"shutdownCallbackTimeoutSeconds" default:"10" validate:"omitempty,min=1"`
+	ProbeServer                    ProbeServerConfig   `yaml:"probeServer" json:"probeServer"`
+	MetricsServer                  MetricsServerConfig `yaml:"metricsServer" json:"metricsServer"`
+	PprofServer                    PprofServerConfig   `yaml:"pprofServer" json:"pprofServer"`                                // for debugging only
+	StackDumpFile                  string              `yaml:"stackDumpFile" json:"stackDumpFile"`                            // logged if empty
+	RestartOnReload                bool                `yaml:"restartOnReload" json:"restartOnReload"`                        // recreates the services on the reload signal
+	MaxRunSeconds                  int                 `yaml:"maxRunSeconds" json:"maxRunSeconds" validate:"omitempty,min=1"` // runs until stopped if zero
+	// Enabled and Disabled select the services to start by name, e.g. for debugging or staged rollouts.
+	// All of the services are enabled if Enabled is empty.
+	Enabled  []string `yaml:"enabled" json:"enabled"`
+	Disabled []string `yaml:"disabled" json:"disabled"`
+	// ReadinessProbes are the HTTP readiness checks by service name.
+	ReadinessProbes map[string]ReadinessProbeConfig `yaml:"readinessProbes" json:"readinessProbes" validate:"omitempty,dive"`
+}
+
 type Config struct {
 	// runtime values
 
@@ -141,7 +233,9 @@ type Config struct {
 
 	// yaml config values
 
-	ChainID int `yaml:"chainId" json:"chainId" default:"1" `
+	Version int    `yaml:"version" json:"version"`
+	Profile string `yaml:"profile" json:"profile"` // overlays the profile from the profiles section
+	ChainID int    `yaml:"chainId" json:"chainId" default:"1" `
 
 	Scan  ScannerConfig `yaml:"scan" json:"scan"`
 	Trace TraceConfig   `yaml:"trace" json:"trace"`
@@ -157,19 +251,40 @@ type Config struct {
 	AgentLogsConfig  AgentLogsConfig    `yaml:"agentLogs" json:"agentLogs"`
 	LocalModeConfig  LocalModeConfig    `yaml:"localMode" json:"localMode"`
 	InspectionConfig InspectionConfig   `yaml:"inspection" json:"inspection"`
+	Services         ServicesConfig     `yaml:"services" json:"services"`
 }
 
 func (cfg *Config) ConfigFilePath() string {
 	return path.Join(cfg.FortaDir, DefaultConfigFileName)
 }
 
-// GetConfigForContainer is how a container gets the forta configuration (file or env var)
+// GetConfigForContainer is how a container gets the forta configuration (file or env var).
+// If set, the comma-separated config paths in the FORTA_CONFIG_FILES env var are merged in
+// the given order instead of using the default path. See readMergedFiles for the precedence.
+// A path can also be an http(s):// or ipfs:// URI to fetch the config from. The env vars in
+// EnvConfigVars override the values from the files. If no paths are set and there is no file
+// at the default path, the config is made only from the env vars.
 func GetConfigForContainer() (Config, error) {
-	var cfg Config
-	if _, err := os.Stat(DefaultContainerConfigPath); os.IsNotExist(err) {
-		return cfg, errors.New("config file not found")
+	var filenames []string
+	for _, filename := range strings.Split(os.Getenv(EnvConfigFiles), ",") {
+		if filename = strings.TrimSpace(filename); len(filename) > 0 {
+			filenames = append(filenames, filename)
+		}
+	}
+	if len(filenames) == 0 {
+		if _, err := os.Stat(DefaultContainerConfigPath); err == nil || !os.IsNotExist(err) {
+			filenames = []string{DefaultContainerConfigPath}
+		}
+	}
+	return getContainerConfig(defaultConfigReader, filenames...)
+}
+
+func getContainerConfig(reader *configReader, filenames ...string) (Config, error) {
+	b, err := readMergedFiles(reader, filenames...)
+	if err != nil {
+		return Config{}, err
 	}
-	cfg, err := getConfigFromFile(DefaultContainerConfigPath)
+	cfg, err := parse(bytes.NewReader(b), applyEnvConfig)
 	if err != nil {
 		return Config{}, err
 	}
@@ -189,14 +304,3 @@ func applyContextDefaults(cfg *Config) {
 	cfg.FortaDir = DefaultContainerFortaDirPath
 	cfg.KeyDirPath = path.Join(cfg.FortaDir, DefaultKeysDirName)
 }
-
-func getConfigFromFile(filename string) (Config, error) {
-	var cfg Config
-	if err := readFile(filename, &cfg); err != nil {
-		return Config{}, err
-	}
-	if err := defaults.Set(&cfg); err != nil {
-		return Config{}, err
-	}
-	return cfg, nil
-}
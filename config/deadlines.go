@@ -0,0 +1,38 @@
+package config
+
+import (
+	"context"
+	"time"
+)
+
+// Default deadlines of the ENS calls
+const (
+	DefaultENSEndpointTimeout = time.Second * 30
+	DefaultResolveTimeout     = time.Minute * 2
+)
+
+// ENSEndpointTimeout returns how long a single ENS endpoint is given to respond.
+func ENSEndpointTimeout(cfg Config) time.Duration {
+	if cfg.ENSConfig.EndpointTimeoutSeconds > 0 {
+		return time.Duration(cfg.ENSConfig.EndpointTimeoutSeconds) * time.Second
+	}
+	return DefaultENSEndpointTimeout
+}
+
+// ResolveTimeout returns how long the whole registry contract resolution can take.
+func ResolveTimeout(cfg Config) time.Duration {
+	if cfg.ENSConfig.ResolveTimeoutSeconds > 0 {
+		return time.Duration(cfg.ENSConfig.ResolveTimeoutSeconds) * time.Second
+	}
+	return DefaultResolveTimeout
+}
+
+// WithENSEndpointTimeout returns a child context which is done after the ENS endpoint timeout.
+func WithENSEndpointTimeout(ctx context.Context, cfg Config) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, ENSEndpointTimeout(cfg))
+}
+
+// WithResolveTimeout returns a child context which is done after the contract resolution timeout.
+func WithResolveTimeout(ctx context.Context, cfg Config) (context.Context, context.CancelFunc) {
+	return context.WithTimeout(ctx, ResolveTimeout(cfg))
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeadlines(t *testing.T) {
+	r := require.New(t)
+
+	var cfg Config
+	cfg.ENSConfig.EndpointTimeoutSeconds = 5
+	cfg.ENSConfig.ResolveTimeoutSeconds = 60
+
+	begin := time.Now()
+	endpointCtx, cancel := WithENSEndpointTimeout(context.Background(), cfg)
+	defer cancel()
+	deadline, ok := endpointCtx.Deadline()
+	r.True(ok)
+	r.WithinDuration(begin.Add(time.Second*5), deadline, time.Second)
+
+	resolveCtx, cancel := WithResolveTimeout(context.Background(), cfg)
+	defer cancel()
+	deadline, ok = resolveCtx.Deadline()
+	r.True(ok)
+	r.WithinDuration(begin.Add(time.Minute), deadline, time.Second)
+}
+
+func TestDeadlinesDefaults(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(DefaultENSEndpointTimeout, ENSEndpointTimeout(Config{}))
+	r.Equal(DefaultResolveTimeout, ResolveTimeout(Config{}))
+
+	// the config defaults are the same
+	cfg := testValidConfig(t)
+	r.Equal(DefaultENSEndpointTimeout, ENSEndpointTimeout(cfg))
+	r.Equal(DefaultResolveTimeout, ResolveTimeout(cfg))
+}
+
+func TestDeadlinesParentDone(t *testing.T) {
+	r := require.New(t)
+
+	parent, cancelParent := context.WithCancel(context.Background())
+	ctx, cancel := WithResolveTimeout(parent, Config{})
+	defer cancel()
+	cancelParent()
+	<-ctx.Done()
+	r.ErrorIs(ctx.Err(), context.Canceled)
+}
@@ -1,5 +1,7 @@
 package config
 
+import "github.com/creasty/defaults"
+
 const (
 	DefaultKeysDirName         = ".keys"
 	DefaultConfigFileName      = "config.yml"
@@ -10,3 +12,9 @@ const (
 	DefaultJWTProviderPort     = "8515"
 	DefaultFortaNodeBinaryPath = "/forta-node" // the path for the common binary in the container image
 )
+
+// ApplyDefaults fills in the unset fields of the config with the values from the `default`
+// tags. The fields which are already set are never overwritten.
+func ApplyDefaults(cfg *Config) error {
+	return defaults.Set(cfg)
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// checkDefaulted checks that all fields with a default value are set.
+func checkDefaulted(t *testing.T, val reflect.Value, path string) {
+	switch val.Kind() {
+	case reflect.Ptr:
+		if !val.IsNil() {
+			checkDefaulted(t, val.Elem(), path)
+		}
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Type().Field(i)
+			fieldPath := path + "." + field.Name
+			if defaultVal, ok := field.Tag.Lookup("default"); ok && defaultVal != "false" {
+				require.False(t, val.Field(i).IsZero(), "%s is not defaulted", fieldPath)
+			}
+			checkDefaulted(t, val.Field(i), fieldPath)
+		}
+	}
+}
+
+func TestApplyDefaultsEmpty(t *testing.T) {
+	r := require.New(t)
+
+	var cfg Config
+	r.NoError(ApplyDefaults(&cfg))
+	checkDefaulted(t, reflect.ValueOf(cfg), "Config")
+
+	r.Equal("info", cfg.Log.Level)
+	r.Equal(600, cfg.Services.StartTimeoutSeconds)
+	r.Equal(3, *cfg.Services.Restart.MaxAttempts)
+	r.Equal("https://polygon-rpc.com", cfg.Registry.JsonRpc.Url)
+	r.NotNil(cfg.Publish.Batch.MaxAlerts)
+	r.Equal(1000, *cfg.Publish.Batch.MaxAlerts)
+	r.Equal(3, *cfg.ENSConfig.Retry.MaxRetries)
+	r.NoError(cfg.Validate())
+}
+
+func TestApplyDefaultsKeepsExplicitValues(t *testing.T) {
+	r := require.New(t)
+
+	maxAlerts := 10
+	noRetries := 0
+	cfg := Config{
+		ChainID:   137,
+		Log:       LogConfig{Level: "debug"},
+		Registry:  RegistryConfig{JsonRpc: JsonRpcConfig{Url: "https://rpc.example.com"}},
+		Publish:   PublisherConfig{Batch: BatchConfig{MaxAlerts: &maxAlerts}},
+		Services:  ServicesConfig{StartTimeoutSeconds: 30},
+		ENSConfig: ENSConfig{Retry: ENSRetryConfig{MaxRetries: &noRetries}},
+	}
+	r.NoError(ApplyDefaults(&cfg))
+
+	r.Equal(137, cfg.ChainID)
+	r.Equal("debug", cfg.Log.Level)
+	r.Equal("https://rpc.example.com", cfg.Registry.JsonRpc.Url)
+	r.Equal(10, *cfg.Publish.Batch.MaxAlerts)
+	r.Equal(30, cfg.Services.StartTimeoutSeconds)
+	// zero is kept so that the retries can be disabled
+	r.Equal(0, *cfg.ENSConfig.Retry.MaxRetries)
+
+	// the rest is still defaulted
+	r.Equal(10, cfg.Log.MaxLogFiles)
+	r.Equal(3, *cfg.Services.Restart.MaxAttempts)
+}
@@ -1,9 +1,13 @@
 package config
 
 const (
-	EnvHostFortaDir = "HOST_FORTA_DIR" // for retrieving forta dir path on the host os
-	EnvDevelopment  = "FORTA_DEVELOPMENT"
-	EnvReleaseInfo  = "FORTA_RELEASE_INFO"
+	EnvHostFortaDir  = "HOST_FORTA_DIR" // for retrieving forta dir path on the host os
+	EnvDevelopment   = "FORTA_DEVELOPMENT"
+	EnvReleaseInfo   = "FORTA_RELEASE_INFO"
+	EnvConfigFiles   = "FORTA_CONFIG_FILES"   // for merging multiple config files in a container
+	EnvExecID        = "FORTA_EXEC_ID"        // for sharing the same exec ID between the containers
+	EnvDryRun        = "FORTA_DRY_RUN"        // for checking the config and the services without starting them
+	EnvConfigProfile = "FORTA_CONFIG_PROFILE" // for selecting the config profile
 
 	// Agent env vars
 	EnvJsonRpcHost     = "JSON_RPC_HOST"
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfigVars map the env vars which can set the config values to the config paths. The
+// env vars take precedence over the config files and they are enough for running without one.
+var EnvConfigVars = map[string]string{
+	"FORTA_CHAIN_ID":                 "chainId",
+	"FORTA_SCAN_JSONRPC_URL":         "scan.jsonRpc.url",
+	"FORTA_TRACE_JSONRPC_URL":        "trace.jsonRpc.url",
+	"FORTA_TRACE_ENABLED":            "trace.enabled",
+	"FORTA_REGISTRY_JSONRPC_URL":     "registry.jsonRpc.url",
+	"FORTA_JSONRPC_PROXY_URL":        "jsonRpcProxy.jsonRpc.url",
+	"FORTA_ENS_JSONRPC_URL":          "ens.jsonRpc.url",
+	"FORTA_ENS_CONTRACT_ADDRESS":     "ens.contractAddress",
+	"FORTA_PUBLISH_API_URL":          "publish.apiUrl",
+	"FORTA_PUBLISH_SKIP":             "publish.skipPublish",
+	"FORTA_LOG_LEVEL":                "log.level",
+	"FORTA_LOG_FORMAT":               "log.format",
+	"FORTA_PROBE_SERVER_ENABLE":      "services.probeServer.enable",
+	"FORTA_METRICS_SERVER_ENABLE":    "services.metricsServer.enable",
+	"FORTA_START_TIMEOUT_SECONDS":    "services.startTimeoutSeconds",
+	"FORTA_SHUTDOWN_TIMEOUT_SECONDS": "services.shutdownTimeoutSeconds",
+	"FORTA_MAX_RUN_SECONDS":          "services.maxRunSeconds",
+}
+
+// applyEnvConfig merges the config values from the env vars on top of the raw YAML config.
+func applyEnvConfig(raw []byte) ([]byte, error) {
+	envValues, err := readEnvConfig()
+	if err != nil {
+		return nil, err
+	}
+	if len(envValues) == 0 {
+		return raw, nil
+	}
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(raw, &values); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %v", err)
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	mergeConfigMaps(values, envValues)
+	return yaml.Marshal(values)
+}
+
+// readEnvConfig returns the config values from the env vars as nested maps.
+func readEnvConfig() (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	for envVar, configPath := range EnvConfigVars {
+		envValue, ok := os.LookupEnv(envVar)
+		if !ok {
+			continue
+		}
+		keys := strings.Split(configPath, ".")
+		value, err := parseEnvConfigValue(keys, envValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid value for %s: %v", envVar, err)
+		}
+		section := values
+		for _, key := range keys[:len(keys)-1] {
+			next, ok := section[key].(map[string]interface{})
+			if !ok {
+				next = make(map[string]interface{})
+				section[key] = next
+			}
+			section = next
+		}
+		section[keys[len(keys)-1]] = value
+	}
+	return values, nil
+}
+
+// parseEnvConfigValue converts the env var value to the type of the config field at the path.
+func parseEnvConfigValue(keys []string, envValue string) (interface{}, error) {
+	fieldType := reflect.TypeOf(Config{})
+	for _, key := range keys {
+		field, ok := findYAMLField(fieldType, key)
+		if !ok {
+			return nil, fmt.Errorf("unknown config field '%s'", strings.Join(keys, "."))
+		}
+		fieldType = field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+	}
+	switch fieldType.Kind() {
+	case reflect.String:
+		return envValue, nil
+	case reflect.Bool:
+		return strconv.ParseBool(envValue)
+	case reflect.Int, reflect.Int64:
+		return strconv.Atoi(envValue)
+	case reflect.Float64:
+		return strconv.ParseFloat(envValue, 64)
+	default:
+		return nil, fmt.Errorf("unsupported config field type %s", fieldType)
+	}
+}
+
+func findYAMLField(structType reflect.Type, name string) (reflect.StructField, bool) {
+	if structType.Kind() != reflect.Struct {
+		return reflect.StructField{}, false
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		if strings.SplitN(field.Tag.Get("yaml"), ",", 2)[0] == name {
+			return field, true
+		}
+	}
+	return reflect.StructField{}, false
+}
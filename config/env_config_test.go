@@ -0,0 +1,88 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvConfigVarPaths(t *testing.T) {
+	r := require.New(t)
+
+	for envVar, configPath := range EnvConfigVars {
+		r.True(strings.HasPrefix(envVar, "FORTA_"), envVar)
+		_, err := parseEnvConfigValue(strings.Split(configPath, "."), "1")
+		r.NoError(err, envVar)
+	}
+}
+
+func TestConfigFromEnvOnly(t *testing.T) {
+	r := require.New(t)
+
+	setTestEnv(t, "FORTA_CHAIN_ID", "137")
+	setTestEnv(t, "FORTA_SCAN_JSONRPC_URL", "https://polygon.example.com")
+	setTestEnv(t, "FORTA_ENS_CONTRACT_ADDRESS", "0x2222222222222222222222222222222222222222")
+	setTestEnv(t, "FORTA_PROBE_SERVER_ENABLE", "true")
+	setTestEnv(t, "FORTA_START_TIMEOUT_SECONDS", "30")
+
+	cfg, err := getContainerConfigFromFiles()
+	r.NoError(err)
+	r.Equal(137, cfg.ChainID)
+	r.Equal("https://polygon.example.com", cfg.Scan.JsonRpc.Url)
+	r.Equal("0x2222222222222222222222222222222222222222", cfg.ENSConfig.ContractAddress)
+	r.True(cfg.Services.ProbeServer.Enable)
+	r.Equal(30, cfg.Services.StartTimeoutSeconds)
+
+	// the defaults apply to the rest
+	r.Equal("info", cfg.Log.Level)
+	r.Equal(DefaultContainerFortaDirPath, cfg.FortaDir)
+}
+
+func TestConfigFromFileAndEnv(t *testing.T) {
+	r := require.New(t)
+
+	base := writeTestConfigFile(t, t.TempDir(), "base.yml", testBaseConfig)
+	setTestEnv(t, "FORTA_SCAN_JSONRPC_URL", "https://env.example.com")
+	setTestEnv(t, "FORTA_LOG_LEVEL", "warn")
+
+	cfg, err := getContainerConfigFromFiles(base)
+	r.NoError(err)
+	r.Equal("https://env.example.com", cfg.Scan.JsonRpc.Url)
+	r.Equal("warn", cfg.Log.Level)
+
+	// the rest of the file values are kept
+	r.Equal("base-key", cfg.Scan.JsonRpc.Headers["X-Api-Key"])
+	r.Equal(100, cfg.Scan.BlockRateLimit)
+	r.Equal(137, cfg.ChainID)
+}
+
+func TestConfigFromEnvOverProfile(t *testing.T) {
+	r := require.New(t)
+
+	base := writeTestConfigFile(t, t.TempDir(), "base.yml", testProfilesConfig+"profile: dev\n")
+	setTestEnv(t, "FORTA_LOG_LEVEL", "warn")
+
+	cfg, err := getContainerConfigFromFiles(base)
+	r.NoError(err)
+	r.Equal("dev", cfg.Profile)
+	r.Equal("warn", cfg.Log.Level)
+
+	// the profile values which are not in the env are kept
+	r.Equal("https://dev.example.com", cfg.Scan.JsonRpc.Url)
+}
+
+func TestConfigFromEnvErrors(t *testing.T) {
+	r := require.New(t)
+
+	setTestEnv(t, "FORTA_CHAIN_ID", "polygon")
+	_, err := getContainerConfigFromFiles()
+	r.Error(err)
+	r.Contains(err.Error(), "FORTA_CHAIN_ID")
+
+	setTestEnv(t, "FORTA_CHAIN_ID", "137")
+	setTestEnv(t, "FORTA_ENS_JSONRPC_URL", "polygon-rpc.com")
+	_, err = getContainerConfigFromFiles()
+	r.Error(err)
+	r.Contains(err.Error(), "'ens.jsonRpc.url': missing scheme")
+}
@@ -0,0 +1,154 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// expandEnvVars replaces the ${VAR} and $VAR references in the string values of the config
+// with the values from the process environment. A reference to an unset variable is an error
+// and "$$" is replaced with a literal "$".
+func expandEnvVars(cfg *Config) error {
+	var errs *multierror.Error
+	expandEnvVarsInValue(reflect.ValueOf(cfg).Elem(), "", &errs)
+	return errs.ErrorOrNil()
+}
+
+func expandEnvVarsInValue(val reflect.Value, path string, errs **multierror.Error) {
+	switch val.Kind() {
+	case reflect.String:
+		expanded, err := expandEnvString(val.String())
+		if err != nil {
+			*errs = multierror.Append(*errs, fmt.Errorf("invalid value for '%s': %v", path, err))
+			return
+		}
+		val.SetString(expanded)
+
+	case reflect.Ptr:
+		if !val.IsNil() {
+			expandEnvVarsInValue(val.Elem(), path, errs)
+		}
+
+	case reflect.Struct:
+		for i := 0; i < val.NumField(); i++ {
+			field := val.Type().Field(i)
+			name := strings.SplitN(field.Tag.Get("yaml"), ",", 2)[0]
+			// skip the runtime values and the unexported fields
+			if name == "-" || len(field.PkgPath) > 0 {
+				continue
+			}
+			if len(name) == 0 {
+				name = field.Name
+			}
+			if len(path) > 0 {
+				name = path + "." + name
+			}
+			expandEnvVarsInValue(val.Field(i), name, errs)
+		}
+
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			expandEnvVarsInValue(val.Index(i), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+
+	case reflect.Map:
+		if val.Type().Elem().Kind() != reflect.String {
+			return
+		}
+		for _, key := range val.MapKeys() {
+			expanded, err := expandEnvString(val.MapIndex(key).String())
+			if err != nil {
+				*errs = multierror.Append(*errs, fmt.Errorf("invalid value for '%s[%v]': %v", path, key, err))
+				continue
+			}
+			val.SetMapIndex(key, reflect.ValueOf(expanded).Convert(val.Type().Elem()))
+		}
+	}
+}
+
+func expandEnvString(s string) (string, error) {
+	if !strings.Contains(s, "$") {
+		return s, nil
+	}
+
+	var (
+		b     strings.Builder
+		unset []string
+	)
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+
+		var name string
+		switch next := s[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+			continue
+
+		case next == '{':
+			end := strings.IndexByte(s[i+2:], '}')
+			if end < 0 {
+				return "", fmt.Errorf("unterminated variable reference in %q", s)
+			}
+			name = s[i+2 : i+2+end]
+			if !isEnvVarName(name) {
+				return "", fmt.Errorf("bad variable name %q", name)
+			}
+			i += 2 + end
+
+		case isEnvVarNameChar(next, true):
+			end := i + 1
+			for end < len(s) && isEnvVarNameChar(s[end], end == i+1) {
+				end++
+			}
+			name = s[i+1 : end]
+			i = end - 1
+
+		default:
+			b.WriteByte('$')
+			continue
+		}
+
+		value, ok := os.LookupEnv(name)
+		if !ok {
+			unset = append(unset, name)
+			continue
+		}
+		b.WriteString(value)
+	}
+
+	if len(unset) > 0 {
+		return "", fmt.Errorf("environment variables are not set: %s", strings.Join(unset, ", "))
+	}
+	return b.String(), nil
+}
+
+func isEnvVarName(name string) bool {
+	if len(name) == 0 {
+		return false
+	}
+	for i := 0; i < len(name); i++ {
+		if !isEnvVarNameChar(name[i], i == 0) {
+			return false
+		}
+	}
+	return true
+}
+
+func isEnvVarNameChar(c byte, first bool) bool {
+	switch {
+	case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case c >= '0' && c <= '9':
+		return !first
+	default:
+		return false
+	}
+}
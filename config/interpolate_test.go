@@ -0,0 +1,123 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func setTestEnv(t *testing.T, key, value string) {
+	require.NoError(t, os.Setenv(key, value))
+	t.Cleanup(func() {
+		os.Unsetenv(key)
+	})
+}
+
+func TestExpandEnvString(t *testing.T) {
+	setTestEnv(t, "FORTA_TEST_KEY", "abc123")
+	setTestEnv(t, "FORTA_TEST_EMPTY", "")
+
+	testCases := []struct {
+		input    string
+		expected string
+		err      bool
+	}{
+		{input: "https://mainnet.infura.io/v3/${FORTA_TEST_KEY}", expected: "https://mainnet.infura.io/v3/abc123"},
+		{input: "https://mainnet.infura.io/v3/$FORTA_TEST_KEY", expected: "https://mainnet.infura.io/v3/abc123"},
+		{input: "$FORTA_TEST_KEY/path", expected: "abc123/path"},
+		{input: "${FORTA_TEST_KEY}${FORTA_TEST_KEY}", expected: "abc123abc123"},
+		{input: "x${FORTA_TEST_EMPTY}y", expected: "xy"},
+		{input: "pa$$word", expected: "pa$word"},
+		{input: "$${FORTA_TEST_KEY}", expected: "${FORTA_TEST_KEY}"},
+		{input: "price: 5$", expected: "price: 5$"},
+		{input: "$-", expected: "$-"},
+		{input: "no variables", expected: "no variables"},
+		{input: "${FORTA_TEST_UNSET}", err: true},
+		{input: "$FORTA_TEST_UNSET", err: true},
+		{input: "${FORTA_TEST_KEY", err: true},
+		{input: "${1ABC}", err: true},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.input, func(t *testing.T) {
+			r := require.New(t)
+
+			expanded, err := expandEnvString(testCase.input)
+			if testCase.err {
+				r.Error(err)
+				return
+			}
+			r.NoError(err)
+			r.Equal(testCase.expected, expanded)
+		})
+	}
+}
+
+func TestExpandEnvVars(t *testing.T) {
+	r := require.New(t)
+
+	setTestEnv(t, "FORTA_TEST_KEY", "abc123")
+
+	cfg := Config{
+		Scan: ScannerConfig{
+			JsonRpc: JsonRpcConfig{
+				Url:     "https://mainnet.infura.io/v3/${FORTA_TEST_KEY}",
+				Headers: map[string]string{"X-Api-Key": "$FORTA_TEST_KEY"},
+			},
+		},
+		ENSConfig: ENSConfig{JsonRpcUrls: []string{"https://rpc.example.com/$FORTA_TEST_KEY"}},
+		LocalModeConfig: LocalModeConfig{
+			ContainerRegistry: &ContainerRegistryConfig{Password: "${FORTA_TEST_KEY}"},
+		},
+	}
+	r.NoError(expandEnvVars(&cfg))
+	r.Equal("https://mainnet.infura.io/v3/abc123", cfg.Scan.JsonRpc.Url)
+	r.Equal("abc123", cfg.Scan.JsonRpc.Headers["X-Api-Key"])
+	r.Equal([]string{"https://rpc.example.com/abc123"}, cfg.ENSConfig.JsonRpcUrls)
+	r.Equal("abc123", cfg.LocalModeConfig.ContainerRegistry.Password)
+}
+
+func TestExpandEnvVarsUnset(t *testing.T) {
+	r := require.New(t)
+
+	cfg := Config{
+		Scan:     ScannerConfig{JsonRpc: JsonRpcConfig{Url: "https://mainnet.infura.io/v3/${FORTA_TEST_UNSET}"}},
+		Registry: RegistryConfig{Password: "$FORTA_TEST_UNSET"},
+	}
+	err := expandEnvVars(&cfg)
+	r.Error(err)
+	r.Contains(err.Error(), "'scan.jsonRpc.url'")
+	r.Contains(err.Error(), "'registry.password'")
+	r.Contains(err.Error(), "FORTA_TEST_UNSET")
+}
+
+func TestGetContainerConfigExpandsEnvVars(t *testing.T) {
+	r := require.New(t)
+
+	setTestEnv(t, "FORTA_TEST_KEY", "abc123")
+
+	configPath := path.Join(t.TempDir(), DefaultConfigFileName)
+	r.NoError(ioutil.WriteFile(configPath, []byte(`
+scan:
+  jsonRpc:
+    url: https://mainnet.infura.io/v3/${FORTA_TEST_KEY}
+registry:
+  password: pa$$word
+`), 0644))
+
+	cfg, err := getContainerConfigFromFiles(configPath)
+	r.NoError(err)
+	r.Equal("https://mainnet.infura.io/v3/abc123", cfg.Scan.JsonRpc.Url)
+	r.Equal("pa$word", cfg.Registry.Password)
+
+	r.NoError(ioutil.WriteFile(configPath, []byte(`
+scan:
+  jsonRpc:
+    url: https://mainnet.infura.io/v3/${FORTA_TEST_UNSET}
+`), 0644))
+	_, err = getContainerConfigFromFiles(configPath)
+	r.Error(err)
+}
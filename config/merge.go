@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// readMergedFiles reads the config files in the given order and returns the merged result
+// as YAML. The files are merged left-to-right so the later files take precedence:
+//
+//   - Scalar values in a later file overwrite the earlier values.
+//   - Maps (i.e. the config sections and the headers) are merged deeply, key by key.
+//   - Lists in a later file replace the earlier lists as a whole.
+//   - Keys which are missing or null in a later file leave the earlier values untouched.
+func readMergedFiles(reader *configReader, filenames ...string) ([]byte, error) {
+	merged := make(map[string]interface{})
+	for _, filename := range filenames {
+		b, err := reader.read(filename)
+		if err != nil {
+			return nil, err
+		}
+		b, err = Migrate(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate config file %s: %v", filename, err)
+		}
+		var values map[string]interface{}
+		if err := yaml.Unmarshal(b, &values); err != nil {
+			return nil, fmt.Errorf("failed to parse config file %s: %v", filename, err)
+		}
+		mergeConfigMaps(merged, values)
+	}
+	return yaml.Marshal(merged)
+}
+
+func mergeConfigMaps(dst, src map[string]interface{}) {
+	for key, srcVal := range src {
+		if srcVal == nil {
+			continue
+		}
+		srcMap, srcIsMap := srcVal.(map[string]interface{})
+		dstMap, dstIsMap := dst[key].(map[string]interface{})
+		if srcIsMap && dstIsMap {
+			mergeConfigMaps(dstMap, srcMap)
+			continue
+		}
+		dst[key] = srcVal
+	}
+}
@@ -0,0 +1,126 @@
+package config
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testBaseConfig = `
+chainId: 137
+scan:
+  jsonRpc:
+    url: https://polygon.example.com
+    headers:
+      X-Api-Key: base-key
+      X-Region: eu
+  blockRateLimit: 100
+localMode:
+  enable: true
+  botImages:
+    - bot-1
+    - bot-2
+log:
+  level: debug
+`
+
+// getContainerConfigFromFiles gets the container config from the local files.
+func getContainerConfigFromFiles(filenames ...string) (Config, error) {
+	return getContainerConfig(defaultConfigReader, filenames...)
+}
+
+func writeTestConfigFile(t *testing.T, dir, name, content string) string {
+	filename := path.Join(dir, name)
+	require.NoError(t, ioutil.WriteFile(filename, []byte(content), 0644))
+	return filename
+}
+
+func TestMergeConfigFiles(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	base := writeTestConfigFile(t, dir, "base.yml", testBaseConfig)
+	override1 := writeTestConfigFile(t, dir, "override1.yml", `
+scan:
+  jsonRpc:
+    headers:
+      X-Api-Key: override-key
+  blockRateLimit: 50
+localMode:
+  botImages:
+    - bot-3
+log:
+  level:
+`)
+	override2 := writeTestConfigFile(t, dir, "override2.yml", `
+chainId: 1
+`)
+
+	cfg, err := getContainerConfigFromFiles(base, override1, override2)
+	r.NoError(err)
+
+	// scalar overrides
+	r.Equal(1, cfg.ChainID)
+	r.Equal(50, cfg.Scan.BlockRateLimit)
+
+	// deep map merge
+	r.Equal("https://polygon.example.com", cfg.Scan.JsonRpc.Url)
+	r.Equal(map[string]string{
+		"X-Api-Key": "override-key",
+		"X-Region":  "eu",
+	}, cfg.Scan.JsonRpc.Headers)
+
+	// slice replacement
+	r.Equal([]string{"bot-3"}, cfg.LocalModeConfig.BotImages)
+
+	// missing and empty values do not clobber the base
+	r.True(cfg.LocalModeConfig.Enable)
+	r.Equal("debug", cfg.Log.Level)
+
+	// defaults are still applied
+	r.Equal(int64(600), cfg.Scan.BlockMaxAgeSeconds)
+}
+
+func TestMergeConfigFilesOrder(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	file1 := writeTestConfigFile(t, dir, "1.yml", "chainId: 1\n")
+	file2 := writeTestConfigFile(t, dir, "2.yml", "chainId: 2\n")
+
+	cfg, err := getContainerConfigFromFiles(file1, file2)
+	r.NoError(err)
+	r.Equal(2, cfg.ChainID)
+
+	cfg, err = getContainerConfigFromFiles(file2, file1)
+	r.NoError(err)
+	r.Equal(1, cfg.ChainID)
+}
+
+func TestMergeConfigFilesMissing(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	base := writeTestConfigFile(t, dir, "base.yml", testBaseConfig)
+
+	_, err := getContainerConfigFromFiles(base, path.Join(dir, "missing.yml"))
+	r.Error(err)
+	r.Contains(err.Error(), "missing.yml")
+}
+
+func TestGetConfigForContainerFromEnv(t *testing.T) {
+	r := require.New(t)
+
+	dir := t.TempDir()
+	base := writeTestConfigFile(t, dir, "base.yml", testBaseConfig)
+	override := writeTestConfigFile(t, dir, "override.yml", "chainId: 1\n")
+	setTestEnv(t, EnvConfigFiles, base+", "+override)
+
+	cfg, err := GetConfigForContainer()
+	r.NoError(err)
+	r.Equal(1, cfg.ChainID)
+	r.Equal("https://polygon.example.com", cfg.Scan.JsonRpc.Url)
+	r.Equal(DefaultContainerFortaDirPath, cfg.FortaDir)
+}
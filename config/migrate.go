@@ -0,0 +1,70 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentConfigVersion is the version of the config schema which this node supports.
+// The config files without a version are considered to be version 0.
+const CurrentConfigVersion = 1
+
+// configMigration upgrades the config values by one version.
+type configMigration func(values map[string]interface{}) error
+
+// configMigrations are indexed by the version that they upgrade from.
+var configMigrations = []configMigration{
+	migrateV0JsonRpcURLs,
+}
+
+// Migrate upgrades the raw YAML config from an older version to the current version.
+func Migrate(raw []byte) ([]byte, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+
+	version := 0
+	if versionVal, ok := values["version"]; ok && versionVal != nil {
+		if version, ok = versionVal.(int); !ok || version < 0 {
+			return nil, fmt.Errorf("invalid config version: %v", versionVal)
+		}
+	}
+	if version > CurrentConfigVersion {
+		return nil, fmt.Errorf("config version %d is newer than supported %d", version, CurrentConfigVersion)
+	}
+	if version == CurrentConfigVersion {
+		return raw, nil
+	}
+
+	for ; version < CurrentConfigVersion; version++ {
+		if err := configMigrations[version](values); err != nil {
+			return nil, fmt.Errorf("failed to migrate the config from version %d: %v", version, err)
+		}
+	}
+	values["version"] = CurrentConfigVersion
+	return yaml.Marshal(values)
+}
+
+// migrateV0JsonRpcURLs converts the JSON-RPC URLs given as plain strings to the JSON-RPC
+// config sections, e.g. "jsonRpc: https://..." becomes "jsonRpc: {url: https://...}".
+func migrateV0JsonRpcURLs(values map[string]interface{}) error {
+	for _, section := range []string{"scan", "trace", "registry", "jsonRpcProxy", "ens"} {
+		sectionValues, ok := values[section].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		switch jsonRpc := sectionValues["jsonRpc"].(type) {
+		case string:
+			sectionValues["jsonRpc"] = map[string]interface{}{"url": jsonRpc}
+		case nil, map[string]interface{}:
+		default:
+			return fmt.Errorf("unexpected value for '%s.jsonRpc': %v", section, jsonRpc)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const testV0Config = `
+chainId: 137
+scan:
+  jsonRpc: https://polygon.example.com
+trace:
+  enabled: true
+  jsonRpc: https://trace.example.com
+registry:
+  jsonRpc:
+    url: https://registry.example.com
+`
+
+func TestMigrateV0(t *testing.T) {
+	r := require.New(t)
+
+	migrated, err := Migrate([]byte(testV0Config))
+	r.NoError(err)
+
+	var cfg Config
+	r.NoError(yaml.Unmarshal(migrated, &cfg))
+	r.Equal(CurrentConfigVersion, cfg.Version)
+	r.Equal(137, cfg.ChainID)
+	r.Equal("https://polygon.example.com", cfg.Scan.JsonRpc.Url)
+	r.Equal("https://trace.example.com", cfg.Trace.JsonRpc.Url)
+	r.True(cfg.Trace.Enabled)
+	r.Equal("https://registry.example.com", cfg.Registry.JsonRpc.Url)
+
+	// migrating again does not change anything
+	migratedAgain, err := Migrate(migrated)
+	r.NoError(err)
+	r.Equal(string(migrated), string(migratedAgain))
+}
+
+func TestMigrateCurrentVersion(t *testing.T) {
+	r := require.New(t)
+
+	raw := []byte(fmt.Sprintf("version: %d\nchainId: 137\n", CurrentConfigVersion))
+	migrated, err := Migrate(raw)
+	r.NoError(err)
+	r.Equal(raw, migrated)
+}
+
+func TestMigrateEmpty(t *testing.T) {
+	r := require.New(t)
+
+	migrated, err := Migrate(nil)
+	r.NoError(err)
+
+	var cfg Config
+	r.NoError(yaml.Unmarshal(migrated, &cfg))
+	r.Equal(CurrentConfigVersion, cfg.Version)
+}
+
+func TestMigrateNewerVersion(t *testing.T) {
+	r := require.New(t)
+
+	_, err := Migrate([]byte(fmt.Sprintf("version: %d\n", CurrentConfigVersion+1)))
+	r.EqualError(err, fmt.Sprintf("config version %d is newer than supported %d", CurrentConfigVersion+1, CurrentConfigVersion))
+}
+
+func TestMigrateInvalid(t *testing.T) {
+	r := require.New(t)
+
+	_, err := Migrate([]byte("version: latest\n"))
+	r.Error(err)
+
+	_, err = Migrate([]byte("scan:\n  jsonRpc:\n    - https://polygon.example.com\n"))
+	r.Error(err)
+}
+
+func TestGetContainerConfigMigrates(t *testing.T) {
+	r := require.New(t)
+
+	configPath := writeTestConfigFile(t, t.TempDir(), "config.yml", testV0Config)
+	cfg, err := getContainerConfigFromFiles(configPath)
+	r.NoError(err)
+	r.Equal(CurrentConfigVersion, cfg.Version)
+	r.Equal("https://polygon.example.com", cfg.Scan.JsonRpc.Url)
+
+	newerPath := writeTestConfigFile(t, path.Dir(configPath), "newer.yml", "version: 100\n")
+	_, err = getContainerConfigFromFiles(configPath, newerPath)
+	r.Error(err)
+	r.Contains(err.Error(), "newer than supported")
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Parse reads a YAML or JSON config from the reader, migrates it to the current version,
+// resolves the active profile, applies the defaults, expands the env vars and validates the result.
+func Parse(r io.Reader) (Config, error) {
+	return parse(r, nil)
+}
+
+// parse is Parse with an overlay which is applied to the raw values after the profile is
+// resolved, so that the overlay takes precedence over both the base and the profile values.
+func parse(r io.Reader, overlay func([]byte) ([]byte, error)) (Config, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to read config: %v", err)
+	}
+	b, err = Migrate(b)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to migrate config: %v", err)
+	}
+	b, err = resolveProfile(b)
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to resolve config profile: %v", err)
+	}
+	if overlay != nil {
+		if b, err = overlay(b); err != nil {
+			return Config{}, err
+		}
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(b, &cfg); err != nil {
+		return Config{}, fmt.Errorf("failed to parse config: %v", err)
+	}
+	if err := ApplyDefaults(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := expandEnvVars(&cfg); err != nil {
+		return Config{}, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
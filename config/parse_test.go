@@ -0,0 +1,55 @@
+package config
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseYAML(t *testing.T) {
+	r := require.New(t)
+
+	cfg, err := Parse(strings.NewReader(`
+chainId: 137
+scan:
+  jsonRpc: https://polygon.example.com
+log:
+  level: debug
+`))
+	r.NoError(err)
+	r.Equal(137, cfg.ChainID)
+	r.Equal("https://polygon.example.com", cfg.Scan.JsonRpc.Url)
+	r.Equal("debug", cfg.Log.Level)
+	r.Equal(600, cfg.Services.StartTimeoutSeconds)
+}
+
+func TestParseJSON(t *testing.T) {
+	r := require.New(t)
+
+	cfg, err := Parse(strings.NewReader(`{"version": 1, "chainId": 1, "scan": {"jsonRpc": {"url": "https://mainnet.example.com"}}}`))
+	r.NoError(err)
+	r.Equal(1, cfg.ChainID)
+	r.Equal("https://mainnet.example.com", cfg.Scan.JsonRpc.Url)
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, testCase := range []struct {
+		name    string
+		input   string
+		errText string
+	}{
+		{name: "malformed yaml", input: "chainId: [1", errText: "failed to migrate config"},
+		{name: "malformed json", input: `{"chainId": 1`, errText: "failed to migrate config"},
+		{name: "wrong type", input: "version: 1\nchainId: mainnet", errText: "failed to parse config"},
+		{name: "invalid value", input: "services:\n  startTimeoutSeconds: -1", errText: "services.startTimeoutSeconds"},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := require.New(t)
+
+			_, err := Parse(strings.NewReader(testCase.input))
+			r.Error(err)
+			r.Contains(err.Error(), testCase.errText)
+		})
+	}
+}
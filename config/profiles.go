@@ -0,0 +1,49 @@
+package config
+
+import (
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resolveProfile overlays the values of the active profile onto the base values in the same
+// way as the config files are merged and removes the profiles. The profile in the env var
+// takes precedence over the profile in the config.
+func resolveProfile(raw []byte) ([]byte, error) {
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(raw, &values); err != nil {
+		return nil, err
+	}
+	if values == nil {
+		values = make(map[string]interface{})
+	}
+	profiles, hasProfiles := values["profiles"]
+	delete(values, "profiles")
+
+	name, _ := values["profile"].(string)
+	if envName := os.Getenv(EnvConfigProfile); len(envName) > 0 {
+		name = envName
+	}
+	if len(name) == 0 {
+		if !hasProfiles {
+			return raw, nil
+		}
+		return yaml.Marshal(values)
+	}
+
+	profileMaps, _ := profiles.(map[string]interface{})
+	profileValues, ok := profileMaps[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown config profile '%s'", name)
+	}
+	if profileValues != nil {
+		profileMap, ok := profileValues.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("invalid config profile '%s': expected a map", name)
+		}
+		mergeConfigMaps(values, profileMap)
+	}
+	values["profile"] = name
+	return yaml.Marshal(values)
+}
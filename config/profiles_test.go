@@ -0,0 +1,93 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testProfilesConfig = `
+version: 1
+chainId: 137
+log:
+  level: info
+scan:
+  jsonRpc:
+    url: https://prod.example.com
+profiles:
+  dev:
+    log:
+      level: debug
+    scan:
+      jsonRpc:
+        url: https://dev.example.com
+  staging:
+    scan:
+      jsonRpc:
+        url: https://staging.example.com
+  prod:
+`
+
+func TestConfigProfiles(t *testing.T) {
+	for _, testCase := range []struct {
+		profile  string
+		logLevel string
+		scanURL  string
+	}{
+		{profile: "", logLevel: "info", scanURL: "https://prod.example.com"},
+		{profile: "dev", logLevel: "debug", scanURL: "https://dev.example.com"},
+		{profile: "staging", logLevel: "info", scanURL: "https://staging.example.com"},
+		{profile: "prod", logLevel: "info", scanURL: "https://prod.example.com"},
+	} {
+		t.Run(testCase.profile, func(t *testing.T) {
+			r := require.New(t)
+
+			input := testProfilesConfig
+			if len(testCase.profile) > 0 {
+				input += "profile: " + testCase.profile + "\n"
+			}
+			cfg, err := Parse(strings.NewReader(input))
+			r.NoError(err)
+			r.Equal(testCase.profile, cfg.Profile)
+			r.Equal(testCase.logLevel, cfg.Log.Level)
+			r.Equal(testCase.scanURL, cfg.Scan.JsonRpc.Url)
+			// the base values which are not in the profile are kept
+			r.Equal(137, cfg.ChainID)
+		})
+	}
+}
+
+func TestConfigProfileFromEnv(t *testing.T) {
+	r := require.New(t)
+
+	os.Setenv(EnvConfigProfile, "dev")
+	defer os.Unsetenv(EnvConfigProfile)
+
+	cfg, err := Parse(strings.NewReader(testProfilesConfig + "profile: staging\n"))
+	r.NoError(err)
+	r.Equal("dev", cfg.Profile)
+	r.Equal("https://dev.example.com", cfg.Scan.JsonRpc.Url)
+}
+
+func TestUnknownConfigProfile(t *testing.T) {
+	r := require.New(t)
+
+	_, err := Parse(strings.NewReader(testProfilesConfig + "profile: qa\n"))
+	r.Error(err)
+	r.Contains(err.Error(), "unknown config profile 'qa'")
+
+	_, err = Parse(strings.NewReader("version: 1\nprofile: dev\n"))
+	r.Error(err)
+	r.Contains(err.Error(), "unknown config profile 'dev'")
+}
+
+func TestInvalidConfigProfile(t *testing.T) {
+	r := require.New(t)
+
+	// the profile is resolved before the validation
+	_, err := Parse(strings.NewReader("version: 1\nprofile: dev\nprofiles:\n  dev:\n    log:\n      level: loud\n"))
+	r.Error(err)
+	r.Contains(err.Error(), "log.level")
+}
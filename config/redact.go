@@ -0,0 +1,113 @@
+package config
+
+import (
+	"encoding/json"
+	"net/url"
+)
+
+const redactedValue = "REDACTED"
+
+// Redacted returns a copy of the config which is safe to log. The secrets in the URLs,
+// the headers and the passwords are masked but the hosts are kept for debugging.
+func (cfg Config) Redacted() Config {
+	cfg.Passphrase = redactString(cfg.Passphrase)
+
+	cfg.Scan.JsonRpc = cfg.Scan.JsonRpc.redacted()
+	cfg.Trace.JsonRpc = cfg.Trace.JsonRpc.redacted()
+	cfg.JsonRpcProxy.JsonRpc = cfg.JsonRpcProxy.JsonRpc.redacted()
+
+	cfg.Registry.JsonRpc = cfg.Registry.JsonRpc.redacted()
+	cfg.Registry.IPFS = cfg.Registry.IPFS.redacted()
+	cfg.Registry.Password = redactString(cfg.Registry.Password)
+
+	cfg.Publish.APIURL = RedactURL(cfg.Publish.APIURL)
+	cfg.Publish.IPFS = cfg.Publish.IPFS.redacted()
+
+	cfg.ENSConfig.JsonRpc = cfg.ENSConfig.JsonRpc.redacted()
+	if cfg.ENSConfig.JsonRpcUrls != nil {
+		urls := make([]string, len(cfg.ENSConfig.JsonRpcUrls))
+		for i, rawURL := range cfg.ENSConfig.JsonRpcUrls {
+			urls[i] = RedactURL(rawURL)
+		}
+		cfg.ENSConfig.JsonRpcUrls = urls
+	}
+
+	cfg.TelemetryConfig.URL = RedactURL(cfg.TelemetryConfig.URL)
+	cfg.TelemetryConfig.CustomURL = RedactURL(cfg.TelemetryConfig.CustomURL)
+	cfg.AgentLogsConfig.URL = RedactURL(cfg.AgentLogsConfig.URL)
+
+	if cfg.Services.ReadinessProbes != nil {
+		probes := make(map[string]ReadinessProbeConfig, len(cfg.Services.ReadinessProbes))
+		for name, probeCfg := range cfg.Services.ReadinessProbes {
+			probeCfg.URL = RedactURL(probeCfg.URL)
+			probes[name] = probeCfg
+		}
+		cfg.Services.ReadinessProbes = probes
+	}
+
+	cfg.LocalModeConfig.WebhookURL = RedactURL(cfg.LocalModeConfig.WebhookURL)
+	if registryCfg := cfg.LocalModeConfig.ContainerRegistry; registryCfg != nil {
+		cfg.LocalModeConfig.ContainerRegistry = &ContainerRegistryConfig{
+			Username: registryCfg.Username,
+			Password: redactString(registryCfg.Password),
+		}
+	}
+
+	return cfg
+}
+
+// String implements the fmt.Stringer interface and prints the redacted config. The JSON
+// encoding is not redacted so the config should be logged by using Redacted or String.
+func (cfg Config) String() string {
+	b, _ := json.Marshal(cfg.Redacted())
+	return string(b)
+}
+
+func (jsonRpcCfg JsonRpcConfig) redacted() JsonRpcConfig {
+	jsonRpcCfg.Url = RedactURL(jsonRpcCfg.Url)
+	if jsonRpcCfg.Headers != nil {
+		headers := make(map[string]string, len(jsonRpcCfg.Headers))
+		for key := range jsonRpcCfg.Headers {
+			headers[key] = redactedValue
+		}
+		jsonRpcCfg.Headers = headers
+	}
+	return jsonRpcCfg
+}
+
+func (ipfsCfg IPFSConfig) redacted() IPFSConfig {
+	ipfsCfg.GatewayURL = RedactURL(ipfsCfg.GatewayURL)
+	ipfsCfg.APIURL = RedactURL(ipfsCfg.APIURL)
+	ipfsCfg.Password = redactString(ipfsCfg.Password)
+	return ipfsCfg
+}
+
+func redactString(s string) string {
+	if len(s) == 0 {
+		return s
+	}
+	return redactedValue
+}
+
+// RedactURL masks the userinfo and the query values of the URL. The whole value is masked
+// if it cannot be parsed, as it is not possible to tell which part of it is secret.
+func RedactURL(rawURL string) string {
+	if len(rawURL) == 0 {
+		return rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return redactedValue
+	}
+	if u.User != nil {
+		u.User = url.User(redactedValue)
+	}
+	if len(u.RawQuery) > 0 {
+		query := u.Query()
+		for key := range query {
+			query.Set(key, redactedValue)
+		}
+		u.RawQuery = query.Encode()
+	}
+	return u.String()
+}
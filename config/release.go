@@ -1,6 +1,8 @@
 package config
 
 import (
+	"fmt"
+
 	"github.com/forta-network/forta-core-go/release"
 )
 
@@ -9,8 +11,40 @@ var (
 	CommitHash = ""
 	ReleaseCid = ""
 	Version    = ""
+	BuildDate  = ""
 )
 
+// devBuild is used in the build info in place of the values which were not injected.
+const devBuild = "dev"
+
+// BuildInfo is the version info of the running build.
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildDate string `json:"buildDate"`
+}
+
+func (info BuildInfo) String() string {
+	return fmt.Sprintf("%s (commit: %s, built: %s)", info.Version, info.Commit, info.BuildDate)
+}
+
+// GetBuildInfo returns the build info from the build vars. The values which were not
+// injected are reported as "dev".
+func GetBuildInfo() BuildInfo {
+	return BuildInfo{
+		Version:   valueOrDev(Version),
+		Commit:    valueOrDev(CommitHash),
+		BuildDate: valueOrDev(BuildDate),
+	}
+}
+
+func valueOrDev(value string) string {
+	if len(value) == 0 {
+		return devBuild
+	}
+	return value
+}
+
 // GetBuildReleaseSummary returns the build summary from build vars.
 func GetBuildReleaseSummary() (*release.ReleaseSummary, bool) {
 	if len(CommitHash) == 0 {
@@ -0,0 +1,20 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetBuildInfo(t *testing.T) {
+	r := require.New(t)
+
+	r.Equal(BuildInfo{Version: "dev", Commit: "dev", BuildDate: "dev"}, GetBuildInfo())
+	r.Equal("dev (commit: dev, built: dev)", GetBuildInfo().String())
+
+	defer func(version, commitHash, buildDate string) {
+		Version, CommitHash, BuildDate = version, commitHash, buildDate
+	}(Version, CommitHash, BuildDate)
+	Version, CommitHash, BuildDate = "v0.1.2", "abcdef", "2021-06-01T00:00:00Z"
+	r.Equal(BuildInfo{Version: "v0.1.2", Commit: "abcdef", BuildDate: "2021-06-01T00:00:00Z"}, GetBuildInfo())
+}
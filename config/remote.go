@@ -0,0 +1,108 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultRemoteConfigTimeout is how long fetching a remote config is allowed to take.
+const DefaultRemoteConfigTimeout = time.Second * 30
+
+const (
+	defaultIPFSGatewayURL = "https://ipfs.forta.network"
+	remoteConfigCacheDir  = "remote-config"
+)
+
+// configReader reads the local config files and fetches the remote configs. The last fetched
+// remote configs are cached locally and used if fetching fails.
+type configReader struct {
+	client         *http.Client
+	ipfsGatewayURL string
+	cacheDir       string
+}
+
+var defaultConfigReader = &configReader{
+	client:         &http.Client{Timeout: DefaultRemoteConfigTimeout},
+	ipfsGatewayURL: defaultIPFSGatewayURL,
+	cacheDir:       path.Join(DefaultContainerFortaDirPath, remoteConfigCacheDir),
+}
+
+func isRemoteConfigPath(configPath string) bool {
+	for _, prefix := range []string{"http://", "https://", "ipfs://"} {
+		if strings.HasPrefix(configPath, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (reader *configReader) read(configPath string) ([]byte, error) {
+	if !isRemoteConfigPath(configPath) {
+		b, err := ioutil.ReadFile(configPath)
+		if os.IsNotExist(err) {
+			return nil, fmt.Errorf("config file not found: %s", configPath)
+		}
+		return b, err
+	}
+
+	b, fetchErr := reader.fetch(configPath)
+	if fetchErr == nil {
+		if err := reader.writeCache(configPath, b); err != nil {
+			log.WithError(err).WithField("uri", configPath).Warn("failed to cache the remote config")
+		}
+		return b, nil
+	}
+
+	b, err := ioutil.ReadFile(reader.cachePath(configPath))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the remote config %s: %v", configPath, fetchErr)
+	}
+	log.WithError(fetchErr).WithField("uri", configPath).Warn("failed to fetch the remote config - using the cached config")
+	return b, nil
+}
+
+func (reader *configReader) fetch(uri string) ([]byte, error) {
+	if strings.HasPrefix(uri, "ipfs://") {
+		uri = fmt.Sprintf("%s/ipfs/%s", strings.TrimSuffix(reader.ipfsGatewayURL, "/"), strings.TrimPrefix(uri, "ipfs://"))
+	}
+	resp, err := reader.client.Get(uri)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	// make sure that a broken config does not replace the last good one in the cache
+	if _, err := Parse(bytes.NewReader(b)); err != nil {
+		return nil, fmt.Errorf("invalid remote config: %v", err)
+	}
+	return b, nil
+}
+
+func (reader *configReader) cachePath(uri string) string {
+	hash := sha256.Sum256([]byte(uri))
+	return path.Join(reader.cacheDir, hex.EncodeToString(hash[:])+".yml")
+}
+
+func (reader *configReader) writeCache(uri string, b []byte) error {
+	if err := os.MkdirAll(reader.cacheDir, 0755); err != nil {
+		return err
+	}
+	// the config can have credentials
+	return ioutil.WriteFile(reader.cachePath(uri), b, 0600)
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+const testRemoteConfig = `
+chainId: 137
+scan:
+  jsonRpc:
+    url: https://polygon.example.com
+`
+
+type testConfigServer struct {
+	mu     sync.Mutex
+	status int
+	body   string
+	delay  time.Duration
+	paths  []string
+}
+
+func (server *testConfigServer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	server.mu.Lock()
+	status, body, delay := server.status, server.body, server.delay
+	server.paths = append(server.paths, req.URL.Path)
+	server.mu.Unlock()
+
+	time.Sleep(delay)
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
+
+func (server *testConfigServer) set(status int, body string, delay time.Duration) {
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	server.status, server.body, server.delay = status, body, delay
+}
+
+func newTestConfigReader(t *testing.T, gatewayURL string, timeout time.Duration) *configReader {
+	return &configReader{
+		client:         &http.Client{Timeout: timeout},
+		ipfsGatewayURL: gatewayURL,
+		cacheDir:       t.TempDir(),
+	}
+}
+
+func TestRemoteConfig(t *testing.T) {
+	r := require.New(t)
+
+	configServer := &testConfigServer{status: http.StatusOK, body: testRemoteConfig}
+	server := httptest.NewServer(configServer)
+	defer server.Close()
+
+	reader := newTestConfigReader(t, server.URL, time.Second)
+	cfg, err := getContainerConfig(reader, server.URL+"/config.yml")
+	r.NoError(err)
+	r.Equal(137, cfg.ChainID)
+	r.Equal("https://polygon.example.com", cfg.Scan.JsonRpc.Url)
+	// defaults are applied to the remote config too
+	r.Equal("info", cfg.Log.Level)
+}
+
+func TestRemoteConfigIPFS(t *testing.T) {
+	r := require.New(t)
+
+	configServer := &testConfigServer{status: http.StatusOK, body: testRemoteConfig}
+	server := httptest.NewServer(configServer)
+	defer server.Close()
+
+	reader := newTestConfigReader(t, server.URL, time.Second)
+	cfg, err := getContainerConfig(reader, "ipfs://bafybeibvkqkf7i3c5ouehviwjb2dzbukgqied3cg36axl7gzm23r6ielnu")
+	r.NoError(err)
+	r.Equal(137, cfg.ChainID)
+	r.Equal([]string{"/ipfs/bafybeibvkqkf7i3c5ouehviwjb2dzbukgqied3cg36axl7gzm23r6ielnu"}, configServer.paths)
+}
+
+func TestRemoteConfigTimeout(t *testing.T) {
+	r := require.New(t)
+
+	configServer := &testConfigServer{status: http.StatusOK, body: testRemoteConfig, delay: time.Millisecond * 200}
+	server := httptest.NewServer(configServer)
+	defer server.Close()
+
+	reader := newTestConfigReader(t, server.URL, time.Millisecond*50)
+	_, err := getContainerConfig(reader, server.URL+"/config.yml")
+	r.Error(err)
+	r.Contains(err.Error(), "failed to fetch the remote config")
+}
+
+func TestRemoteConfigCachedFallback(t *testing.T) {
+	r := require.New(t)
+
+	configServer := &testConfigServer{status: http.StatusOK, body: testRemoteConfig}
+	server := httptest.NewServer(configServer)
+	defer server.Close()
+
+	reader := newTestConfigReader(t, server.URL, time.Millisecond*50)
+	uri := server.URL + "/config.yml"
+	_, err := getContainerConfig(reader, uri)
+	r.NoError(err)
+	info, err := os.Stat(reader.cachePath(uri))
+	r.NoError(err)
+	r.Equal(os.FileMode(0600), info.Mode().Perm())
+
+	for _, failure := range []struct {
+		status int
+		body   string
+		delay  time.Duration
+	}{
+		{status: http.StatusInternalServerError},
+		{status: http.StatusOK, body: "chainId: ["},
+		// the configs which parse but are not valid do not replace the cached config
+		{status: http.StatusOK, body: testRemoteConfig + "log:\n  level: loud\n"},
+		{status: http.StatusOK, body: testRemoteConfig + "version: 99\n"},
+		{status: http.StatusOK, body: testRemoteConfig, delay: time.Millisecond * 200},
+	} {
+		configServer.set(failure.status, failure.body, failure.delay)
+		cfg, err := getContainerConfig(reader, uri)
+		r.NoError(err)
+		r.Equal(137, cfg.ChainID)
+	}
+}
@@ -2,10 +2,8 @@ package config
 
 import (
 	"math/big"
-	"os"
 
 	log "github.com/sirupsen/logrus"
-	"gopkg.in/yaml.v3"
 )
 
 func ParseBigInt(num int) *big.Int {
@@ -26,21 +24,27 @@ func InitLogLevel(cfg Config) error {
 	} else {
 		log.SetLevel(log.InfoLevel)
 	}
-	log.SetFormatter(&log.TextFormatter{
+	log.SetFormatter(cfg.Log.NewFormatter(&log.TextFormatter{
 		FullTimestamp: true,
-	})
+	}))
 	return nil
 }
 
-func readFile(filename string, cfg *Config) error {
-	f, err := os.Open(filename)
-	if f != nil {
-		defer f.Close()
-	}
-	if err != nil {
-		return err
-	}
+// Log formats
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
 
-	decoder := yaml.NewDecoder(f)
-	return decoder.Decode(cfg)
+// NewFormatter creates a log formatter for the configured format or returns the given default
+// if the format is not set.
+func (logCfg LogConfig) NewFormatter(defaultFormatter log.Formatter) log.Formatter {
+	switch logCfg.Format {
+	case LogFormatText:
+		return &log.TextFormatter{FullTimestamp: true}
+	case LogFormatJSON:
+		return &log.JSONFormatter{}
+	default:
+		return defaultFormatter
+	}
 }
@@ -0,0 +1,32 @@
+package config
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogConfigNewFormatter(t *testing.T) {
+	r := require.New(t)
+
+	defaultFormatter := &log.JSONFormatter{PrettyPrint: true}
+
+	r.Equal(defaultFormatter, LogConfig{}.NewFormatter(defaultFormatter))
+	r.IsType(&log.TextFormatter{}, LogConfig{Format: LogFormatText}.NewFormatter(defaultFormatter))
+	r.IsType(&log.JSONFormatter{}, LogConfig{Format: LogFormatJSON}.NewFormatter(defaultFormatter))
+	r.NotEqual(defaultFormatter, LogConfig{Format: LogFormatJSON}.NewFormatter(defaultFormatter))
+}
+
+func TestInitLogLevelFormat(t *testing.T) {
+	r := require.New(t)
+
+	defer log.SetLevel(log.GetLevel())
+	defer log.SetFormatter(log.StandardLogger().Formatter)
+
+	r.NoError(InitLogLevel(Config{}))
+	r.IsType(&log.TextFormatter{}, log.StandardLogger().Formatter)
+
+	r.NoError(InitLogLevel(Config{Log: LogConfig{Format: LogFormatJSON}}))
+	r.IsType(&log.JSONFormatter{}, log.StandardLogger().Formatter)
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/hashicorp/go-multierror"
+	log "github.com/sirupsen/logrus"
+)
+
+func newValidator() *validator.Validate {
+	validate := validator.New()
+
+	// Use the YAML names while validating the struct.
+	validate.RegisterTagNameFunc(func(fld reflect.StructField) string {
+		name := strings.SplitN(fld.Tag.Get("yaml"), ",", 2)[0]
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	// replace the URL check so that the problem can be described
+	_ = validate.RegisterValidation("url", func(fl validator.FieldLevel) bool {
+		return checkURL(fl.Field().String()) == nil
+	})
+	return validate
+}
+
+// supportedURLSchemes are the schemes which the URLs in the config can use.
+var supportedURLSchemes = map[string]bool{
+	"http":  true,
+	"https": true,
+	"ws":    true,
+	"wss":   true,
+}
+
+// checkURL tells what is wrong with the URL, if anything.
+func checkURL(value string) error {
+	if !strings.Contains(value, "://") {
+		return errors.New("missing scheme (did you forget https://?)")
+	}
+	u, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+	if !supportedURLSchemes[strings.ToLower(u.Scheme)] {
+		return fmt.Errorf("unsupported scheme '%s'", u.Scheme)
+	}
+	if len(u.Hostname()) == 0 {
+		return errors.New("missing host")
+	}
+	return nil
+}
+
+// Validate checks the config and returns all of the problems together.
+func (cfg Config) Validate() error {
+	var errs *multierror.Error
+
+	if err := newValidator().Struct(&cfg); err != nil {
+		validationErrs, ok := err.(validator.ValidationErrors)
+		if !ok {
+			return err
+		}
+		for _, validationErr := range validationErrs {
+			// trim the "Config." prefix
+			field := strings.SplitN(validationErr.Namespace(), ".", 2)[1]
+			if validationErr.Tag() == "url" {
+				errs = multierror.Append(errs, fmt.Errorf("invalid value for '%s': %v", field, checkURL(fmt.Sprint(validationErr.Value()))))
+				continue
+			}
+			errs = multierror.Append(errs, fmt.Errorf("invalid value for '%s': failed on '%s'", field, validationErr.Tag()))
+		}
+	}
+
+	if len(cfg.Log.Level) > 0 {
+		if _, err := log.ParseLevel(cfg.Log.Level); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid value for 'log.level': %v", err))
+		}
+	}
+
+	for name, level := range cfg.Log.ServiceLevels {
+		if _, err := log.ParseLevel(level); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("invalid value for 'log.serviceLevels.%s': %v", name, err))
+		}
+	}
+
+	// the drain period is a part of the shutdown
+	if servicesCfg := cfg.Services; servicesCfg.ShutdownTimeoutSeconds > 0 &&
+		servicesCfg.DrainPeriodSeconds >= servicesCfg.ShutdownTimeoutSeconds {
+		errs = multierror.Append(errs, errors.New("invalid value for 'services.drainPeriodSeconds': must be shorter than 'services.shutdownTimeoutSeconds'"))
+	}
+
+	return errs.ErrorOrNil()
+}
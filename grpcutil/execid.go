@@ -0,0 +1,55 @@
+package grpcutil
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/forta-network/forta-node/services"
+)
+
+// ExecIDMetadataKey is the gRPC metadata key which carries the exec ID.
+const ExecIDMetadataKey = "forta-exec-id"
+
+// UnaryClientInterceptor adds the exec ID from the context to the outgoing metadata.
+func UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context, method string, req, reply interface{},
+		cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption,
+	) error {
+		if execID, ok := services.ExecIDOk(ctx); ok {
+			ctx = metadata.AppendToOutgoingContext(ctx, ExecIDMetadataKey, execID)
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+// UnaryServerInterceptor puts the exec ID from the incoming metadata to the context so that
+// it can be retrieved with services.ExecID. A new exec ID is generated if the metadata lacks it
+// or if it is not a valid UUID.
+func UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		return handler(services.WithExecID(ctx, incomingExecID(ctx)), req)
+	}
+}
+
+func incomingExecID(ctx context.Context) string {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for _, mdExecID := range md.Get(ExecIDMetadataKey) {
+			if len(mdExecID) == 0 {
+				continue
+			}
+			execID, err := uuid.Parse(mdExecID)
+			if err == nil {
+				return execID.String()
+			}
+			log.WithError(err).WithField("execId", mdExecID).Warn("invalid exec ID in metadata - generating new")
+		}
+	}
+	return uuid.New().String()
+}
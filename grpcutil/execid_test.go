@@ -0,0 +1,91 @@
+package grpcutil
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/forta-network/forta-node/services"
+)
+
+const testExecID = "8d4f5e2a-2c4b-11ee-be56-0242ac120002"
+
+type execIDRecorder struct {
+	healthpb.UnimplementedHealthServer
+	execIDs chan string
+}
+
+func (recorder *execIDRecorder) Check(
+	ctx context.Context, req *healthpb.HealthCheckRequest,
+) (*healthpb.HealthCheckResponse, error) {
+	recorder.execIDs <- services.ExecID(ctx)
+	return &healthpb.HealthCheckResponse{Status: healthpb.HealthCheckResponse_SERVING}, nil
+}
+
+func startTestServer(t *testing.T) (healthpb.HealthClient, chan string) {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(grpc.UnaryInterceptor(UnaryServerInterceptor()))
+	recorder := &execIDRecorder{execIDs: make(chan string, 1)}
+	healthpb.RegisterHealthServer(server, recorder)
+	go server.Serve(listener)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithUnaryInterceptor(UnaryClientInterceptor()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		conn.Close()
+	})
+	return healthpb.NewHealthClient(conn), recorder.execIDs
+}
+
+func TestExecIDRoundTrip(t *testing.T) {
+	r := require.New(t)
+
+	client, execIDs := startTestServer(t)
+
+	ctx := services.WithExecID(context.Background(), testExecID)
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	r.NoError(err)
+	r.Equal(testExecID, <-execIDs)
+}
+
+func TestExecIDGeneratedByServer(t *testing.T) {
+	r := require.New(t)
+
+	client, execIDs := startTestServer(t)
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+	r.NoError(err)
+	execID := <-execIDs
+	_, err = uuid.Parse(execID)
+	r.NoError(err)
+	r.NotEqual(testExecID, execID)
+}
+
+func TestInvalidExecIDReplacedByServer(t *testing.T) {
+	r := require.New(t)
+
+	client, execIDs := startTestServer(t)
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), ExecIDMetadataKey, "not-a-uuid")
+	_, err := client.Check(ctx, &healthpb.HealthCheckRequest{})
+	r.NoError(err)
+	execID := <-execIDs
+	_, err = uuid.Parse(execID)
+	r.NoError(err)
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// AsyncLogWriter writes the formatted log entries in the background through a bounded buffer.
+// When the buffer is full, the entries are dropped and counted instead of blocking the caller.
+// The drop count is logged periodically so that the loss is visible.
+type AsyncLogWriter struct {
+	logger  *log.Logger
+	out     io.Writer
+	entries chan []byte
+	ticker  *time.Ticker
+
+	dropped    int64
+	unreported int64
+
+	closed    bool
+	closeOnce sync.Once
+	done      chan struct{}
+	mu        sync.RWMutex
+}
+
+// NewAsyncLogWriter creates a writer which writes to the current output of the logger and
+// uses the formatter of the logger for the drop summaries. It does not replace the output.
+func NewAsyncLogWriter(logger *log.Logger, bufferSize int, summaryInterval time.Duration) *AsyncLogWriter {
+	writer := &AsyncLogWriter{
+		logger:  logger,
+		out:     logger.Out,
+		entries: make(chan []byte, bufferSize),
+		ticker:  time.NewTicker(summaryInterval),
+		done:    make(chan struct{}),
+	}
+	go writer.writeLoop()
+	return writer
+}
+
+// installAsyncLogging makes the logger write through an async writer if it is enabled in the
+// config. The returned function flushes the buffer and should be called before exiting.
+func installAsyncLogging(logger *log.Logger, asyncCfg config.AsyncLogConfig) func() {
+	if !asyncCfg.Enable {
+		return func() {}
+	}
+	writer := NewAsyncLogWriter(logger, asyncCfg.BufferSize, time.Duration(asyncCfg.DropSummaryIntervalSeconds)*time.Second)
+	logger.SetOutput(writer)
+	return func() {
+		_ = writer.Close()
+	}
+}
+
+// Write implements the io.Writer interface. It never blocks on the output.
+func (writer *AsyncLogWriter) Write(p []byte) (int, error) {
+	writer.mu.RLock()
+	defer writer.mu.RUnlock()
+	if writer.closed {
+		return writer.out.Write(p)
+	}
+	// the logger reuses the buffer after writing
+	entry := append([]byte(nil), p...)
+	select {
+	case writer.entries <- entry:
+	default:
+		atomic.AddInt64(&writer.dropped, 1)
+		atomic.AddInt64(&writer.unreported, 1)
+	}
+	return len(p), nil
+}
+
+// Dropped returns how many entries were dropped in total.
+func (writer *AsyncLogWriter) Dropped() int64 {
+	return atomic.LoadInt64(&writer.dropped)
+}
+
+// Close writes the buffered entries and the last drop summary. The entries which are written
+// after closing go to the output directly.
+func (writer *AsyncLogWriter) Close() error {
+	writer.closeOnce.Do(func() {
+		writer.mu.Lock()
+		writer.closed = true
+		close(writer.entries)
+		writer.mu.Unlock()
+		<-writer.done
+	})
+	return nil
+}
+
+func (writer *AsyncLogWriter) writeLoop() {
+	defer close(writer.done)
+	defer writer.ticker.Stop()
+	for {
+		select {
+		case entry, ok := <-writer.entries:
+			if !ok {
+				writer.writeDropSummary()
+				return
+			}
+			_, _ = writer.out.Write(entry)
+		case <-writer.ticker.C:
+			writer.writeDropSummary()
+		}
+	}
+}
+
+// writeDropSummary writes how many entries were dropped since the last summary. It writes to
+// the output directly so that the summary itself is not dropped.
+func (writer *AsyncLogWriter) writeDropSummary() {
+	dropped := atomic.SwapInt64(&writer.unreported, 0)
+	if dropped == 0 {
+		return
+	}
+	entry := log.NewEntry(writer.logger).WithField("dropped", dropped)
+	entry.Time = time.Now()
+	entry.Level = log.WarnLevel
+	entry.Message = "dropped log entries because the log buffer was full"
+	summary, err := entry.Bytes()
+	if err != nil || len(summary) == 0 {
+		return
+	}
+	_, _ = writer.out.Write(summary)
+}
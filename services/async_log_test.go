@@ -0,0 +1,104 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingWriter blocks the writes until it is released.
+type blockingWriter struct {
+	release chan struct{}
+	buf     bytes.Buffer
+	mu      sync.Mutex
+}
+
+func (w *blockingWriter) Write(p []byte) (int, error) {
+	<-w.release
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *blockingWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func requireTaken(r *require.Assertions, writer *AsyncLogWriter) {
+	r.Eventually(func() bool {
+		return len(writer.entries) == 0
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestAsyncLogWriterDoesNotBlock(t *testing.T) {
+	r := require.New(t)
+
+	out := &blockingWriter{release: make(chan struct{})}
+	logger := logrus.New()
+	logger.SetOutput(out)
+	logger.SetFormatter(&logrus.TextFormatter{DisableTimestamp: true})
+	writer := NewAsyncLogWriter(logger, 2, time.Hour)
+	logger.SetOutput(writer)
+
+	// the first entry gets stuck in the output
+	logger.Info("starting service")
+	requireTaken(r, writer)
+
+	logged := make(chan struct{})
+	go func() {
+		for i := 0; i < 9; i++ {
+			logger.Info("starting service")
+		}
+		close(logged)
+	}()
+	select {
+	case <-logged:
+	case <-time.After(time.Second):
+		r.FailNow("logging blocked on the full buffer")
+	}
+	// one entry is being written and two are buffered
+	r.Equal(int64(7), writer.Dropped())
+
+	close(out.release)
+	r.NoError(writer.Close())
+	r.Equal(3, strings.Count(out.String(), "starting service"))
+	r.Contains(out.String(), "dropped log entries because the log buffer was full")
+	r.Contains(out.String(), "dropped=7")
+
+	// the writes go to the output directly after closing
+	logger.Info("exiting")
+	r.Contains(out.String(), "exiting")
+}
+
+func TestAsyncLogWriterDropSummary(t *testing.T) {
+	r := require.New(t)
+
+	out := &blockingWriter{release: make(chan struct{})}
+	logger := logrus.New()
+	logger.SetOutput(out)
+	writer := NewAsyncLogWriter(logger, 1, time.Millisecond*20)
+	defer writer.Close()
+
+	_, err := writer.Write([]byte("entry\n"))
+	r.NoError(err)
+	requireTaken(r, writer)
+	for i := 0; i < 4; i++ {
+		_, err := writer.Write([]byte("entry\n"))
+		r.NoError(err)
+	}
+	r.Equal(int64(3), writer.Dropped())
+
+	// the summary is written periodically without waiting for the close
+	close(out.release)
+	r.Eventually(func() bool {
+		return strings.Contains(out.String(), "dropped=3")
+	}, time.Second, time.Millisecond*10)
+	r.Equal(2, strings.Count(out.String(), "entry\n"))
+}
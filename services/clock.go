@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Clock abstracts the time functions which the service lifecycle depends on so that
+// the timeouts and intervals can be triggered deterministically in the tests.
+type Clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is the abstraction of time.Timer.
+type Timer interface {
+	C() <-chan time.Time
+	Stop() bool
+	Reset(d time.Duration) bool
+}
+
+// RealClock is the clock which uses the time package.
+var RealClock Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t *realTimer) Reset(d time.Duration) bool {
+	return t.timer.Reset(d)
+}
+
+func (opts Options) clock() Clock {
+	if opts.Clock != nil {
+		return opts.Clock
+	}
+	return RealClock
+}
+
+// withClockTimeout is context.WithTimeout with the timer of the clock.
+func withClockTimeout(parent context.Context, clock Clock, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	timeoutCtx := &clockTimeoutContext{Context: ctx, deadline: clock.Now().Add(timeout)}
+	timer := clock.NewTimer(timeout)
+	go func() {
+		select {
+		case <-timer.C():
+			timeoutCtx.expire()
+			cancel()
+		case <-ctx.Done():
+			timer.Stop()
+		}
+	}()
+	return timeoutCtx, cancel
+}
+
+// clockTimeoutContext is cancelled when the timer of the clock fires.
+type clockTimeoutContext struct {
+	context.Context
+	deadline time.Time
+	expired  bool
+	mu       sync.Mutex
+}
+
+func (ctx *clockTimeoutContext) expire() {
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	ctx.expired = true
+}
+
+func (ctx *clockTimeoutContext) Deadline() (time.Time, bool) {
+	return ctx.deadline, true
+}
+
+func (ctx *clockTimeoutContext) Err() error {
+	err := ctx.Context.Err()
+	ctx.mu.Lock()
+	defer ctx.mu.Unlock()
+	if err != nil && ctx.expired {
+		return context.DeadlineExceeded
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// Command is a one-shot function which runs with the config instead of the services.
+type Command func(ctx context.Context, cfg config.Config) error
+
+// RunCommand runs the command with the config from the container config files.
+func RunCommand(command Command) error {
+	return RunCommandWithLoader(config.GetConfigForContainer, command)
+}
+
+// RunCommandWithLoader runs the command with the config from the loader and returns the
+// command error. Unlike ContainerMain, it is quiet: it does not log the lifecycle messages,
+// handle the OS signals or exit the process so that the CLI output stays clean.
+func RunCommandWithLoader(loader func() (config.Config, error), command Command) error {
+	cfg, err := loader()
+	if err != nil {
+		return fmt.Errorf("could not get config: %w", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	if err := SetLogLevels(cfg.Log); err != nil {
+		return fmt.Errorf("could not initialize log level: %w", err)
+	}
+	return command(initExecID(context.Background()), cfg)
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+func TestRunCommandIsQuiet(t *testing.T) {
+	r := require.New(t)
+
+	oldHooks := logrus.StandardLogger().ReplaceHooks(make(logrus.LevelHooks))
+	defer logrus.StandardLogger().ReplaceHooks(oldHooks)
+	hook := test.NewLocal(logrus.StandardLogger())
+	oldLevel := logrus.GetLevel()
+	defer logrus.SetLevel(oldLevel)
+	processMainCtx := getProcessMainContext()
+
+	commandErr := errors.New("contract not found")
+	var ran bool
+	err := RunCommandWithLoader(func() (config.Config, error) {
+		var cfg config.Config
+		err := config.ApplyDefaults(&cfg)
+		cfg.Log.Level = "debug"
+		return cfg, err
+	}, func(ctx context.Context, cfg config.Config) error {
+		ran = true
+		_, ok := ExecIDOk(ctx)
+		r.True(ok)
+		return commandErr
+	})
+	r.True(ran)
+	r.Equal(commandErr, err)
+	r.Empty(hook.AllEntries())
+	// the signal handling is not set up
+	r.Equal(processMainCtx, getProcessMainContext())
+}
+
+func TestRunCommandConfigErrors(t *testing.T) {
+	r := require.New(t)
+
+	loadErr := errors.New("no config file")
+	err := RunCommandWithLoader(func() (config.Config, error) {
+		return config.Config{}, loadErr
+	}, func(ctx context.Context, cfg config.Config) error {
+		r.FailNow("command ran without the config")
+		return nil
+	})
+	r.ErrorIs(err, loadErr)
+
+	err = RunCommandWithLoader(func() (config.Config, error) {
+		var cfg config.Config
+		err := config.ApplyDefaults(&cfg)
+		cfg.Log.Level = "loud"
+		return cfg, err
+	}, func(ctx context.Context, cfg config.Config) error {
+		r.FailNow("command ran with invalid config")
+		return nil
+	})
+	r.Error(err)
+	r.Contains(err.Error(), "invalid config")
+}
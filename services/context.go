@@ -0,0 +1,430 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// MainContext carries the state of running a set of services so that multiple
+// sets can run independently in the same process.
+type MainContext struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	sigc       chan os.Signal
+	interruptc chan struct{}
+	exit       func(code int)
+	statuses   *StatusRegistry
+	events     *EventBus
+	// statusLogger receives the status dumps
+	statusLogger *log.Entry
+	// stackDumpWriter receives the goroutine stack dumps
+	stackDumpWriter io.Writer
+	// signalDebounce is the window in which a repeated signal is ignored
+	signalDebounce time.Duration
+	// clock times the signal debounce
+	clock Clock
+
+	// cfg is the config which the services are running with, if it is known
+	cfg             *config.Config
+	loadConfig      func() (config.Config, error)
+	reloadCallbacks []*reloadCallback
+	restartEnabled  bool
+	restartc        chan struct{}
+
+	shutdownCallbacks []ShutdownCallback
+
+	// runs is how many sets of services are running and idle is closed when none are
+	runs    int
+	idle    chan struct{}
+	runErrs []error
+
+	gracefulShutdown bool
+	shutdownSignal   os.Signal
+	exitTriggered    bool
+	mu               sync.RWMutex
+}
+
+// NewMainContext creates a main context which is not bound to the OS signals.
+func NewMainContext() *MainContext {
+	ctx, cancel := context.WithCancel(initExecID(context.Background()))
+	mainCtx := &MainContext{
+		ctx:        ctx,
+		cancel:     cancel,
+		sigc:       make(chan os.Signal, signalBufferSize),
+		interruptc: make(chan struct{}, 1),
+		restartc:   make(chan struct{}, 1),
+		exit:       os.Exit,
+		statuses:   NewStatusRegistry(),
+		events:     NewEventBus(),
+
+		signalDebounce: DefaultSignalDebounce,
+		clock:          RealClock,
+		statusLogger:   log.NewEntry(log.StandardLogger()),
+	}
+	go mainCtx.handleSignals()
+	return mainCtx
+}
+
+var (
+	processMainCtx *MainContext
+	processMu      sync.RWMutex
+)
+
+// DefaultSignalDebounce is the default window in which a repeated signal is ignored so that
+// mashing Ctrl-C does not force the exit right after starting the shutdown.
+const DefaultSignalDebounce = time.Millisecond * 500
+
+// signalBufferSize is how many signals can wait for the handler before the new ones are dropped.
+const signalBufferSize = 8
+
+// StatusDumpSignal makes the service statuses logged without cancelling the main context.
+const StatusDumpSignal = syscall.SIGUSR1
+
+// DefaultSignals are the signals which are handled by default.
+var DefaultSignals = []os.Signal{
+	syscall.SIGHUP,
+	syscall.SIGINT,
+	syscall.SIGTERM,
+	syscall.SIGQUIT,
+	StatusDumpSignal,
+	StackDumpSignal,
+}
+
+// InitMainContext creates a main context which is cancelled by the default signals.
+func InitMainContext() *MainContext {
+	return InitMainContextWithSignals(DefaultSignals...)
+}
+
+// InitMainContextWithSignals creates a main context which is cancelled by the given OS signals
+// or the default signals if none are given. It becomes the process main context which the
+// package level functions act on.
+func InitMainContextWithSignals(sigs ...os.Signal) *MainContext {
+	if len(sigs) == 0 {
+		sigs = DefaultSignals
+	}
+	mainCtx := NewMainContext()
+	signal.Notify(mainCtx.sigc, sigs...)
+	processMu.Lock()
+	processMainCtx = mainCtx
+	processMu.Unlock()
+	return mainCtx
+}
+
+func getProcessMainContext() *MainContext {
+	processMu.RLock()
+	defer processMu.RUnlock()
+	return processMainCtx
+}
+
+// handleSignals cancels the context after the first shutdown signal and forces the exit
+// if the same signal is received again while the services are being stopped. If the shutdown
+// was not started by a signal, a signal during it arms the forced exit in the same way until
+// the services stop. A signal which repeats within the debounce window is ignored.
+func (mainCtx *MainContext) handleSignals() {
+	var (
+		shutdownSig os.Signal
+		lastSig     os.Signal
+		lastSigTime time.Time
+	)
+	done := mainCtx.ctx.Done()
+	// idle is set when the shutdown was not started by a signal and it is closed after the services stop
+	var idle <-chan struct{}
+	for {
+		select {
+		case sig := <-mainCtx.sigc:
+			now := mainCtx.getClock().Now()
+			if sig == lastSig && now.Sub(lastSigTime) < mainCtx.getSignalDebounce() {
+				log.WithField("signal", sig.String()).Debug("ignoring the repeated signal")
+				continue
+			}
+			lastSig, lastSigTime = sig, now
+			log.Infof("received signal: %s", sig.String())
+			if sig == StatusDumpSignal {
+				mainCtx.dumpStatuses()
+				continue
+			}
+			if sig == StackDumpSignal {
+				mainCtx.dumpStacks()
+				continue
+			}
+			if shutdownSig != nil {
+				if sig == shutdownSig {
+					log.WithField("signal", sig.String()).Error("received the shutdown signal again - forcing exit")
+					mainCtx.exit(ExitCodeForced)
+				}
+				continue
+			}
+			if mainCtx.ctx.Err() != nil {
+				// the shutdown is already in progress so the signal only arms the forced exit
+				log.WithField("signal", sig.String()).Warn("received signal during the shutdown - send it again to force exit")
+				shutdownSig = sig
+				continue
+			}
+			if sig == ReloadSignal && mainCtx.canRestart() {
+				mainCtx.Restart()
+				continue
+			}
+			if sig == ReloadSignal && mainCtx.canReload() {
+				mainCtx.reload()
+				continue
+			}
+			mainCtx.mu.Lock()
+			mainCtx.gracefulShutdown = sig == GracefulShutdownSignal
+			mainCtx.shutdownSignal = sig
+			mainCtx.mu.Unlock()
+			shutdownSig = sig
+			mainCtx.cancel()
+		case <-mainCtx.interruptc:
+			log.Info("interrupted internally")
+			mainCtx.cancel()
+		case <-done:
+			done = nil
+			if shutdownSig != nil {
+				continue
+			}
+			// keep handling the signals until the services stop so that a hung shutdown can be forced
+			mainCtx.mu.RLock()
+			runIdle := mainCtx.idle
+			mainCtx.mu.RUnlock()
+			if runIdle == nil {
+				return
+			}
+			idle = runIdle
+		case <-idle:
+			if shutdownSig == nil {
+				return
+			}
+			idle = nil
+		}
+	}
+}
+
+// Context returns the context which is cancelled when the services should stop.
+func (mainCtx *MainContext) Context() context.Context {
+	return mainCtx.ctx
+}
+
+// Cancel cancels the main context.
+func (mainCtx *MainContext) Cancel() {
+	mainCtx.cancel()
+}
+
+// SetSignalDebounce sets the window in which a repeated signal is ignored. Zero disables the debounce.
+func (mainCtx *MainContext) SetSignalDebounce(debounce time.Duration) {
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	mainCtx.signalDebounce = debounce
+}
+
+func (mainCtx *MainContext) getSignalDebounce() time.Duration {
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	return mainCtx.signalDebounce
+}
+
+// SetClock sets the clock which times the signal debounce.
+func (mainCtx *MainContext) SetClock(clock Clock) {
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	mainCtx.clock = clock
+}
+
+func (mainCtx *MainContext) getClock() Clock {
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	return mainCtx.clock
+}
+
+// SetStatusLogger sets the logger which receives the status dumps.
+func (mainCtx *MainContext) SetStatusLogger(logger *log.Entry) {
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	mainCtx.statusLogger = logger
+}
+
+func (mainCtx *MainContext) dumpStatuses() {
+	mainCtx.mu.RLock()
+	logger := mainCtx.statusLogger
+	mainCtx.mu.RUnlock()
+	logStatuses(logger, mainCtx.statuses.Statuses(), mainCtx.statuses.clockNow())
+}
+
+// Statuses returns the registry which receives the service status updates.
+func (mainCtx *MainContext) Statuses() *StatusRegistry {
+	return mainCtx.statuses
+}
+
+// Events returns the bus which receives the service lifecycle events.
+func (mainCtx *MainContext) Events() *EventBus {
+	return mainCtx.events
+}
+
+// WaitReady blocks until all of the services started with the main context are running.
+func (mainCtx *MainContext) WaitReady(ctx context.Context) error {
+	return mainCtx.statuses.WaitReady(ctx)
+}
+
+// IsGracefulShutdown tells if we have reached a graceful shutdown condition.
+func (mainCtx *MainContext) IsGracefulShutdown() bool {
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	return mainCtx.gracefulShutdown
+}
+
+// ShutdownSignal returns the signal which started the shutdown and tells if the shutdown was
+// started by a signal.
+func (mainCtx *MainContext) ShutdownSignal() (os.Signal, bool) {
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	return mainCtx.shutdownSignal, mainCtx.shutdownSignal != nil
+}
+
+// withShutdownSignal adds the signal which started the shutdown to the error of the services.
+func (mainCtx *MainContext) withShutdownSignal(err error) error {
+	sig, ok := mainCtx.ShutdownSignal()
+	if err == nil || err == ErrExitTriggered || !ok {
+		return err
+	}
+	return &ShutdownError{Signal: sig, Err: err}
+}
+
+func (mainCtx *MainContext) isExitTriggered() bool {
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	return mainCtx.exitTriggered
+}
+
+// Interrupt interrupts the main context from within runtime. Unlike the signals,
+// repeated interrupts do not force the exit.
+func (mainCtx *MainContext) Interrupt() {
+	select {
+	case mainCtx.interruptc <- struct{}{}:
+	default:
+	}
+}
+
+// Shutdown starts the graceful shutdown as the graceful shutdown signal would, without
+// the OS signals. The services are stopped in the normal way. Repeated calls do nothing.
+func (mainCtx *MainContext) Shutdown() {
+	mainCtx.mu.Lock()
+	if mainCtx.ctx.Err() == nil {
+		mainCtx.gracefulShutdown = true
+	}
+	mainCtx.mu.Unlock()
+	mainCtx.cancel()
+}
+
+// ShutdownAfter starts the graceful shutdown after the duration, unless the main context is
+// done before.
+func (mainCtx *MainContext) ShutdownAfter(duration time.Duration) {
+	go func() {
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+			log.WithField("maxRun", duration.String()).Info("reached the max run duration - shutting down")
+			mainCtx.Shutdown()
+		case <-mainCtx.ctx.Done():
+		}
+	}()
+}
+
+// StopAll cancels the main context and waits for all of the services to stop. It returns the
+// errors which the services stopped with, or an error if they do not stop before the context
+// is done.
+func (mainCtx *MainContext) StopAll(ctx context.Context) error {
+	mainCtx.cancel()
+	mainCtx.mu.RLock()
+	idle := mainCtx.idle
+	mainCtx.mu.RUnlock()
+	if idle != nil {
+		select {
+		case <-idle:
+		case <-ctx.Done():
+			var stopping []string
+			for _, status := range mainCtx.statuses.Statuses() {
+				if status.State == StateRunning || status.State == StateStopping {
+					stopping = append(stopping, status.Name)
+				}
+			}
+			return fmt.Errorf("services did not stop: %w - still stopping: %s", ctx.Err(), strings.Join(stopping, ", "))
+		}
+	}
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	return combineErrors(mainCtx.runErrs...)
+}
+
+// beginRun tracks a set of services which is started with the main context.
+func (mainCtx *MainContext) beginRun() {
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	if mainCtx.runs == 0 {
+		mainCtx.idle = make(chan struct{})
+	}
+	mainCtx.runs++
+}
+
+// endRun records how the set of services stopped.
+func (mainCtx *MainContext) endRun(err error) {
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	if err != nil {
+		mainCtx.runErrs = append(mainCtx.runErrs, err)
+	}
+	mainCtx.runs--
+	if mainCtx.runs == 0 {
+		close(mainCtx.idle)
+	}
+}
+
+// TriggerExit triggers exit internally.
+func (mainCtx *MainContext) TriggerExit(delay time.Duration) {
+	if delay > 0 {
+		log.WithField("timeout", fmt.Sprintf("%s", delay)).Info("waiting before triggering exit")
+		time.Sleep(delay)
+		log.WithField("timeout", fmt.Sprintf("%s", delay)).Info("done waiting before triggering exit")
+	}
+	mainCtx.mu.Lock()
+	mainCtx.exitTriggered = true
+	mainCtx.mu.Unlock()
+	mainCtx.Interrupt()
+}
+
+// IsGracefulShutdown tells if the process main context has reached a graceful shutdown condition.
+func IsGracefulShutdown() bool {
+	mainCtx := getProcessMainContext()
+	return mainCtx != nil && mainCtx.IsGracefulShutdown()
+}
+
+// InterruptMainContext interrupts the process main context.
+func InterruptMainContext() {
+	if mainCtx := getProcessMainContext(); mainCtx != nil {
+		mainCtx.Interrupt()
+	}
+}
+
+// Shutdown starts the graceful shutdown of the process main context.
+func Shutdown() {
+	if mainCtx := getProcessMainContext(); mainCtx != nil {
+		mainCtx.Shutdown()
+	}
+}
+
+// TriggerExit triggers exit of the process main context internally.
+func TriggerExit(delay time.Duration) {
+	if mainCtx := getProcessMainContext(); mainCtx != nil {
+		mainCtx.TriggerExit(delay)
+	}
+}
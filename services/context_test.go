@@ -0,0 +1,349 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIndependentServiceSets(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx1 := NewMainContext()
+	defer mainCtx1.Cancel()
+	mainCtx2 := NewMainContext()
+	defer mainCtx2.Cancel()
+
+	var stopped1, stopped2 []string
+	errCh1 := make(chan error, 1)
+	errCh2 := make(chan error, 1)
+	go func() {
+		errCh1 <- StartServices(mainCtx1, logrus.NewEntry(logrus.StandardLogger()), []Service{
+			&orderedService{name: "a", stopped: &stopped1},
+		})
+	}()
+	go func() {
+		errCh2 <- StartServices(mainCtx2, logrus.NewEntry(logrus.StandardLogger()), []Service{
+			&orderedService{name: "a", stopped: &stopped2},
+		})
+	}()
+
+	time.AfterFunc(time.Millisecond*50, mainCtx1.Interrupt)
+	select {
+	case err := <-errCh1:
+		r.NoError(err)
+	case <-time.After(time.Second):
+		r.FailNow("first set did not stop")
+	}
+	r.Equal([]string{"a"}, stopped1)
+
+	// the second set keeps running until its own context is cancelled
+	select {
+	case <-errCh2:
+		r.FailNow("second set stopped with the first one")
+	case <-time.After(time.Millisecond * 50):
+	}
+	r.NoError(mainCtx2.Context().Err())
+	status, ok := mainCtx2.Statuses().Status("a")
+	r.True(ok)
+	r.Equal(StateRunning, status.State)
+
+	mainCtx2.Cancel()
+	r.NoError(<-errCh2)
+	r.Equal([]string{"a"}, stopped2)
+}
+
+func TestSecondSignalForcesExit(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	exitCodes := make(chan int, 1)
+	mainCtx.exit = func(code int) {
+		exitCodes <- code
+	}
+
+	mainCtx.sigc <- syscall.SIGTERM
+	<-mainCtx.Context().Done()
+
+	// a different signal does not force the exit
+	mainCtx.sigc <- syscall.SIGINT
+	select {
+	case <-exitCodes:
+		r.FailNow("different signal forced the exit")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	mainCtx.sigc <- syscall.SIGTERM
+	select {
+	case code := <-exitCodes:
+		r.Equal(ExitCodeForced, code)
+	case <-time.After(time.Second):
+		r.FailNow("second signal did not force the exit")
+	}
+}
+
+func TestSignalsForceExitDuringInternalShutdown(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	mainCtx.SetSignalDebounce(0)
+	exitCodes := make(chan int, 1)
+	mainCtx.exit = func(code int) {
+		exitCodes <- code
+	}
+
+	slow := &slowStopService{name: "slow", delay: time.Millisecond * 300, stopped: make(chan struct{})}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{slow})
+	}()
+	r.Eventually(func() bool {
+		status, _ := mainCtx.Statuses().Status("slow")
+		return status.State == StateRunning
+	}, time.Second, time.Millisecond*10)
+
+	// the shutdown is not started by a signal and the service is slow to stop
+	mainCtx.Cancel()
+	mainCtx.sigc <- syscall.SIGTERM
+	select {
+	case <-exitCodes:
+		r.FailNow("first signal during the shutdown forced the exit")
+	case <-time.After(time.Millisecond * 50):
+	}
+	mainCtx.sigc <- syscall.SIGTERM
+	select {
+	case code := <-exitCodes:
+		r.Equal(ExitCodeForced, code)
+	case <-time.After(time.Second):
+		r.FailNow("second signal during the shutdown did not force the exit")
+	}
+
+	<-slow.stopped
+	r.NoError(<-errCh)
+}
+
+func TestRepeatedInterruptDoesNotForceExit(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	exitCodes := make(chan int, 1)
+	mainCtx.exit = func(code int) {
+		exitCodes <- code
+	}
+
+	mainCtx.Interrupt()
+	<-mainCtx.Context().Done()
+	mainCtx.Interrupt()
+	select {
+	case <-exitCodes:
+		r.FailNow("interrupt forced the exit")
+	case <-time.After(time.Millisecond * 50):
+	}
+}
+
+func TestUnregisteredSignalDoesNotCancel(t *testing.T) {
+	r := require.New(t)
+
+	// catch the signal here so that it does not terminate the test
+	testSigc := make(chan os.Signal, 1)
+	signal.Notify(testSigc, syscall.SIGWINCH)
+	defer signal.Stop(testSigc)
+
+	mainCtx := InitMainContext()
+	defer mainCtx.Cancel()
+	defer signal.Stop(mainCtx.sigc)
+
+	r.NoError(syscall.Kill(os.Getpid(), syscall.SIGWINCH))
+	<-testSigc
+	select {
+	case <-mainCtx.Context().Done():
+		r.FailNow("unregistered signal cancelled the context")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	mainCtx = InitMainContextWithSignals(syscall.SIGWINCH)
+	defer mainCtx.Cancel()
+	defer signal.Stop(mainCtx.sigc)
+
+	r.NoError(syscall.Kill(os.Getpid(), syscall.SIGWINCH))
+	select {
+	case <-mainCtx.Context().Done():
+	case <-time.After(time.Second):
+		r.FailNow("registered signal did not cancel the context")
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var stopped []string
+	first := &orderedService{name: "first", stopped: &stopped}
+	second := &orderedService{name: "second", stopped: &stopped}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{first, second})
+	}()
+	r.Eventually(func() bool {
+		status, _ := mainCtx.Statuses().Status("second")
+		return status.State == StateRunning
+	}, time.Second, time.Millisecond*10)
+
+	mainCtx.Shutdown()
+	select {
+	case err := <-errCh:
+		r.NoError(err)
+	case <-time.After(time.Second):
+		r.FailNow("shutdown did not stop the services")
+	}
+	r.Equal([]string{"second", "first"}, stopped)
+	r.True(mainCtx.IsGracefulShutdown())
+
+	// repeated calls do nothing
+	mainCtx.Shutdown()
+}
+
+func TestStopAll(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	// nothing to wait for before the services are started
+	r.NoError(NewMainContext().StopAll(context.Background()))
+
+	var stopped []string
+	stopErr := errors.New("failed to flush")
+	first := &orderedService{name: "first", stopped: &stopped}
+	second := &orderedService{name: "second", stopped: &stopped, stopErr: stopErr}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{first, second})
+	}()
+	r.Eventually(func() bool {
+		status, _ := mainCtx.Statuses().Status("second")
+		return status.State == StateRunning
+	}, time.Second, time.Millisecond*10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	err := mainCtx.StopAll(ctx)
+	r.ErrorIs(err, stopErr)
+	// everything is stopped by the time it returns
+	r.Equal([]string{"second", "first"}, stopped)
+	r.ErrorIs(<-errCh, stopErr)
+}
+
+func TestStopAllDeadline(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	slow := &slowStopService{name: "slow", delay: time.Millisecond * 300, stopped: make(chan struct{})}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{slow})
+	}()
+	r.Eventually(func() bool {
+		status, _ := mainCtx.Statuses().Status("slow")
+		return status.State == StateRunning
+	}, time.Second, time.Millisecond*10)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err := mainCtx.StopAll(ctx)
+	r.ErrorIs(err, context.DeadlineExceeded)
+	r.Contains(err.Error(), "still stopping: slow")
+
+	<-slow.stopped
+	r.NoError(<-errCh)
+}
+
+// steppedClock is the real clock which tells the time that it is moved to.
+type steppedClock struct {
+	Clock
+	now time.Time
+	mu  sync.Mutex
+}
+
+func (clock *steppedClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+func (clock *steppedClock) advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+}
+
+func TestRepeatedSignalsAreDebounced(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	clock := &steppedClock{Clock: RealClock, now: time.Now()}
+	mainCtx.SetClock(clock)
+	mainCtx.SetSignalDebounce(time.Minute)
+	exitCodes := make(chan int, 1)
+	mainCtx.exit = func(code int) {
+		exitCodes <- code
+	}
+
+	// mashing the signal only starts the shutdown
+	for i := 0; i < 5; i++ {
+		mainCtx.sigc <- syscall.SIGINT
+	}
+	<-mainCtx.Context().Done()
+	select {
+	case <-exitCodes:
+		r.FailNow("rapid repeated signals forced the exit")
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	// the handler keeps looping and the signal after the window forces the exit
+	clock.advance(time.Minute)
+	mainCtx.sigc <- syscall.SIGINT
+	select {
+	case code := <-exitCodes:
+		r.Equal(ExitCodeForced, code)
+	case <-time.After(time.Second):
+		r.FailNow("signal after the debounce window did not force the exit")
+	}
+}
+
+func TestSignalDebounceDisabled(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	mainCtx.SetSignalDebounce(0)
+	exitCodes := make(chan int, 1)
+	mainCtx.exit = func(code int) {
+		exitCodes <- code
+	}
+
+	mainCtx.sigc <- syscall.SIGINT
+	mainCtx.sigc <- syscall.SIGINT
+	select {
+	case code := <-exitCodes:
+		r.Equal(ExitCodeForced, code)
+	case <-time.After(time.Second):
+		r.FailNow("repeated signal did not force the exit")
+	}
+}
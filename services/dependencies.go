@@ -0,0 +1,132 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Dependent is implemented by services which need other services to be started first.
+type Dependent interface {
+	DependsOn() []string
+}
+
+func dependenciesOf(service Service) []string {
+	dependent, ok := underlying(service).(Dependent)
+	if !ok {
+		return nil
+	}
+	return dependent.DependsOn()
+}
+
+// sortByDependencies sorts the services so that every service comes after its dependencies.
+// The services which do not have an ordering constraint keep their original order.
+func sortByDependencies(services []Service) ([]Service, error) {
+	byName := make(map[string]int)
+	for i, service := range services {
+		if _, ok := byName[service.Name()]; !ok {
+			byName[service.Name()] = i
+		}
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	var (
+		sorted []Service
+		state  = make([]int, len(services))
+		path   []string
+	)
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		service := services[i]
+		switch state[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("dependency cycle detected: %s", strings.Join(append(path, service.Name()), " -> "))
+		}
+		state[i] = visiting
+		path = append(path, service.Name())
+		for _, depName := range dependenciesOf(service) {
+			dep, ok := byName[depName]
+			if !ok {
+				return fmt.Errorf("service '%s' depends on unknown service '%s'", service.Name(), depName)
+			}
+			if err := visit(dep); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[i] = visited
+		sorted = append(sorted, service)
+		return nil
+	}
+
+	for i := range services {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// startBatches splits the sorted services into the batches which are started concurrently.
+// Every service is a batch on its own if the services are started one by one, otherwise all
+// of the services are started together and the dependents wait for their dependencies.
+func startBatches(services []Service, parallelism int) [][]Service {
+	if parallelism > 1 {
+		return [][]Service{services}
+	}
+	var batches [][]Service
+	for _, service := range services {
+		batches = append(batches, []Service{service})
+	}
+	return batches
+}
+
+// startSignal is fired when a service is started or fails to start.
+type startSignal struct {
+	done chan struct{}
+	err  error
+	once sync.Once
+}
+
+func newStartSignals(services []Service) map[string]*startSignal {
+	signals := make(map[string]*startSignal)
+	for _, service := range services {
+		signals[service.Name()] = &startSignal{done: make(chan struct{})}
+	}
+	return signals
+}
+
+func (signal *startSignal) fire(err error) {
+	signal.once.Do(func() {
+		signal.err = err
+		close(signal.done)
+	})
+}
+
+// awaitDependencies waits until the dependencies of the service are started. It returns
+// a service error without waiting for the rest if any of the dependencies failed to start.
+func awaitDependencies(ctx context.Context, signals map[string]*startSignal, service Service) error {
+	for _, depName := range dependenciesOf(service) {
+		signal := signals[depName]
+		select {
+		case <-signal.done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if signal.err != nil {
+			return &ServiceError{
+				Name:  service.Name(),
+				Phase: PhaseStart,
+				Err:   fmt.Errorf("%w: '%s'", ErrDependencyFailed, depName),
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,320 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type dependentService struct {
+	name    string
+	deps    []string
+	started bool
+}
+
+func (s *dependentService) Start(ctx context.Context) error {
+	s.started = true
+	return nil
+}
+
+func (s *dependentService) Stop() error {
+	return nil
+}
+
+func (s *dependentService) Name() string {
+	return s.name
+}
+
+func (s *dependentService) DependsOn() []string {
+	return s.deps
+}
+
+func serviceNames(services []Service) (names []string) {
+	for _, service := range services {
+		names = append(names, service.Name())
+	}
+	return
+}
+
+func TestSortByDependencies(t *testing.T) {
+	r := require.New(t)
+
+	sorted, err := sortByDependencies([]Service{
+		&dependentService{name: "publisher", deps: []string{"scanner"}},
+		&dependentService{name: "health"},
+		&dependentService{name: "scanner", deps: []string{"json-rpc"}},
+		&dependentService{name: "json-rpc"},
+		&dependentService{name: "logger"},
+	})
+	r.NoError(err)
+	r.Equal([]string{"json-rpc", "scanner", "publisher", "health", "logger"}, serviceNames(sorted))
+}
+
+func TestSortByDependenciesKeepsOrder(t *testing.T) {
+	r := require.New(t)
+
+	sorted, err := sortByDependencies([]Service{
+		&dependentService{name: "c"},
+		&dependentService{name: "a"},
+		&dependentService{name: "b"},
+	})
+	r.NoError(err)
+	r.Equal([]string{"c", "a", "b"}, serviceNames(sorted))
+}
+
+func TestSortByDependenciesCycle(t *testing.T) {
+	r := require.New(t)
+
+	_, err := sortByDependencies([]Service{
+		&dependentService{name: "a", deps: []string{"b"}},
+		&dependentService{name: "b", deps: []string{"c"}},
+		&dependentService{name: "c", deps: []string{"a"}},
+	})
+	r.EqualError(err, "dependency cycle detected: a -> b -> c -> a")
+}
+
+func TestSortByDependenciesMissing(t *testing.T) {
+	r := require.New(t)
+
+	_, err := sortByDependencies([]Service{
+		&dependentService{name: "a", deps: []string{"b"}},
+	})
+	r.EqualError(err, "service 'a' depends on unknown service 'b'")
+}
+
+func TestStartServicesDependencyCycle(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	a := &dependentService{name: "a", deps: []string{"b"}}
+	b := &dependentService{name: "b", deps: []string{"a"}}
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{a, b})
+	r.Error(err)
+	r.False(a.started)
+	r.False(b.started)
+}
+
+func TestStartBatches(t *testing.T) {
+	r := require.New(t)
+
+	services := []Service{
+		&dependentService{name: "json-rpc"},
+		&dependentService{name: "scanner", deps: []string{"json-rpc"}},
+		&dependentService{name: "health"},
+	}
+	r.Equal([][]Service{services}, startBatches(services, 2))
+
+	// one by one
+	var batchNames [][]string
+	for _, batch := range startBatches(services, 1) {
+		batchNames = append(batchNames, serviceNames(batch))
+	}
+	r.Equal([][]string{{"json-rpc"}, {"scanner"}, {"health"}}, batchNames)
+}
+
+// startTracker records the concurrent starts of the services.
+type startTracker struct {
+	active    int
+	maxActive int
+	ready     map[string]bool
+	// depsReady tells if the dependencies were ready when a service started to start
+	depsReady map[string]bool
+	mu        sync.Mutex
+}
+
+type trackedService struct {
+	dependentService
+	delay   time.Duration
+	tracker *startTracker
+}
+
+func (s *trackedService) Start(ctx context.Context) error {
+	tracker := s.tracker
+	tracker.mu.Lock()
+	tracker.active++
+	if tracker.active > tracker.maxActive {
+		tracker.maxActive = tracker.active
+	}
+	depsReady := true
+	for _, dep := range s.deps {
+		depsReady = depsReady && tracker.ready[dep]
+	}
+	tracker.depsReady[s.name] = depsReady
+	tracker.mu.Unlock()
+
+	time.Sleep(s.delay)
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.active--
+	tracker.ready[s.name] = true
+	return nil
+}
+
+func TestStartServicesInParallel(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	tracker := &startTracker{ready: make(map[string]bool), depsReady: make(map[string]bool)}
+	newService := func(name string, deps ...string) Service {
+		return &trackedService{
+			dependentService: dependentService{name: name, deps: deps},
+			delay:            time.Millisecond * 50,
+			tracker:          tracker,
+		}
+	}
+	var started []string
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		newService("publisher", "scanner"),
+		newService("health"),
+		newService("scanner", "json-rpc", "logger"),
+		newService("json-rpc"),
+		newService("logger"),
+	}, Options{
+		StartParallelism: 2,
+		OnStart: func(name string) {
+			started = append(started, name)
+			if len(started) == 5 {
+				mainCtx.Cancel()
+			}
+		},
+	}))
+
+	// only two of the three independent services start at the same time
+	r.Equal(2, tracker.maxActive)
+	r.Equal(map[string]bool{"json-rpc": true, "health": true, "logger": true, "scanner": true, "publisher": true}, tracker.depsReady)
+	// the start hooks follow the dependencies
+	r.Len(started, 5)
+	startIndex := make(map[string]int)
+	for i, name := range started {
+		startIndex[name] = i
+	}
+	r.Less(startIndex["json-rpc"], startIndex["scanner"])
+	r.Less(startIndex["logger"], startIndex["scanner"])
+	r.Less(startIndex["scanner"], startIndex["publisher"])
+}
+
+type groupedService struct {
+	trackedService
+	group string
+}
+
+func (s *groupedService) StartGroup() string {
+	return s.group
+}
+
+func TestStartGroupLimits(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	dockerTracker := &startTracker{ready: make(map[string]bool), depsReady: make(map[string]bool)}
+	otherTracker := &startTracker{ready: make(map[string]bool), depsReady: make(map[string]bool)}
+	newService := func(name, group string, tracker *startTracker) Service {
+		return &groupedService{
+			trackedService: trackedService{
+				dependentService: dependentService{name: name},
+				delay:            time.Millisecond * 50,
+				tracker:          tracker,
+			},
+			group: group,
+		}
+	}
+	var started []string
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		newService("agent-1", "docker", dockerTracker),
+		newService("agent-2", "docker", dockerTracker),
+		newService("agent-3", "docker", dockerTracker),
+		newService("health", "", otherTracker),
+		newService("json-rpc", "", otherTracker),
+	}, Options{
+		StartParallelism: 5,
+		StartGroupLimits: map[string]int{"docker": 1},
+		OnStart: func(name string) {
+			started = append(started, name)
+			if len(started) == 5 {
+				mainCtx.Cancel()
+			}
+		},
+	}))
+
+	// the docker services start one at a time and the others are not held back by them
+	r.Equal(1, dockerTracker.maxActive)
+	r.Equal(2, otherTracker.maxActive)
+	r.Len(started, 5)
+}
+
+func TestDependentWaitsForReadiness(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	tracker := &startTracker{ready: make(map[string]bool), depsReady: make(map[string]bool)}
+	slowDep := &trackedService{
+		dependentService: dependentService{name: "json-rpc"},
+		delay:            time.Millisecond * 100,
+		tracker:          tracker,
+	}
+	dependent := &trackedService{
+		dependentService: dependentService{name: "scanner", deps: []string{"json-rpc"}},
+		tracker:          tracker,
+	}
+	independent := &trackedService{
+		dependentService: dependentService{name: "health"},
+		delay:            time.Millisecond * 50,
+		tracker:          tracker,
+	}
+
+	var started []string
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		dependent, slowDep, independent,
+	}, Options{
+		StartParallelism: 3,
+		OnStart: func(name string) {
+			started = append(started, name)
+			if len(started) == 3 {
+				mainCtx.Cancel()
+			}
+		},
+	}))
+	r.True(tracker.depsReady["scanner"])
+	// the independent service did not wait for the slow dependency
+	r.Equal(2, tracker.maxActive)
+}
+
+func TestFailedDependencyPreventsStart(t *testing.T) {
+	for _, parallelism := range []int{1, 2} {
+		r := require.New(t)
+
+		mainCtx := NewMainContext()
+		statuses := NewStatusRegistry()
+
+		dep := &failingService{name: "json-rpc", startErr: errors.New("failed to listen")}
+		dependent := &dependentService{name: "scanner", deps: []string{"json-rpc"}}
+		independent := &dependentService{name: "health"}
+
+		time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+		err := StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+			dep, dependent, independent,
+		}, Options{StartParallelism: parallelism, CollectStartErrors: true, Statuses: statuses})
+		mainCtx.Cancel()
+
+		r.ErrorIs(err, ErrDependencyFailed)
+		r.False(dependent.started, parallelism)
+		r.True(independent.started, parallelism)
+		status, _ := statuses.Status("scanner")
+		r.Equal(StateFailed, status.State)
+		r.Contains(status.Error, "'json-rpc'")
+	}
+}
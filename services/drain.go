@@ -0,0 +1,62 @@
+package services
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Drainer is implemented by services which can flush their in-flight work before they
+// are stopped. The context given to Drain is done when the drain period is over.
+type Drainer interface {
+	Drain(ctx context.Context) error
+}
+
+// drainServices gives the services the drain period before they are stopped. The drainers
+// are drained concurrently. The drain ends early only if all of the services are drainers
+// and they are all done, otherwise the full period is waited.
+func (opts Options) drainServices(logger *log.Entry, services []Service) {
+	if opts.DrainPeriod <= 0 || len(services) == 0 {
+		return
+	}
+	logger.WithField("drainPeriod", opts.DrainPeriod.String()).Info("draining services")
+
+	ctx, cancel := withClockTimeout(context.Background(), opts.clock(), opts.DrainPeriod)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		waitFull bool
+	)
+	for _, service := range services {
+		drainer, ok := underlying(service).(Drainer)
+		if !ok {
+			waitFull = true
+			continue
+		}
+		service := service
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := drainer.Drain(ctx); err != nil {
+				logger.WithField("service", service.Name()).WithError(err).Warn("failed to drain service")
+			}
+		}()
+	}
+
+	drained := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(drained)
+	}()
+	if waitFull {
+		<-ctx.Done()
+		return
+	}
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		logger.Warn("drain period is over before all services are drained")
+	}
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type drainingService struct {
+	drainDuration time.Duration
+	drainDeadline time.Time
+	drainedAt     time.Time
+	stoppedAt     time.Time
+	mu            sync.Mutex
+}
+
+func (s *drainingService) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *drainingService) Drain(ctx context.Context) error {
+	s.mu.Lock()
+	s.drainDeadline, _ = ctx.Deadline()
+	s.mu.Unlock()
+	select {
+	case <-time.After(s.drainDuration):
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.drainedAt = time.Now()
+	return ctx.Err()
+}
+
+func (s *drainingService) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stoppedAt = time.Now()
+	return nil
+}
+
+func (s *drainingService) Name() string {
+	return "draining"
+}
+
+func TestDrainDeadlineIsRespected(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	drainPeriod := time.Millisecond * 100
+	svc := &drainingService{drainDuration: time.Hour}
+	var cancelledAt time.Time
+	time.AfterFunc(time.Millisecond*50, func() {
+		cancelledAt = time.Now()
+		mainCtx.Cancel()
+	})
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc}, Options{
+		DrainPeriod: drainPeriod,
+	}))
+
+	svc.mu.Lock()
+	defer svc.mu.Unlock()
+	r.WithinDuration(cancelledAt.Add(drainPeriod), svc.drainDeadline, time.Millisecond*30)
+	r.False(svc.stoppedAt.Before(svc.drainDeadline))
+}
+
+func TestDrainEndsWhenAllDrained(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	svc := &drainingService{drainDuration: time.Millisecond * 10}
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc}, Options{
+		DrainPeriod: time.Hour,
+	}))
+	r.False(svc.stoppedAt.Before(svc.drainedAt))
+}
+
+func TestStopAfterDrainPeriodWithoutDrainer(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	drainPeriod := time.Millisecond * 100
+	var (
+		stopped     []string
+		cancelledAt time.Time
+		stoppedAt   time.Time
+	)
+	time.AfterFunc(time.Millisecond*50, func() {
+		cancelledAt = time.Now()
+		mainCtx.Cancel()
+	})
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&orderedService{name: "publisher", stopped: &stopped},
+	}, Options{
+		DrainPeriod: drainPeriod,
+		OnStop: func(name string, err error) {
+			stoppedAt = time.Now()
+		},
+	}))
+	r.Equal([]string{"publisher"}, stopped)
+	r.GreaterOrEqual(int64(stoppedAt.Sub(cancelledAt)), int64(drainPeriod))
+}
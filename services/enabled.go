@@ -0,0 +1,70 @@
+package services
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// filterEnabled removes the services which are not enabled in the config and logs them.
+// It fails if an enabled service depends on one of the removed services. The names in the
+// config which do not match any service are logged, as they are likely to be typos.
+func filterEnabled(logger *log.Entry, services []Service, servicesCfg config.ServicesConfig) ([]Service, error) {
+	if len(servicesCfg.Enabled) == 0 && len(servicesCfg.Disabled) == 0 {
+		return services, nil
+	}
+	enabled := make(map[string]bool)
+	for _, name := range servicesCfg.Enabled {
+		enabled[name] = true
+	}
+	disabled := make(map[string]bool)
+	for _, name := range servicesCfg.Disabled {
+		disabled[name] = true
+	}
+
+	var (
+		filtered []Service
+		skipped  []string
+		removed  = make(map[string]bool)
+	)
+	for _, service := range services {
+		name := service.Name()
+		if (len(enabled) > 0 && !enabled[name]) || disabled[name] {
+			skipped = append(skipped, name)
+			removed[name] = true
+			continue
+		}
+		filtered = append(filtered, service)
+	}
+	if unknown := unknownServiceNames(services, servicesCfg); len(unknown) > 0 {
+		logger.WithField("names", unknown).Warn("unknown service names in the enabled or disabled services")
+	}
+	for _, service := range filtered {
+		for _, dep := range dependenciesOf(service) {
+			if removed[dep] {
+				return nil, fmt.Errorf("%w: service '%s' depends on '%s'", ErrDisabledDependency, service.Name(), dep)
+			}
+		}
+	}
+	if len(skipped) > 0 {
+		logger.WithField("services", skipped).Warn("not starting the services which are disabled in the config")
+	}
+	return filtered, nil
+}
+
+// unknownServiceNames returns the enabled and disabled names which do not match any service.
+func unknownServiceNames(services []Service, servicesCfg config.ServicesConfig) []string {
+	known := make(map[string]bool)
+	for _, service := range services {
+		known[service.Name()] = true
+	}
+	var unknown []string
+	for _, name := range append(append([]string(nil), servicesCfg.Enabled...), servicesCfg.Disabled...) {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,86 @@
+package services
+
+import (
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+func testEnabledServices() []Service {
+	return []Service{
+		&dependentService{name: "json-rpc"},
+		&dependentService{name: "scanner", deps: []string{"json-rpc"}},
+		&dependentService{name: "publisher", deps: []string{"scanner"}},
+		&dependentService{name: "health"},
+	}
+}
+
+func TestFilterEnabledAllowlist(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	filtered, err := filterEnabled(logrus.NewEntry(logger), testEnabledServices(), config.ServicesConfig{
+		Enabled: []string{"json-rpc", "health"},
+	})
+	r.NoError(err)
+	r.Equal([]string{"json-rpc", "health"}, serviceNames(filtered))
+	r.Equal([]string{"scanner", "publisher"}, hook.LastEntry().Data["services"])
+}
+
+func TestFilterEnabledDenylist(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	filtered, err := filterEnabled(logrus.NewEntry(logger), testEnabledServices(), config.ServicesConfig{
+		Disabled: []string{"publisher", "health"},
+	})
+	r.NoError(err)
+	r.Equal([]string{"json-rpc", "scanner"}, serviceNames(filtered))
+	r.Equal([]string{"publisher", "health"}, hook.LastEntry().Data["services"])
+
+	// nothing is filtered or logged by default
+	hook.Reset()
+	filtered, err = filterEnabled(logrus.NewEntry(logger), testEnabledServices(), config.ServicesConfig{})
+	r.NoError(err)
+	r.Len(filtered, 4)
+	r.Nil(hook.LastEntry())
+}
+
+func TestFilterEnabledDependencyConflict(t *testing.T) {
+	r := require.New(t)
+
+	logger, _ := test.NewNullLogger()
+	_, err := filterEnabled(logrus.NewEntry(logger), testEnabledServices(), config.ServicesConfig{
+		Enabled: []string{"scanner", "publisher"},
+	})
+	r.ErrorIs(err, ErrDisabledDependency)
+	r.EqualError(err, "dependency is disabled: service 'scanner' depends on 'json-rpc'")
+
+	_, err = filterEnabled(logrus.NewEntry(logger), testEnabledServices(), config.ServicesConfig{
+		Disabled: []string{"scanner"},
+	})
+	r.EqualError(err, "dependency is disabled: service 'publisher' depends on 'scanner'")
+}
+
+func TestFilterEnabledUnknownNames(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	_, err := filterEnabled(logrus.NewEntry(logger), testEnabledServices(), config.ServicesConfig{
+		Enabled:  []string{"json-rpc", "json-rcp"},
+		Disabled: []string{"helth"},
+	})
+	r.NoError(err)
+	var warned bool
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == "unknown service names in the enabled or disabled services" {
+			warned = true
+			r.Equal([]string{"json-rcp", "helth"}, entry.Data["names"])
+		}
+	}
+	r.True(warned)
+}
@@ -0,0 +1,105 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// Service lifecycle phases
+const (
+	PhaseStart = "start"
+	PhaseStop  = "stop"
+	PhaseRun   = "run"
+)
+
+// ErrStartTimeout is used when a service does not start within its start timeout.
+var ErrStartTimeout = errors.New("start timed out")
+
+// ErrNilService is used when the services to start contain a nil service.
+var ErrNilService = errors.New("nil service")
+
+// ErrDuplicateServiceName is used when the services to start share names.
+var ErrDuplicateServiceName = errors.New("duplicate service names")
+
+// ErrNoServices is used when a container has no services to run.
+var ErrNoServices = errors.New("no services to run")
+
+// ErrStartupDeadline is used when the services are not all started within the startup deadline.
+var ErrStartupDeadline = errors.New("startup deadline exceeded")
+
+// ErrDependencyFailed is used when a service is not started because a dependency failed to start.
+var ErrDependencyFailed = errors.New("dependency failed to start")
+
+// ErrDisabledDependency is used when an enabled service depends on a disabled service.
+var ErrDisabledDependency = errors.New("dependency is disabled")
+
+// ErrNotReady is used when the services do not become ready.
+var ErrNotReady = errors.New("services are not ready")
+
+// ErrStopTimeout is used when a service does not stop within its stop timeout.
+var ErrStopTimeout = errors.New("stop timed out")
+
+// ErrShutdownTimeout is used when the services are not stopped within the shutdown timeout.
+var ErrShutdownTimeout = errors.New("shutdown timed out")
+
+// ServiceError tells which service failed in which phase.
+type ServiceError struct {
+	Name  string
+	Phase string
+	Err   error
+}
+
+func (e *ServiceError) Error() string {
+	return fmt.Sprintf("service '%s' failed to %s: %v", e.Name, e.Phase, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// ShutdownError tells which signal started the shutdown which the services failed during.
+type ShutdownError struct {
+	Signal os.Signal
+	Err    error
+}
+
+func (e *ShutdownError) Error() string {
+	return fmt.Sprintf("shutdown by signal %s: %v", e.Signal, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *ShutdownError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError is a panic recovered from a service.
+type PanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// combineErrors returns nil, the only non-nil error or all of the non-nil errors combined.
+func combineErrors(errs ...error) error {
+	var nonNil []error
+	for _, err := range errs {
+		if err != nil {
+			nonNil = append(nonNil, err)
+		}
+	}
+	switch len(nonNil) {
+	case 0:
+		return nil
+	case 1:
+		return nonNil[0]
+	default:
+		return multierror.Append(nonNil[0], nonNil[1:]...)
+	}
+}
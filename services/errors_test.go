@@ -0,0 +1,288 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+type failingService struct {
+	name     string
+	startErr error
+	stopErr  error
+}
+
+func (s *failingService) Start(ctx context.Context) error {
+	return s.startErr
+}
+
+func (s *failingService) Stop() error {
+	return s.stopErr
+}
+
+func (s *failingService) Name() string {
+	return s.name
+}
+
+func TestServiceErrorOnStart(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	startErr := errors.New("bad config")
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&failingService{name: "json-rpc"},
+		&failingService{name: "scanner", startErr: startErr},
+	})
+
+	var serviceErr *ServiceError
+	r.True(errors.As(err, &serviceErr))
+	r.Equal("scanner", serviceErr.Name)
+	r.Equal(PhaseStart, serviceErr.Phase)
+	r.ErrorIs(err, startErr)
+}
+
+func TestServiceErrorOnStop(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	stopErr := errors.New("stuck")
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&failingService{name: "json-rpc"},
+		&failingService{name: "publisher", stopErr: stopErr},
+	})
+
+	var serviceErr *ServiceError
+	r.True(errors.As(err, &serviceErr))
+	r.Equal("publisher", serviceErr.Name)
+	r.Equal(PhaseStop, serviceErr.Phase)
+	r.ErrorIs(err, stopErr)
+}
+
+type panickingService struct{}
+
+func (s *panickingService) Start(ctx context.Context) error {
+	panic("oops")
+}
+
+func (s *panickingService) Stop() error {
+	return nil
+}
+
+func (s *panickingService) Name() string {
+	return "panicking"
+}
+
+func TestServicePanicOnStart(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var stopped []string
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&orderedService{name: "json-rpc", stopped: &stopped},
+		&panickingService{},
+	})
+
+	var serviceErr *ServiceError
+	r.True(errors.As(err, &serviceErr))
+	r.Equal("panicking", serviceErr.Name)
+	r.Equal(PhaseStart, serviceErr.Phase)
+
+	var panicErr *PanicError
+	r.True(errors.As(err, &panicErr))
+	r.Equal("oops", panicErr.Value)
+	r.NotEmpty(panicErr.Stack)
+
+	r.Equal([]string{"json-rpc"}, stopped)
+}
+
+type recordingPanicSink struct {
+	reports []PanicReport
+	mu      sync.Mutex
+}
+
+func (sink *recordingPanicSink) ReportPanic(report PanicReport) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.reports = append(sink.reports, report)
+}
+
+func TestServicePanicIsReported(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	sink := &recordingPanicSink{}
+	err := StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&panickingService{},
+	}, Options{Panics: sink})
+	r.Error(err)
+
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	r.Len(sink.reports, 1)
+	report := sink.reports[0]
+	r.Equal("panicking", report.Service)
+	r.Equal(ExecID(mainCtx.Context()), report.ExecID)
+	r.Equal("oops", report.Value)
+	r.Contains(string(report.Stack), "panickingService")
+	r.False(report.Time.IsZero())
+}
+
+func TestCollectStartErrors(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var stopped []string
+	err := StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&failingService{name: "json-rpc", startErr: errors.New("bad url")},
+		&orderedService{name: "publisher", stopped: &stopped},
+		&failingService{name: "scanner", startErr: errors.New("bad key")},
+	}, Options{CollectStartErrors: true})
+	r.Error(err)
+	r.Contains(err.Error(), "service 'json-rpc' failed to start: bad url")
+	r.Contains(err.Error(), "service 'scanner' failed to start: bad key")
+	r.ErrorIs(mainCtx.Context().Err(), context.Canceled)
+	r.Equal([]string{"publisher"}, stopped)
+}
+
+func TestStartStopsAtFirstError(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&failingService{name: "json-rpc", startErr: errors.New("bad url")},
+		&failingService{name: "scanner", startErr: errors.New("bad key")},
+	})
+	r.EqualError(err, "service 'json-rpc' failed to start: bad url")
+}
+
+type runFailingService struct {
+	name   string
+	runErr error
+}
+
+func (s *runFailingService) Start(ctx context.Context) error {
+	go func() {
+		time.Sleep(time.Millisecond * 20)
+		FailService(ctx, s.runErr)
+	}()
+	return nil
+}
+
+func (s *runFailingService) Stop() error {
+	return nil
+}
+
+func (s *runFailingService) Name() string {
+	return s.name
+}
+
+func TestSignalShutdownReturnsNil(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	time.AfterFunc(time.Millisecond*50, func() {
+		mainCtx.sigc <- syscall.SIGTERM
+	})
+	r.NoError(StartServices(mainCtx, logrus.NewEntry(logger), []Service{&failingService{name: "scanner"}}))
+	r.True(hasLogEntry(hook, "shutting down"))
+	r.False(hasLogEntry(hook, "shutting down after a service failure"))
+}
+
+func TestServiceFailureShutdownReturnsError(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	runErr := errors.New("lost the connection")
+	err := StartServices(mainCtx, logrus.NewEntry(logger), []Service{
+		&failingService{name: "publisher"},
+		&runFailingService{name: "scanner", runErr: runErr},
+	})
+	r.ErrorIs(err, runErr)
+	var serviceErr *ServiceError
+	r.True(errors.As(err, &serviceErr))
+	r.Equal("scanner", serviceErr.Name)
+	r.Equal(PhaseRun, serviceErr.Phase)
+	r.True(hasLogEntry(hook, "shutting down after a service failure"))
+}
+
+func TestFailServiceWithoutServiceContext(t *testing.T) {
+	r := require.New(t)
+
+	r.False(FailService(context.Background(), errors.New("failed")))
+	r.False(FailService(WithServiceName(context.Background(), "scanner"), errors.New("failed")))
+}
+
+func TestShutdownErrorHasSignal(t *testing.T) {
+	for _, sig := range []os.Signal{syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP} {
+		t.Run(sig.String(), func(t *testing.T) {
+			r := require.New(t)
+
+			mainCtx := NewMainContext()
+			defer mainCtx.Cancel()
+
+			var stopped []string
+			stopErr := errors.New("failed to flush")
+			time.AfterFunc(time.Millisecond*50, func() {
+				mainCtx.sigc <- sig
+			})
+			err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+				&orderedService{name: "publisher", stopped: &stopped, stopErr: stopErr},
+			})
+			var shutdownErr *ShutdownError
+			r.True(errors.As(err, &shutdownErr))
+			r.Equal(sig, shutdownErr.Signal)
+			r.ErrorIs(err, stopErr)
+			r.Contains(err.Error(), "shutdown by signal "+sig.String())
+
+			shutdownSig, ok := mainCtx.ShutdownSignal()
+			r.True(ok)
+			r.Equal(sig, shutdownSig)
+		})
+	}
+}
+
+func TestShutdownErrorWithoutSignal(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var stopped []string
+	stopErr := errors.New("failed to flush")
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&orderedService{name: "publisher", stopped: &stopped, stopErr: stopErr},
+	})
+	r.ErrorIs(err, stopErr)
+	var shutdownErr *ShutdownError
+	r.False(errors.As(err, &shutdownErr))
+	_, ok := mainCtx.ShutdownSignal()
+	r.False(ok)
+}
@@ -0,0 +1,85 @@
+package services
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType is the type of a service lifecycle event.
+type EventType string
+
+// Service lifecycle events
+const (
+	ServiceStarting EventType = "starting"
+	ServiceStarted  EventType = "started"
+	ServiceFailed   EventType = "failed"
+	ServiceStopping EventType = "stopping"
+	ServiceStopped  EventType = "stopped"
+)
+
+var stateEvents = map[ServiceState]EventType{
+	StateStarting: ServiceStarting,
+	StateRunning:  ServiceStarted,
+	StateFailed:   ServiceFailed,
+	StateStopping: ServiceStopping,
+	StateStopped:  ServiceStopped,
+}
+
+// Event is a service lifecycle event.
+type Event struct {
+	Type    EventType
+	Service string
+	Err     error
+	Time    time.Time
+}
+
+// EventBus delivers the service lifecycle events to the subscribers. It is safe for concurrent use.
+type EventBus struct {
+	subscribers []func(Event)
+	mu          sync.RWMutex
+	// publishMu makes the subscribers receive the events one by one in the same order
+	publishMu sync.Mutex
+}
+
+// NewEventBus creates a new event bus.
+func NewEventBus() *EventBus {
+	return &EventBus{}
+}
+
+// Subscribe adds a subscriber which is called synchronously for every published event.
+// The subscribers are called in the subscription order and they should not block or publish.
+func (bus *EventBus) Subscribe(subscriber func(Event)) {
+	bus.mu.Lock()
+	defer bus.mu.Unlock()
+	bus.subscribers = append(bus.subscribers, subscriber)
+}
+
+// Publish delivers the event to all of the subscribers.
+func (bus *EventBus) Publish(event Event) {
+	bus.mu.RLock()
+	subscribers := bus.subscribers
+	bus.mu.RUnlock()
+
+	bus.publishMu.Lock()
+	defer bus.publishMu.Unlock()
+	for _, subscriber := range subscribers {
+		subscriber(event)
+	}
+}
+
+// setState updates the status of the service and publishes the lifecycle event.
+func (opts Options) setState(name string, state ServiceState, err error) {
+	now := opts.clock().Now()
+	opts.Statuses.setAt(name, state, err, now)
+	eventType, ok := stateEvents[state]
+	if !ok || opts.Events == nil {
+		return
+	}
+	opts.Events.Publish(Event{Type: eventType, Service: name, Err: err, Time: now})
+}
+
+// setStopState updates the status of a stopping or stopped service together with the stop reason.
+func (opts Options) setStopState(name string, state ServiceState, reason StopReason, err error) {
+	opts.Statuses.setStopReason(name, reason)
+	opts.setState(name, state, err)
+}
@@ -0,0 +1,76 @@
+package services
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type recordedEvent struct {
+	Type    EventType
+	Service string
+}
+
+func TestLifecycleEvents(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var events []recordedEvent
+	mainCtx.Events().Subscribe(func(event Event) {
+		r.NoError(event.Err)
+		r.False(event.Time.IsZero())
+		events = append(events, recordedEvent{Type: event.Type, Service: event.Service})
+		if event.Type == ServiceStarted && event.Service == "second" {
+			mainCtx.Cancel()
+		}
+	})
+
+	var stopped []string
+	r.NoError(StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&orderedService{name: "first", stopped: &stopped},
+		&orderedService{name: "second", stopped: &stopped},
+	}))
+	r.Equal([]recordedEvent{
+		{ServiceStarting, "first"},
+		{ServiceStarted, "first"},
+		{ServiceStarting, "second"},
+		{ServiceStarted, "second"},
+		{ServiceStopping, "second"},
+		{ServiceStopped, "second"},
+		{ServiceStopping, "first"},
+		{ServiceStopped, "first"},
+	}, events)
+}
+
+func TestLifecycleEventsFailure(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	bus := NewEventBus()
+	var first, second []EventType
+	var failErr error
+	bus.Subscribe(func(event Event) {
+		first = append(first, event.Type)
+		if event.Type == ServiceFailed {
+			failErr = event.Err
+		}
+	})
+	bus.Subscribe(func(event Event) {
+		second = append(second, event.Type)
+	})
+
+	startErr := errors.New("failed to listen")
+	err := StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&failingService{name: "failing", startErr: startErr},
+	}, Options{Events: bus})
+	r.ErrorIs(err, startErr)
+	r.Equal([]EventType{ServiceStarting, ServiceFailed}, first)
+	r.Equal(first, second)
+	r.ErrorIs(failErr, startErr)
+}
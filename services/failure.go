@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"sync"
+)
+
+// runFailures collects the errors which the services fail with after starting.
+type runFailures struct {
+	errs   []error
+	cancel func()
+	opts   Options
+	mu     sync.Mutex
+}
+
+func (failures *runFailures) fail(name string, err error) {
+	failures.add(&ServiceError{Name: name, Phase: PhaseRun, Err: err})
+	failures.opts.setStopState(name, StateFailed, StopReasonError, err)
+	failures.cancel()
+}
+
+// add records the error and stops all of the services. The status of the failed service
+// is set by the caller.
+func (failures *runFailures) add(err error) {
+	failures.mu.Lock()
+	failures.errs = append(failures.errs, err)
+	failures.mu.Unlock()
+	failures.cancel()
+}
+
+func (failures *runFailures) get() []error {
+	failures.mu.Lock()
+	defer failures.mu.Unlock()
+	return append([]error(nil), failures.errs...)
+}
+
+type runFailuresKey struct{}
+
+func withRunFailures(ctx context.Context, failures *runFailures) context.Context {
+	return context.WithValue(ctx, runFailuresKey{}, failures)
+}
+
+// FailService reports that the service of the context failed while running. All of the
+// services are stopped and the error is returned from starting the services. It tells if
+// the context is a service context which the failure can be reported with.
+func FailService(ctx context.Context, err error) bool {
+	name, ok := ServiceName(ctx)
+	if !ok || err == nil {
+		return false
+	}
+	failures, ok := ctx.Value(runFailuresKey{}).(*runFailures)
+	if !ok {
+		return false
+	}
+	failures.fail(name, err)
+	return true
+}
+
+// failRestart reports that a restarted service failed to start again, so that all of the
+// services are stopped like after the initial start failure.
+func failRestart(ctx context.Context, err *ServiceError) bool {
+	failures, ok := ctx.Value(runFailuresKey{}).(*runFailures)
+	if !ok {
+		return false
+	}
+	failures.add(err)
+	return true
+}
@@ -0,0 +1,107 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// funcService runs a function in the background as a service.
+type funcService struct {
+	name string
+	run  func(ctx context.Context) error
+
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+	mu     sync.Mutex
+}
+
+// ServiceFunc adapts a run loop to the Service interface. Start runs the function in the
+// background with a context which is cancelled only by Stop, so that the loop keeps running
+// while the services drain, and Stop waits for the function to return. If the function
+// fails while running, the failure is reported like FailService.
+func ServiceFunc(name string, run func(ctx context.Context) error) Service {
+	return &funcService{name: name, run: run}
+}
+
+// Start implements the Service interface.
+func (s *funcService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		return errors.New("already started")
+	}
+	runCtx, cancel := context.WithCancel(detachedContext{ctx})
+	done := make(chan struct{})
+	s.cancel = cancel
+	s.done = done
+	s.err = nil
+	go func() {
+		defer close(done)
+		err := s.run(runCtx)
+		if err == nil || runCtx.Err() != nil {
+			// the function was stopped, Stop returns the error if there is one
+			s.setErr(err)
+			return
+		}
+		if !FailService(ctx, err) {
+			s.setErr(err)
+		}
+	}()
+	return nil
+}
+
+func (s *funcService) setErr(err error) {
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	s.mu.Lock()
+	s.err = err
+	s.mu.Unlock()
+}
+
+// Stop implements the Service interface. It can be called before Start or more than once.
+func (s *funcService) Stop() error {
+	s.mu.Lock()
+	cancel, done := s.cancel, s.done
+	s.mu.Unlock()
+	if done == nil {
+		return nil
+	}
+	cancel()
+	<-done
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.err
+	s.cancel, s.done, s.err = nil, nil, nil
+	return err
+}
+
+// Name implements the Service interface.
+func (s *funcService) Name() string {
+	return s.name
+}
+
+// detachedContext keeps the values of the parent context but not its cancellation.
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (ctx detachedContext) Value(key interface{}) interface{} {
+	return ctx.parent.Value(key)
+}
@@ -0,0 +1,146 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceFunc(t *testing.T) {
+	r := require.New(t)
+
+	ticks := make(chan struct{}, 100)
+	returned := make(chan struct{})
+	service := ServiceFunc("ticker", func(ctx context.Context) error {
+		defer close(returned)
+		for {
+			select {
+			case <-ctx.Done():
+				// Stop waits for the cleanup
+				time.Sleep(time.Millisecond * 50)
+				return ctx.Err()
+			case <-time.After(time.Millisecond * 5):
+				ticks <- struct{}{}
+			}
+		}
+	})
+	r.Equal("ticker", service.Name())
+	r.NoError(service.Stop())
+
+	r.NoError(service.Start(context.Background()))
+	r.Error(service.Start(context.Background()))
+	<-ticks
+	r.NoError(service.Stop())
+	select {
+	case <-returned:
+	default:
+		r.FailNow("stop did not wait for the function to return")
+	}
+	r.NoError(service.Stop())
+}
+
+func TestServiceFuncStopError(t *testing.T) {
+	r := require.New(t)
+
+	flushErr := errors.New("failed to flush")
+	service := ServiceFunc("publisher", func(ctx context.Context) error {
+		<-ctx.Done()
+		return flushErr
+	})
+	r.NoError(service.Start(context.Background()))
+	r.ErrorIs(service.Stop(), flushErr)
+
+	// it can be started again after stopping
+	r.NoError(service.Start(context.Background()))
+	r.ErrorIs(service.Stop(), flushErr)
+}
+
+func TestServiceFuncRunFailure(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	runErr := errors.New("lost the connection")
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&failingService{name: "publisher"},
+		ServiceFunc("scanner", func(ctx context.Context) error {
+			time.Sleep(time.Millisecond * 20)
+			return runErr
+		}),
+	})
+	r.ErrorIs(err, runErr)
+	var serviceErr *ServiceError
+	r.True(errors.As(err, &serviceErr))
+	r.Equal("scanner", serviceErr.Name)
+	r.Equal(PhaseRun, serviceErr.Phase)
+}
+
+func TestServiceFuncStopsWithServices(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	running := make(chan struct{})
+	stopped := make(chan struct{})
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+			ServiceFunc("loop", func(ctx context.Context) error {
+				close(running)
+				<-ctx.Done()
+				close(stopped)
+				return nil
+			}),
+		})
+	}()
+	<-running
+	mainCtx.Cancel()
+	select {
+	case err := <-errCh:
+		r.NoError(err)
+	case <-time.After(time.Second):
+		r.FailNow("services did not stop")
+	}
+	select {
+	case <-stopped:
+	default:
+		r.FailNow("the function was not stopped")
+	}
+}
+
+func TestServiceFuncRunsDuringDrain(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var loopStoppedAt time.Time
+	running := make(chan struct{})
+	drainer := &drainingService{drainDuration: time.Millisecond * 50}
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		ServiceFunc("loop", func(ctx context.Context) error {
+			close(running)
+			<-ctx.Done()
+			loopStoppedAt = time.Now()
+			return nil
+		}),
+		drainer,
+	}, Options{
+		DrainPeriod: time.Second,
+	}))
+	<-running
+
+	// the loop is cancelled by Stop, after the drain and the later services are stopped
+	drainer.mu.Lock()
+	defer drainer.mu.Unlock()
+	r.False(loopStoppedAt.IsZero())
+	r.False(loopStoppedAt.Before(drainer.drainedAt))
+	r.False(loopStoppedAt.Before(drainer.stoppedAt))
+}
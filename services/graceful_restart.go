@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// EnableRestart makes the reload signal restart the services with the reloaded config,
+// instead of reloading them in place. It has an effect only if the reload is enabled, too.
+func (mainCtx *MainContext) EnableRestart() {
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	mainCtx.restartEnabled = true
+}
+
+// Restart requests the services to be restarted with the reloaded config as the reload
+// signal would.
+func (mainCtx *MainContext) Restart() {
+	select {
+	case mainCtx.restartc <- struct{}{}:
+	default:
+	}
+}
+
+func (mainCtx *MainContext) canRestart() bool {
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	return mainCtx.restartEnabled && mainCtx.loadConfig != nil
+}
+
+// newChild creates a main context which is cancelled together with this one and shares
+// the statuses and the events.
+func (mainCtx *MainContext) newChild() *MainContext {
+	ctx, cancel := context.WithCancel(mainCtx.ctx)
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	child := &MainContext{
+		ctx:        ctx,
+		cancel:     cancel,
+		sigc:       make(chan os.Signal, signalBufferSize),
+		interruptc: make(chan struct{}, 1),
+		restartc:   make(chan struct{}, 1),
+		exit:       mainCtx.exit,
+		statuses:   mainCtx.statuses,
+		events:     mainCtx.events,
+
+		signalDebounce:  mainCtx.signalDebounce,
+		clock:           mainCtx.clock,
+		statusLogger:    mainCtx.statusLogger,
+		stackDumpWriter: mainCtx.stackDumpWriter,
+	}
+	go child.handleSignals()
+	return child
+}
+
+// generation is a set of services which runs in its own context until it is restarted.
+type generation struct {
+	mainCtx  *MainContext
+	cfg      config.Config
+	services []Service
+	opts     Options
+
+	startupDone chan error
+	exited      chan struct{}
+	err         error
+}
+
+// newGeneration gets the services of the config in a new child context.
+func newGeneration(
+	mainCtx *MainContext, logger *log.Entry, cfg config.Config, servers serverSet,
+	getServices func(ctx context.Context, cfg config.Config) ([]Service, error),
+) (*generation, error) {
+	child := mainCtx.newChild()
+	serviceList, err := initServices(child.Context(), logger, cfg, getServices)
+	if err != nil {
+		child.Cancel()
+		return nil, err
+	}
+	child.setConfig(cfg)
+	return &generation{
+		mainCtx:  child,
+		cfg:      cfg,
+		services: serviceList,
+		opts:     servers.options(cfg),
+	}, nil
+}
+
+func (gen *generation) start(logger *log.Entry) {
+	gen.startupDone = make(chan error, 1)
+	gen.exited = make(chan struct{})
+	opts := gen.opts
+	opts.onStartupDone = func(err error) {
+		gen.startupDone <- err
+	}
+	go func() {
+		defer close(gen.exited)
+		gen.err = StartServicesWithOptions(gen.mainCtx, logger, gen.services, opts)
+	}()
+}
+
+// waitForStartup waits until all of the services are started. If the startup fails, it waits
+// for the services to stop and returns the error.
+func (gen *generation) waitForStartup() error {
+	select {
+	case err := <-gen.startupDone:
+		if err == nil {
+			return nil
+		}
+	case <-gen.exited:
+	}
+	<-gen.exited
+	return gen.err
+}
+
+// stop stops the services and waits for them.
+func (gen *generation) stop() error {
+	gen.mainCtx.Cancel()
+	<-gen.exited
+	return gen.err
+}
+
+// runWithRestarts runs the servers from the config once and recreates the rest of the services
+// every time a restart is requested. If the new services cannot be created or started, the
+// previous services keep running or are started again.
+func runWithRestarts(
+	mainCtx *MainContext, logger *log.Entry, cfg config.Config, servers serverSet,
+	getServices func(ctx context.Context, cfg config.Config) ([]Service, error),
+) error {
+	current, err := newGeneration(mainCtx, logger, cfg, servers, getServices)
+	if err != nil {
+		return err
+	}
+	if isDryRun(logger, servers.wrap(current.services)) {
+		current.mainCtx.Cancel()
+		return nil
+	}
+
+	// the servers keep listening during the restarts
+	serverGen := &generation{mainCtx: mainCtx.newChild(), cfg: cfg, services: servers.all(), opts: servers.options(cfg)}
+	serverGen.opts.Statuses = NewStatusRegistry()
+	serverGen.opts.Events = NewEventBus()
+	if len(serverGen.services) > 0 {
+		serverGen.start(logger)
+		if err := serverGen.waitForStartup(); err != nil {
+			current.mainCtx.Cancel()
+			return err
+		}
+	}
+
+	current.start(logger)
+	err = current.waitForStartup()
+runLoop:
+	for err == nil {
+		select {
+		case <-current.exited:
+			err = current.err
+			break runLoop
+		case <-serverGen.exited: // blocks if there are no servers
+			mainCtx.Cancel()
+			err = combineErrors(current.stop(), serverGen.err)
+			serverGen.exited = nil
+			break runLoop
+		case <-mainCtx.restartc:
+			current, err = restartGeneration(mainCtx, logger, current, servers, getServices)
+		}
+	}
+	// all of the services stop if a restart fails
+	mainCtx.Cancel()
+	if serverGen.exited != nil {
+		err = combineErrors(err, serverGen.stop())
+	}
+
+	opts := servers.options(cfg)
+	opts.runShutdownCallbacks(logger, mainCtx.getShutdownCallbacks())
+	if mainCtx.isExitTriggered() {
+		return ErrExitTriggered
+	}
+	return mainCtx.withShutdownSignal(err)
+}
+
+// restartGeneration reloads the config and replaces the current services with the new ones.
+// It returns the services which are running after the restart.
+func restartGeneration(
+	mainCtx *MainContext, logger *log.Entry, current *generation, servers serverSet,
+	getServices func(ctx context.Context, cfg config.Config) ([]Service, error),
+) (*generation, error) {
+	logger.Info("restarting the services")
+	mainCtx.mu.RLock()
+	loadConfig := mainCtx.loadConfig
+	mainCtx.mu.RUnlock()
+
+	cfg, err := loadConfig()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err != nil {
+		logger.WithError(err).Error("failed to reload config - keeping the running services")
+		return current, nil
+	}
+	next, err := newGeneration(mainCtx, logger, cfg, servers, getServices)
+	if err != nil {
+		logger.WithError(err).Error("failed to create the new services - keeping the running services")
+		return current, nil
+	}
+
+	if err := current.stop(); err != nil {
+		logger.WithError(err).Warn("previous services stopped with error")
+	}
+	next.start(logger)
+	err = next.waitForStartup()
+	if err == nil {
+		logger.Info("restarted the services")
+		mainCtx.runReloadCallbacks(cfg)
+		return next, nil
+	}
+	if mainCtx.Context().Err() != nil {
+		// the restart was aborted by the shutdown
+		return next, err
+	}
+
+	logger.WithError(err).Error("failed to start the new services - starting the previous services again")
+	previous, err := newGeneration(mainCtx, logger, current.cfg, servers, getServices)
+	if err != nil {
+		return current, err
+	}
+	previous.start(logger)
+	return previous, previous.waitForStartup()
+}
@@ -0,0 +1,176 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// restartTest runs the services which are named after the chain ID in the config.
+type restartTest struct {
+	cfgs     chan config.Config
+	startErr map[int]error
+	builds   int
+	mu       sync.Mutex
+}
+
+func newRestartTest() *restartTest {
+	return &restartTest{cfgs: make(chan config.Config, 1), startErr: make(map[int]error)}
+}
+
+func (rt *restartTest) loadConfig() (config.Config, error) {
+	return <-rt.cfgs, nil
+}
+
+func (rt *restartTest) getServices(ctx context.Context, cfg config.Config) ([]Service, error) {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	rt.builds++
+	return []Service{&failingService{name: fmt.Sprintf("scanner-%d", cfg.ChainID), startErr: rt.startErr[cfg.ChainID]}}, nil
+}
+
+func (rt *restartTest) getBuilds() int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.builds
+}
+
+func testRestartConfig(t *testing.T, chainID int) config.Config {
+	var cfg config.Config
+	require.NoError(t, config.ApplyDefaults(&cfg))
+	cfg.ChainID = chainID
+	return cfg
+}
+
+func startWithRestarts(mainCtx *MainContext, rt *restartTest, cfg config.Config) chan error {
+	mainCtx.EnableReload(rt.loadConfig)
+	mainCtx.EnableRestart()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- runServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), cfg, rt.getServices)
+	}()
+	return errCh
+}
+
+func requireRunning(r *require.Assertions, mainCtx *MainContext, name string) {
+	r.Eventually(func() bool {
+		status, _ := mainCtx.Statuses().Status(name)
+		return status.State == StateRunning
+	}, time.Second, time.Millisecond*10, name)
+}
+
+func TestRestartSwapsServices(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	rt := newRestartTest()
+	errCh := startWithRestarts(mainCtx, rt, testRestartConfig(t, 1))
+	requireRunning(r, mainCtx, "scanner-1")
+
+	reloaded := make(chan config.Config, 1)
+	mainCtx.OnReload(func(cfg config.Config) {
+		reloaded <- cfg
+	})
+	rt.cfgs <- testRestartConfig(t, 2)
+	mainCtx.Restart()
+	requireRunning(r, mainCtx, "scanner-2")
+	_, ok := mainCtx.Statuses().Status("scanner-1")
+	r.False(ok)
+	r.Equal(2, (<-reloaded).ChainID)
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+}
+
+func TestRestartWithBadConfigKeepsServices(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	rt := newRestartTest()
+	errCh := startWithRestarts(mainCtx, rt, testRestartConfig(t, 1))
+	requireRunning(r, mainCtx, "scanner-1")
+
+	badCfg := testRestartConfig(t, 2)
+	badCfg.Log.Level = "loud"
+	rt.cfgs <- badCfg
+	mainCtx.Restart()
+	r.Eventually(func() bool {
+		return len(rt.cfgs) == 0
+	}, time.Second, time.Millisecond*10)
+	time.Sleep(time.Millisecond * 50)
+
+	r.Equal(1, rt.getBuilds())
+	requireRunning(r, mainCtx, "scanner-1")
+	r.NoError(mainCtx.Context().Err())
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+}
+
+func TestRestartFallsBackWhenServicesFail(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	rt := newRestartTest()
+	startErr := errors.New("failed to start")
+	rt.startErr[2] = startErr
+	errCh := startWithRestarts(mainCtx, rt, testRestartConfig(t, 1))
+	requireRunning(r, mainCtx, "scanner-1")
+
+	rt.cfgs <- testRestartConfig(t, 2)
+	mainCtx.Restart()
+	r.Eventually(func() bool {
+		return rt.getBuilds() == 3
+	}, time.Second, time.Millisecond*10)
+	requireRunning(r, mainCtx, "scanner-1")
+	r.NoError(mainCtx.Context().Err())
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+}
+
+func TestReloadAfterRestartSkipsPreviousServices(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	rt := newRestartTest()
+	mainCtx.EnableReload(rt.loadConfig)
+	mainCtx.EnableRestart()
+
+	var services []*reloadableService
+	getServices := func(ctx context.Context, cfg config.Config) ([]Service, error) {
+		service := &reloadableService{reloaded: make(chan config.Config, 1)}
+		services = append(services, service)
+		return []Service{service}, nil
+	}
+	logger := logrus.NewEntry(logrus.StandardLogger())
+	current, err := newGeneration(mainCtx, logger, testRestartConfig(t, 1), serverSet{}, getServices)
+	r.NoError(err)
+	current.start(logger)
+	r.NoError(current.waitForStartup())
+
+	rt.cfgs <- testRestartConfig(t, 2)
+	next, err := restartGeneration(mainCtx, logger, current, serverSet{}, getServices)
+	r.NoError(err)
+	r.Len(services, 2)
+
+	// the stopped services do not receive the reloaded config anymore
+	current.mainCtx.runReloadCallbacks(testRestartConfig(t, 3))
+	next.mainCtx.runReloadCallbacks(testRestartConfig(t, 3))
+	r.Len(services[0].reloaded, 0)
+	r.Equal(3, (<-services[1].reloaded).ChainID)
+
+	r.NoError(next.stop())
+}
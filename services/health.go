@@ -0,0 +1,191 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Default health check values
+const (
+	DefaultHealthCheckInterval = time.Second * 15
+	DefaultHealthGracePeriod   = time.Minute
+)
+
+// HealthChecker is implemented by services which can tell if they are still healthy after starting.
+type HealthChecker interface {
+	Healthy() error
+}
+
+type healthState struct {
+	unhealthySince time.Time
+	reported       bool
+	// restartDone is closed after the unhealthy service is restarted
+	restartDone chan struct{}
+}
+
+// healthRestarts tracks the restarts of the unhealthy services and the services which are
+// affected by a reload so that the shutdown does not race with them.
+type healthRestarts struct {
+	wg     sync.WaitGroup
+	closed bool
+	mu     sync.Mutex
+}
+
+// add tells if a new restart can begin.
+func (restarts *healthRestarts) add() bool {
+	restarts.mu.Lock()
+	defer restarts.mu.Unlock()
+	if restarts.closed {
+		return false
+	}
+	restarts.wg.Add(1)
+	return true
+}
+
+func (restarts *healthRestarts) done() {
+	restarts.wg.Done()
+}
+
+// wait prevents the new restarts and waits for the ongoing ones until the timeout. It tells
+// if they finished in time.
+func (restarts *healthRestarts) wait(clock Clock, timeout time.Duration) bool {
+	restarts.mu.Lock()
+	restarts.closed = true
+	restarts.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		restarts.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-clock.After(timeout):
+		return false
+	}
+}
+
+func (opts Options) healthCheckInterval() time.Duration {
+	if opts.HealthCheckInterval > 0 {
+		return opts.HealthCheckInterval
+	}
+	return DefaultHealthCheckInterval
+}
+
+func (opts Options) healthGracePeriod() time.Duration {
+	if opts.HealthGracePeriod > 0 {
+		return opts.HealthGracePeriod
+	}
+	return DefaultHealthGracePeriod
+}
+
+// superviseHealth polls the health of the services until the context is done and reports
+// the services which stay unhealthy for longer than the grace period. The services which opt
+// into it are restarted instead.
+func (opts Options) superviseHealth(
+	ctx context.Context, logger *log.Entry, services []Service, restarts *healthRestarts, pending *pendingStarts,
+) {
+	gracePeriod := opts.healthGracePeriod()
+	states := make([]healthState, len(services))
+
+	clock := opts.clock()
+	interval := opts.healthCheckInterval()
+	timer := clock.NewTimer(interval)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C():
+		}
+
+		for i, service := range services {
+			checker, ok := underlying(service).(HealthChecker)
+			if !ok {
+				continue
+			}
+			logger := logger.WithField("service", service.Name())
+			state := &states[i]
+			if state.restartDone != nil {
+				select {
+				case <-state.restartDone:
+					*state = healthState{}
+				default:
+					continue
+				}
+			}
+
+			err := checker.Healthy()
+			if err == nil {
+				opts.Statuses.setHealth(service.Name(), nil, false)
+				if state.reported {
+					logger.Info("service is healthy again")
+				}
+				*state = healthState{}
+				continue
+			}
+
+			if state.unhealthySince.IsZero() {
+				state.unhealthySince = clock.Now()
+			}
+			unhealthyFor := clock.Now().Sub(state.unhealthySince)
+			opts.Statuses.setHealth(service.Name(), err, unhealthyFor > gracePeriod)
+			if unhealthyFor <= gracePeriod || state.reported {
+				continue
+			}
+			logger = logger.WithError(err).WithField("unhealthyFor", unhealthyFor.String())
+			if policy, ok := opts.restartPolicy(service); ok && policy.RestartUnhealthy && restarts.add() {
+				logger.Warn("service is unhealthy - restarting")
+				restartDone := make(chan struct{})
+				state.restartDone = restartDone
+				go func(service Service) {
+					defer restarts.done()
+					defer close(restartDone)
+					opts.restartUnhealthy(WithServiceName(ctx, service.Name()), logger, service, pending)
+				}(service)
+				continue
+			}
+			logger.Error("service is unhealthy")
+			state.reported = true
+		}
+		timer.Reset(interval)
+	}
+}
+
+// restartUnhealthy stops the service and starts it again by using its restart policy. The
+// services are stopped if it does not start again within the restart attempts.
+func (opts Options) restartUnhealthy(ctx context.Context, logger *log.Entry, service Service, pending *pendingStarts) {
+	if err := opts.restartService(ctx, logger, service, StopReasonUnhealthy, pending); err != nil {
+		logger.WithError(err).Error("failed to restart the unhealthy service - stopping")
+		return
+	}
+	logger.Info("restarted the unhealthy service")
+}
+
+// restartService stops the running service for the reason and starts it again in the same way
+// as the initial start, with the start timeout and the panic recovery. If the service fails to
+// start again after its restart attempts, all of the services are stopped with the error unless
+// the service is optional.
+func (opts Options) restartService(
+	ctx context.Context, logger *log.Entry, service Service, reason StopReason, pending *pendingStarts,
+) error {
+	opts.setStopState(service.Name(), StateStopping, reason, nil)
+	if err := opts.stopService(service); err != nil {
+		logger.WithError(err).Warn("failed to stop the service - starting anyway")
+	}
+	opts.recordRestart(service)
+	serviceCtx, cancelService := context.WithCancel(ctx)
+	result := opts.startWithTimeout(ctx, serviceCtx, cancelService, logger, service, pending)
+	if result.err != nil {
+		cancelService()
+		if serviceErr, ok := result.err.(*ServiceError); ok && !isOptional(service) {
+			failRestart(ctx, serviceErr)
+		}
+		return result.err
+	}
+	opts.recordPhaseDuration(service, PhaseStart, result.duration)
+	return nil
+}
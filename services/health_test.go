@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+type flippingService struct {
+	healthyPolls int64
+	polls        int64
+}
+
+func (s *flippingService) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *flippingService) Stop() error {
+	return nil
+}
+
+func (s *flippingService) Name() string {
+	return "flipping"
+}
+
+func (s *flippingService) Healthy() error {
+	if atomic.AddInt64(&s.polls, 1) > s.healthyPolls {
+		return errors.New("stuck")
+	}
+	return nil
+}
+
+func hasLogEntry(hook *test.Hook, msg string) bool {
+	for _, entry := range hook.AllEntries() {
+		if entry.Message == msg {
+			return true
+		}
+	}
+	return false
+}
+
+func TestHealthSupervisorReportsUnhealthy(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	svc := &flippingService{healthyPolls: 3}
+	time.AfterFunc(time.Millisecond*200, mainCtx.Cancel)
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logger), []Service{svc}, Options{
+		HealthCheckInterval: time.Millisecond * 10,
+		HealthGracePeriod:   time.Millisecond * 50,
+	}))
+	r.True(hasLogEntry(hook, "service is unhealthy"))
+}
+
+func TestHealthSupervisorWithinGracePeriod(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	svc := &flippingService{healthyPolls: 3}
+	time.AfterFunc(time.Millisecond*200, mainCtx.Cancel)
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logger), []Service{svc}, Options{
+		HealthCheckInterval: time.Millisecond * 10,
+		HealthGracePeriod:   time.Hour,
+	}))
+	r.Greater(atomic.LoadInt64(&svc.polls), svc.healthyPolls)
+	r.False(hasLogEntry(hook, "service is unhealthy"))
+}
+
+// unhealthyService becomes unhealthy after it starts until it is restarted.
+type unhealthyService struct {
+	name       string
+	starts     int64
+	stops      int64
+	polls      int64
+	stuckAfter int64
+	// onRestart is called when the service is started again
+	onRestart func()
+	// restartErr is returned when the service is started again
+	restartErr error
+}
+
+func (s *unhealthyService) Start(ctx context.Context) error {
+	starts := atomic.AddInt64(&s.starts, 1)
+	atomic.StoreInt64(&s.polls, 0)
+	if starts > 1 && s.onRestart != nil {
+		s.onRestart()
+	}
+	if starts > 1 {
+		return s.restartErr
+	}
+	return nil
+}
+
+func (s *unhealthyService) Stop() error {
+	atomic.AddInt64(&s.stops, 1)
+	return nil
+}
+
+func (s *unhealthyService) Name() string {
+	return s.name
+}
+
+func (s *unhealthyService) Healthy() error {
+	// only the first run gets stuck
+	if atomic.LoadInt64(&s.starts) == 1 && atomic.AddInt64(&s.polls, 1) > s.stuckAfter {
+		return errors.New("stuck")
+	}
+	return nil
+}
+
+func (s *unhealthyService) RestartPolicy() RestartPolicy {
+	return RestartPolicy{RestartUnhealthy: true}
+}
+
+type countingService struct {
+	name   string
+	starts int64
+	stops  int64
+}
+
+func (s *countingService) Start(ctx context.Context) error {
+	atomic.AddInt64(&s.starts, 1)
+	return nil
+}
+
+func (s *countingService) Stop() error {
+	atomic.AddInt64(&s.stops, 1)
+	return nil
+}
+
+func (s *countingService) Name() string {
+	return s.name
+}
+
+func TestHealthSupervisorRestartsUnhealthy(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	stuck := &unhealthyService{name: "stuck", stuckAfter: 2}
+	other := &countingService{name: "other"}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServicesWithOptions(mainCtx, logrus.NewEntry(logger), []Service{other, stuck}, Options{
+			HealthCheckInterval: time.Millisecond * 10,
+			HealthGracePeriod:   time.Millisecond * 30,
+		})
+	}()
+
+	r.Eventually(func() bool {
+		return atomic.LoadInt64(&stuck.starts) == 2
+	}, time.Second, time.Millisecond*10)
+	r.Eventually(func() bool {
+		status, _ := mainCtx.Statuses().Status("stuck")
+		return status.State == StateRunning && status.HealthError == ""
+	}, time.Second, time.Millisecond*10)
+	r.Equal(int64(1), atomic.LoadInt64(&stuck.stops))
+	status, _ := mainCtx.Statuses().Status("stuck")
+	r.Equal(StopReasonUnhealthy, status.StopReason)
+	r.True(hasLogEntry(hook, "restarted the unhealthy service"))
+	r.False(hasLogEntry(hook, "service is unhealthy"))
+
+	// the other service was not touched
+	r.Equal(int64(1), atomic.LoadInt64(&other.starts))
+	r.Equal(int64(0), atomic.LoadInt64(&other.stops))
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+	r.Equal(int64(2), atomic.LoadInt64(&stuck.stops))
+	r.Equal(int64(1), atomic.LoadInt64(&other.stops))
+	status, _ = mainCtx.Statuses().Status("stuck")
+	r.Equal(StopReasonShutdown, status.StopReason)
+}
+
+func TestUnhealthyRestartPanicIsReported(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	sink := &recordingPanicSink{}
+	stuck := &unhealthyService{name: "stuck", stuckAfter: 2, onRestart: func() {
+		panic("failed to reconnect")
+	}}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{stuck}, Options{
+			HealthCheckInterval: time.Millisecond * 10,
+			HealthGracePeriod:   time.Millisecond * 30,
+			Panics:              sink,
+		})
+	}()
+
+	// the panic is recovered and reported like in the initial start, and the failed restart
+	// stops the services
+	select {
+	case err := <-errCh:
+		var panicErr *PanicError
+		r.True(errors.As(err, &panicErr))
+	case <-time.After(time.Second):
+		r.FailNow("services did not stop")
+	}
+	sink.mu.Lock()
+	r.Len(sink.reports, 1)
+	r.Equal("stuck", sink.reports[0].Service)
+	sink.mu.Unlock()
+}
+
+func TestUnhealthyRestartFailureStopsServices(t *testing.T) {
+	tests := []struct {
+		name   string
+		policy RestartPolicy
+		starts int64
+	}{
+		{
+			name:   "no restarts",
+			policy: RestartPolicy{MaxAttempts: NoRestarts},
+			starts: 2,
+		},
+		{
+			name:   "restart attempts exhausted",
+			policy: RestartPolicy{MaxAttempts: 2, BaseInterval: time.Millisecond},
+			starts: 4,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r := require.New(t)
+
+			mainCtx := NewMainContext()
+			defer mainCtx.Cancel()
+
+			restartErr := errors.New("failed to reconnect")
+			stuck := &unhealthyService{name: "stuck", stuckAfter: 2, restartErr: restartErr}
+			other := &countingService{name: "other"}
+			errCh := make(chan error, 1)
+			go func() {
+				errCh <- StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{other, stuck}, Options{
+					HealthCheckInterval: time.Millisecond * 10,
+					HealthGracePeriod:   time.Millisecond * 30,
+					RestartPolicy:       test.policy,
+				})
+			}()
+
+			var err error
+			select {
+			case err = <-errCh:
+			case <-time.After(time.Second):
+				r.FailNow("the failed restart did not stop the services")
+			}
+			r.ErrorIs(err, restartErr)
+			var serviceErr *ServiceError
+			r.True(errors.As(err, &serviceErr))
+			r.Equal("stuck", serviceErr.Name)
+			r.Equal(PhaseStart, serviceErr.Phase)
+			r.Equal(test.starts, atomic.LoadInt64(&stuck.starts))
+			r.Equal(int64(1), atomic.LoadInt64(&other.stops))
+			status, _ := mainCtx.Statuses().Status("stuck")
+			r.Equal(StopReasonError, status.StopReason)
+		})
+	}
+}
+
+func TestHungRestartDoesNotBlockShutdown(t *testing.T) {
+	r := require.New(t)
+
+	defer func(timeout time.Duration) {
+		pendingStartTimeout = timeout
+	}(pendingStartTimeout)
+	pendingStartTimeout = time.Millisecond * 50
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	release := make(chan struct{})
+	defer close(release)
+	stuck := &unhealthyService{name: "stuck", stuckAfter: 2, onRestart: func() {
+		// ignores the context
+		<-release
+	}}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{stuck}, Options{
+			HealthCheckInterval: time.Millisecond * 10,
+			HealthGracePeriod:   time.Millisecond * 30,
+			StartTimeout:        time.Hour,
+			ShutdownTimeout:     time.Millisecond * 100,
+		})
+	}()
+	r.Eventually(func() bool {
+		return atomic.LoadInt64(&stuck.starts) == 2
+	}, time.Second, time.Millisecond*10)
+
+	mainCtx.Cancel()
+	select {
+	case <-errCh:
+	case <-time.After(time.Second):
+		r.FailNow("the hung restart blocked the shutdown")
+	}
+}
+
+func TestHungRestartTimesOut(t *testing.T) {
+	r := require.New(t)
+
+	defer func(timeout time.Duration) {
+		pendingStartTimeout = timeout
+	}(pendingStartTimeout)
+	pendingStartTimeout = time.Millisecond * 50
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	release := make(chan struct{})
+	defer close(release)
+	stuck := &unhealthyService{name: "stuck", stuckAfter: 2, onRestart: func() {
+		<-release
+	}}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{stuck}, Options{
+			HealthCheckInterval: time.Millisecond * 10,
+			HealthGracePeriod:   time.Millisecond * 30,
+			StartTimeout:        time.Millisecond * 50,
+		})
+	}()
+
+	// the restart gets the same start timeout as the initial start and stops the services
+	select {
+	case err := <-errCh:
+		r.ErrorIs(err, ErrStartTimeout)
+	case <-time.After(time.Second):
+		r.FailNow("the timed out restart did not stop the services")
+	}
+}
+
+func TestHealthRestartsWaitTimeout(t *testing.T) {
+	r := require.New(t)
+
+	restarts := &healthRestarts{}
+	clock := Options{}.clock()
+	r.True(restarts.add())
+	r.False(restarts.wait(clock, time.Millisecond*20))
+	// no new restarts after waiting
+	r.False(restarts.add())
+	restarts.done()
+	r.True(restarts.wait(clock, time.Millisecond*20))
+}
@@ -0,0 +1,48 @@
+package services
+
+import "context"
+
+// LegacyService is a service which does not accept a context when starting.
+type LegacyService interface {
+	Start() error
+	Stop() error
+	Name() string
+}
+
+// legacyService adapts a legacy service to the Service interface.
+type legacyService struct {
+	LegacyService
+}
+
+// Legacy adapts a legacy service to the Service interface. The context is not
+// passed to the legacy service so it cannot abort its startup. A nil service stays nil so
+// that it is rejected before starting.
+func Legacy(service LegacyService) Service {
+	if service == nil {
+		return nil
+	}
+	return &legacyService{LegacyService: service}
+}
+
+// LegacyServices adapts all of the legacy services to the Service interface.
+func LegacyServices(services ...LegacyService) []Service {
+	adapted := make([]Service, len(services))
+	for i, service := range services {
+		adapted[i] = Legacy(service)
+	}
+	return adapted
+}
+
+// Start starts the legacy service.
+func (ls *legacyService) Start(ctx context.Context) error {
+	return ls.LegacyService.Start()
+}
+
+// underlying returns the adapted service, if any, so that the optional
+// interfaces implemented by the legacy services can be detected.
+func underlying(service Service) interface{} {
+	if ls, ok := service.(*legacyService); ok {
+		return ls.LegacyService
+	}
+	return service
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type legacyTestService struct {
+	started bool
+}
+
+func (s *legacyTestService) Start() error {
+	s.started = true
+	return nil
+}
+
+func (s *legacyTestService) Stop() error {
+	return nil
+}
+
+func (s *legacyTestService) Name() string {
+	return "legacy"
+}
+
+func (s *legacyTestService) StartTimeout() time.Duration {
+	return time.Second
+}
+
+func TestLegacyService(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	svc := &legacyTestService{}
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	r.NoError(StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), LegacyServices(svc)))
+	r.True(svc.started)
+}
+
+func TestLegacyServiceOptionalInterfaces(t *testing.T) {
+	r := require.New(t)
+
+	var opts Options
+	r.Equal(time.Second, opts.startTimeout(Legacy(&legacyTestService{})))
+}
+
+func TestNilLegacyServices(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	r.Nil(Legacy(nil))
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), LegacyServices(&legacyTestService{}, nil))
+	r.ErrorIs(err, ErrNilService)
+	r.Contains(err.Error(), "at index 1")
+
+	var nilPtr *legacyTestService
+	err = StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), LegacyServices(nilPtr))
+	r.ErrorIs(err, ErrNilService)
+	r.Contains(err.Error(), "at index 0 (*services.legacyTestService)")
+}
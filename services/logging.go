@@ -0,0 +1,119 @@
+package services
+
+import (
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// Log fields which are added by the context hook.
+const (
+	LogFieldExecID    = "execId"
+	LogFieldRequestID = "requestId"
+	LogFieldService   = "service"
+)
+
+// ContextHook adds the exec ID, the request ID and the service name from the entry context to
+// the log entries.
+// The exec ID of the process main context is used for the entries without a context.
+type ContextHook struct{}
+
+// Levels implements the log.Hook interface.
+func (hook *ContextHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+// Fire implements the log.Hook interface.
+func (hook *ContextHook) Fire(entry *log.Entry) error {
+	ctx := entry.Context
+	if ctx == nil {
+		mainCtx := getProcessMainContext()
+		if mainCtx == nil {
+			return nil
+		}
+		ctx = mainCtx.Context()
+	}
+	if _, ok := entry.Data[LogFieldExecID]; !ok {
+		if execID, ok := ExecIDOk(ctx); ok {
+			entry.Data[LogFieldExecID] = execID
+		}
+	}
+	if _, ok := entry.Data[LogFieldRequestID]; !ok {
+		if requestID, ok := RequestID(ctx); ok {
+			entry.Data[LogFieldRequestID] = requestID
+		}
+	}
+	if _, ok := entry.Data[LogFieldService]; !ok {
+		if name, ok := ServiceName(ctx); ok {
+			entry.Data[LogFieldService] = name
+		}
+	}
+	return nil
+}
+
+// ServiceLevelFormatter drops the entries which are below the level of their service, or below
+// the global level if the service does not have its own level. The logger level must let the
+// entries of the most verbose service through.
+type ServiceLevelFormatter struct {
+	Formatter     log.Formatter
+	Level         log.Level
+	ServiceLevels map[string]log.Level
+}
+
+// Format implements the log.Formatter interface.
+func (f *ServiceLevelFormatter) Format(entry *log.Entry) ([]byte, error) {
+	level := f.Level
+	if name, ok := entry.Data[LogFieldService].(string); ok {
+		if serviceLevel, ok := f.ServiceLevels[name]; ok {
+			level = serviceLevel
+		}
+	}
+	if entry.Level > level {
+		return nil, nil
+	}
+	return f.Formatter.Format(entry)
+}
+
+// SetLogLevels applies the global and the per-service log levels from the config to the
+// standard logger.
+func SetLogLevels(logCfg config.LogConfig) error {
+	return setLogLevels(log.StandardLogger(), logCfg)
+}
+
+func setLogLevels(logger *log.Logger, logCfg config.LogConfig) error {
+	level, err := log.ParseLevel(logCfg.Level)
+	if err != nil {
+		return err
+	}
+	formatter := logger.Formatter
+	if levelFormatter, ok := formatter.(*ServiceLevelFormatter); ok {
+		formatter = levelFormatter.Formatter
+	}
+	if len(logCfg.ServiceLevels) == 0 {
+		logger.SetLevel(level)
+		logger.SetFormatter(formatter)
+		return nil
+	}
+
+	serviceLevels := make(map[string]log.Level)
+	loggerLevel := level
+	for name, rawLevel := range logCfg.ServiceLevels {
+		serviceLevel, err := log.ParseLevel(rawLevel)
+		if err != nil {
+			return fmt.Errorf("invalid level for service '%s': %v", name, err)
+		}
+		serviceLevels[name] = serviceLevel
+		if serviceLevel > loggerLevel {
+			loggerLevel = serviceLevel
+		}
+	}
+	logger.SetLevel(loggerLevel)
+	logger.SetFormatter(&ServiceLevelFormatter{
+		Formatter:     formatter,
+		Level:         level,
+		ServiceLevels: serviceLevels,
+	})
+	return nil
+}
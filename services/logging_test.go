@@ -0,0 +1,122 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+type loggingService struct {
+	logger *logrus.Logger
+}
+
+func (s *loggingService) Start(ctx context.Context) error {
+	s.logger.WithContext(ctx).Info("started")
+	return nil
+}
+
+func (s *loggingService) Stop() error {
+	return nil
+}
+
+func (s *loggingService) Name() string {
+	return "logging"
+}
+
+func TestContextHookAddsFields(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	logger.AddHook(&ContextHook{})
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	execID := ExecID(mainCtx.Context())
+
+	logger.WithContext(mainCtx.Context()).Info("main")
+	entry := hook.LastEntry()
+	r.Equal(execID, entry.Data[LogFieldExecID])
+	r.NotContains(entry.Data, LogFieldRequestID)
+	r.NotContains(entry.Data, LogFieldService)
+
+	requestCtx := WithRequestID(mainCtx.Context())
+	requestID, _ := RequestID(requestCtx)
+	logger.WithContext(requestCtx).Info("request")
+	entry = hook.LastEntry()
+	r.Equal(execID, entry.Data[LogFieldExecID])
+	r.Equal(requestID, entry.Data[LogFieldRequestID])
+
+	time.AfterFunc(time.Millisecond*100, mainCtx.Cancel)
+	r.NoError(StartServices(mainCtx, logrus.NewEntry(logger), []Service{&loggingService{logger: logger}}))
+
+	var serviceEntry *logrus.Entry
+	for _, e := range hook.AllEntries() {
+		if e.Message == "started" {
+			serviceEntry = e
+		}
+	}
+	r.NotNil(serviceEntry)
+	r.Equal(execID, serviceEntry.Data[LogFieldExecID])
+	r.Equal("logging", serviceEntry.Data[LogFieldService])
+}
+
+func TestContextHookUsesProcessMainContext(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	logger.AddHook(&ContextHook{})
+
+	mainCtx := InitMainContext()
+	defer mainCtx.Cancel()
+
+	logger.Info("no context")
+	r.Equal(ExecID(mainCtx.Context()), hook.LastEntry().Data[LogFieldExecID])
+
+	// explicit fields are kept
+	logger.WithField(LogFieldExecID, "custom").Info("custom")
+	r.Equal("custom", hook.LastEntry().Data[LogFieldExecID])
+}
+
+func TestServiceLogLevels(t *testing.T) {
+	r := require.New(t)
+
+	var buf bytes.Buffer
+	logger := logrus.New()
+	logger.SetOutput(&buf)
+	logger.SetFormatter(&logrus.JSONFormatter{})
+	r.NoError(setLogLevels(logger, config.LogConfig{
+		Level:         "info",
+		ServiceLevels: map[string]string{"scanner": "debug", "publisher": "warn"},
+	}))
+	r.Equal(logrus.DebugLevel, logger.GetLevel())
+
+	logger.WithField(LogFieldService, "scanner").Debug("scanner debug")
+	logger.WithField(LogFieldService, "publisher").Info("publisher info")
+	logger.WithField(LogFieldService, "publisher").Warn("publisher warn")
+	logger.WithField(LogFieldService, "json-rpc").Debug("json-rpc debug")
+	logger.WithField(LogFieldService, "json-rpc").Info("json-rpc info")
+	logger.Debug("global debug")
+	logger.Info("global info")
+
+	output := buf.String()
+	for _, msg := range []string{"scanner debug", "publisher warn", "json-rpc info", "global info"} {
+		r.Contains(output, msg)
+	}
+	for _, msg := range []string{"publisher info", "json-rpc debug", "global debug"} {
+		r.NotContains(output, msg)
+	}
+
+	// applying the levels again does not wrap the formatter again
+	r.NoError(setLogLevels(logger, config.LogConfig{Level: "warn"}))
+	r.Equal(logrus.WarnLevel, logger.GetLevel())
+	r.IsType(&logrus.JSONFormatter{}, logger.Formatter)
+
+	r.Error(setLogLevels(logger, config.LogConfig{Level: "info", ServiceLevels: map[string]string{"scanner": "loud"}}))
+}
@@ -0,0 +1,52 @@
+package services
+
+import "time"
+
+// Metric names
+const (
+	MetricServicePhaseDuration = "service.phase.duration"
+	MetricServiceRestart       = "service.restart"
+)
+
+// Metric labels
+const (
+	LabelService = "service"
+	LabelPhase   = "phase"
+)
+
+// MetricsSink receives the service lifecycle metrics.
+type MetricsSink interface {
+	RecordDuration(name string, labels map[string]string, duration time.Duration)
+}
+
+// CounterSink is implemented by the metrics sinks which can also count events.
+type CounterSink interface {
+	IncrementCounter(name string, labels map[string]string)
+}
+
+type noopMetricsSink struct{}
+
+func (noopMetricsSink) RecordDuration(name string, labels map[string]string, duration time.Duration) {
+}
+
+func (opts Options) metrics() MetricsSink {
+	if opts.Metrics != nil {
+		return opts.Metrics
+	}
+	return noopMetricsSink{}
+}
+
+func (opts Options) recordPhaseDuration(service Service, phase string, duration time.Duration) {
+	opts.metrics().RecordDuration(MetricServicePhaseDuration, map[string]string{
+		LabelService: service.Name(),
+		LabelPhase:   phase,
+	}, duration)
+}
+
+func (opts Options) recordRestart(service Service) {
+	if sink, ok := opts.metrics().(CounterSink); ok {
+		sink.IncrementCounter(MetricServiceRestart, map[string]string{
+			LabelService: service.Name(),
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	log "github.com/sirupsen/logrus"
+)
+
+const metricsServerShutdownTimeout = time.Second * 5
+
+// MetricsServer exports the service lifecycle and health metrics for Prometheus at /metrics.
+// It is also a metrics sink which receives the start and stop durations and the restarts.
+type MetricsServer struct {
+	address  string
+	statuses *StatusRegistry
+	server   *http.Server
+
+	phaseDuration *prometheus.GaugeVec
+	restarts      *prometheus.CounterVec
+}
+
+// NewMetricsServer creates a new metrics server which listens on the address. The metrics are
+// registered to the given registry. If it is nil, they are registered to a registry of the server
+// and served together with the default Prometheus registry, so that more than one server can be
+// created in the same process. The statuses of the process main context are used if the status
+// registry is nil.
+func NewMetricsServer(address string, registry *prometheus.Registry, statuses *StatusRegistry) (*MetricsServer, error) {
+	var (
+		registerer prometheus.Registerer = registry
+		gatherer   prometheus.Gatherer   = registry
+	)
+	if registry == nil {
+		serverRegistry := prometheus.NewRegistry()
+		registerer = serverRegistry
+		gatherer = prometheus.Gatherers{serverRegistry, prometheus.DefaultGatherer}
+	}
+
+	metricsServer := &MetricsServer{
+		address:  address,
+		statuses: statuses,
+		phaseDuration: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "forta",
+			Name:      "service_phase_duration_seconds",
+			Help:      "How long the last start or stop of the service took.",
+		}, []string{LabelService, LabelPhase}),
+		restarts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "forta",
+			Name:      "service_restarts_total",
+			Help:      "How many times the service was restarted after failing to start.",
+		}, []string{LabelService}),
+	}
+	for _, collector := range []prometheus.Collector{
+		metricsServer.phaseDuration, metricsServer.restarts, &statusCollector{metricsServer: metricsServer},
+	} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(gatherer, promhttp.HandlerOpts{}))
+	metricsServer.server = &http.Server{Handler: mux}
+	return metricsServer, nil
+}
+
+// Start starts serving.
+func (metricsServer *MetricsServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", metricsServer.address)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := metricsServer.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("metrics server failed")
+		}
+	}()
+	return nil
+}
+
+// Stop shuts the server down.
+func (metricsServer *MetricsServer) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), metricsServerShutdownTimeout)
+	defer cancel()
+	return metricsServer.server.Shutdown(ctx)
+}
+
+// Optional implements the Optional interface so that the node runs without the server
+// if it fails to start.
+func (metricsServer *MetricsServer) Optional() bool {
+	return true
+}
+
+// Name returns the name of the service.
+func (metricsServer *MetricsServer) Name() string {
+	return "metrics-server"
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (metricsServer *MetricsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	metricsServer.server.Handler.ServeHTTP(w, r)
+}
+
+// RecordDuration implements the MetricsSink interface.
+func (metricsServer *MetricsServer) RecordDuration(name string, labels map[string]string, duration time.Duration) {
+	if name == MetricServicePhaseDuration {
+		metricsServer.phaseDuration.With(prometheus.Labels{
+			LabelService: labels[LabelService],
+			LabelPhase:   labels[LabelPhase],
+		}).Set(duration.Seconds())
+	}
+}
+
+// IncrementCounter implements the CounterSink interface.
+func (metricsServer *MetricsServer) IncrementCounter(name string, labels map[string]string) {
+	if name == MetricServiceRestart {
+		metricsServer.restarts.With(prometheus.Labels{LabelService: labels[LabelService]}).Inc()
+	}
+}
+
+func (metricsServer *MetricsServer) getStatuses() []ServiceStatus {
+	if metricsServer.statuses != nil {
+		return metricsServer.statuses.Statuses()
+	}
+	return Statuses()
+}
+
+var (
+	servicesRunningDesc = prometheus.NewDesc(
+		"forta_services_running", "How many services are running.", nil, nil,
+	)
+	serviceUpDesc = prometheus.NewDesc(
+		"forta_service_up", "If the service is running (1) or not (0).", []string{LabelService}, nil,
+	)
+	serviceHealthyDesc = prometheus.NewDesc(
+		"forta_service_healthy", "If the last health check of the service succeeded (1) or not (0).", []string{LabelService}, nil,
+	)
+)
+
+// statusCollector collects the state metrics from the latest service statuses.
+type statusCollector struct {
+	metricsServer *MetricsServer
+}
+
+func (collector *statusCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- servicesRunningDesc
+	ch <- serviceUpDesc
+	ch <- serviceHealthyDesc
+}
+
+func (collector *statusCollector) Collect(ch chan<- prometheus.Metric) {
+	var running int
+	for _, status := range collector.metricsServer.getStatuses() {
+		var up, healthy float64
+		if status.State == StateRunning {
+			running++
+			up = 1
+		}
+		if len(status.HealthError) == 0 {
+			healthy = 1
+		}
+		ch <- prometheus.MustNewConstMetric(serviceUpDesc, prometheus.GaugeValue, up, status.Name)
+		ch <- prometheus.MustNewConstMetric(serviceHealthyDesc, prometheus.GaugeValue, healthy, status.Name)
+	}
+	ch <- prometheus.MustNewConstMetric(servicesRunningDesc, prometheus.GaugeValue, float64(running))
+}
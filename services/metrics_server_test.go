@@ -0,0 +1,111 @@
+package services
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricsServer(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	registry := prometheus.NewRegistry()
+	statuses := NewStatusRegistry()
+	metricsServer, err := NewMetricsServer("", registry, statuses)
+	r.NoError(err)
+
+	flaky := &flakyService{
+		failures: 2,
+		policy:   RestartPolicy{MaxAttempts: 3, BaseInterval: time.Millisecond * 10},
+	}
+	other := &contextRecordingService{name: "other"}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServicesWithOptions(
+			mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{flaky, other},
+			Options{Statuses: statuses, Metrics: metricsServer},
+		)
+	}()
+	r.Eventually(func() bool {
+		status, _ := statuses.Status("other")
+		return status.State == StateRunning
+	}, time.Second, time.Millisecond*10)
+
+	r.Equal(float64(2), testutil.ToFloat64(metricsServer.restarts.WithLabelValues("flaky")))
+	r.Equal(float64(0), testutil.ToFloat64(metricsServer.restarts.WithLabelValues("other")))
+	r.Greater(testutil.ToFloat64(metricsServer.phaseDuration.WithLabelValues("flaky", PhaseStart)), float64(0))
+
+	families, err := registry.Gather()
+	r.NoError(err)
+	values := make(map[string][]float64)
+	for _, family := range families {
+		for _, metric := range family.GetMetric() {
+			values[family.GetName()] = append(values[family.GetName()], metric.GetGauge().GetValue())
+		}
+	}
+	r.Equal([]float64{2}, values["forta_services_running"])
+	r.Equal([]float64{1, 1}, values["forta_service_up"])
+	r.Equal([]float64{1, 1}, values["forta_service_healthy"])
+
+	// the metrics are served over HTTP too
+	server := httptest.NewServer(metricsServer)
+	defer server.Close()
+	resp, err := http.Get(server.URL + "/metrics")
+	r.NoError(err)
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	r.NoError(err)
+	r.Contains(string(b), "forta_services_running 2")
+	r.Contains(string(b), `forta_service_restarts_total{service="flaky"} 2`)
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+
+	families, err = registry.Gather()
+	r.NoError(err)
+	for _, family := range families {
+		if family.GetName() == "forta_services_running" {
+			r.Equal(float64(0), family.GetMetric()[0].GetGauge().GetValue())
+		}
+	}
+}
+
+func TestMetricsServerDuplicateRegistry(t *testing.T) {
+	r := require.New(t)
+
+	registry := prometheus.NewRegistry()
+	_, err := NewMetricsServer("", registry, nil)
+	r.NoError(err)
+	_, err = NewMetricsServer("", registry, nil)
+	r.Error(err)
+}
+
+func TestMetricsServerDefaultRegistry(t *testing.T) {
+	r := require.New(t)
+
+	// each server has its own registry so that it can be created again, e.g. by a restart
+	_, err := NewMetricsServer("", nil, nil)
+	r.NoError(err)
+	metricsServer, err := NewMetricsServer("", nil, NewStatusRegistry())
+	r.NoError(err)
+
+	rec := httptest.NewRecorder()
+	metricsServer.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	r.Equal(http.StatusOK, rec.Code)
+	body, err := ioutil.ReadAll(rec.Body)
+	r.NoError(err)
+	r.Contains(string(body), "forta_services_running")
+	// the default registry is served too
+	r.Contains(string(body), "go_goroutines")
+}
@@ -0,0 +1,48 @@
+package services
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+type metric struct {
+	name   string
+	labels map[string]string
+}
+
+type fakeMetricsSink struct {
+	metrics []metric
+	mu      sync.Mutex
+}
+
+func (sink *fakeMetricsSink) RecordDuration(name string, labels map[string]string, duration time.Duration) {
+	sink.mu.Lock()
+	defer sink.mu.Unlock()
+	sink.metrics = append(sink.metrics, metric{name: name, labels: labels})
+}
+
+func TestServicePhaseMetrics(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var stopped []string
+	sink := &fakeMetricsSink{}
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&orderedService{name: "json-rpc", stopped: &stopped},
+		&orderedService{name: "scanner", stopped: &stopped},
+	}, Options{Metrics: sink}))
+
+	r.Equal([]metric{
+		{name: MetricServicePhaseDuration, labels: map[string]string{LabelService: "json-rpc", LabelPhase: PhaseStart}},
+		{name: MetricServicePhaseDuration, labels: map[string]string{LabelService: "scanner", LabelPhase: PhaseStart}},
+		{name: MetricServicePhaseDuration, labels: map[string]string{LabelService: "scanner", LabelPhase: PhaseStop}},
+		{name: MetricServicePhaseDuration, labels: map[string]string{LabelService: "json-rpc", LabelPhase: PhaseStop}},
+	}, sink.metrics)
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"context"
+	"time"
+)
+
+// PanicReport describes a panic which was recovered from a service.
+type PanicReport struct {
+	Service string
+	ExecID  string
+	Value   interface{}
+	Stack   []byte
+	Time    time.Time
+}
+
+// PanicSink receives the reports of the recovered panics, e.g. for sending them to an error
+// tracker. It is called synchronously from the recovering goroutine and it should not block.
+type PanicSink interface {
+	ReportPanic(report PanicReport)
+}
+
+type noopPanicSink struct{}
+
+func (noopPanicSink) ReportPanic(report PanicReport) {
+}
+
+func (opts Options) panics() PanicSink {
+	if opts.Panics != nil {
+		return opts.Panics
+	}
+	return noopPanicSink{}
+}
+
+// reportPanic reports the recovered panic of the service with the exec ID from the context.
+func (opts Options) reportPanic(ctx context.Context, name string, panicErr *PanicError) {
+	execID, _ := ExecIDOk(ctx)
+	opts.panics().ReportPanic(PanicReport{
+		Service: name,
+		ExecID:  execID,
+		Value:   panicErr.Value,
+		Stack:   panicErr.Stack,
+		Time:    opts.clock().Now(),
+	})
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+const pprofServerShutdownTimeout = time.Second * 5
+
+// PprofServer serves the pprof debug endpoints at /debug/pprof/.
+type PprofServer struct {
+	address string
+	server  *http.Server
+}
+
+// NewPprofServer creates a new pprof server which listens on the address.
+func NewPprofServer(address string) *PprofServer {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return &PprofServer{address: address, server: &http.Server{Handler: mux}}
+}
+
+// PprofServerFromConfig creates a pprof server only if it is enabled in the config.
+func PprofServerFromConfig(cfg config.Config) (*PprofServer, bool) {
+	pprofCfg := cfg.Services.PprofServer
+	if !pprofCfg.Enable {
+		return nil, false
+	}
+	return NewPprofServer(pprofCfg.Address), true
+}
+
+// Start starts serving.
+func (pprofServer *PprofServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", pprofServer.address)
+	if err != nil {
+		return err
+	}
+	log.WithField("address", listener.Addr().String()).Warn("pprof server is enabled")
+	go func() {
+		if err := pprofServer.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("pprof server failed")
+		}
+	}()
+	return nil
+}
+
+// Stop shuts the server down.
+func (pprofServer *PprofServer) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), pprofServerShutdownTimeout)
+	defer cancel()
+	return pprofServer.server.Shutdown(ctx)
+}
+
+// Optional implements the Optional interface so that the node runs without the server
+// if it fails to start.
+func (pprofServer *PprofServer) Optional() bool {
+	return true
+}
+
+// Name returns the name of the service.
+func (pprofServer *PprofServer) Name() string {
+	return "pprof-server"
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (pprofServer *PprofServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	pprofServer.server.Handler.ServeHTTP(w, r)
+}
@@ -0,0 +1,53 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+func freeTestAddress(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+func TestPprofServerEnabled(t *testing.T) {
+	r := require.New(t)
+
+	var cfg config.Config
+	cfg.Services.PprofServer = config.PprofServerConfig{Enable: true, Address: freeTestAddress(t)}
+	pprofServer, ok := PprofServerFromConfig(cfg)
+	r.True(ok)
+	r.NoError(pprofServer.Start(context.Background()))
+
+	for _, path := range []string{"/debug/pprof/", "/debug/pprof/goroutine", "/debug/pprof/cmdline"} {
+		resp, err := http.Get(fmt.Sprintf("http://%s%s", cfg.Services.PprofServer.Address, path))
+		r.NoError(err)
+		resp.Body.Close()
+		r.Equal(http.StatusOK, resp.StatusCode, path)
+	}
+
+	r.NoError(pprofServer.Stop())
+	_, err := http.Get(fmt.Sprintf("http://%s/debug/pprof/", cfg.Services.PprofServer.Address))
+	r.Error(err)
+}
+
+func TestPprofServerDisabledByDefault(t *testing.T) {
+	r := require.New(t)
+
+	var cfg config.Config
+	r.NoError(config.ApplyDefaults(&cfg))
+	r.False(cfg.Services.PprofServer.Enable)
+
+	pprofServer, ok := PprofServerFromConfig(cfg)
+	r.False(ok)
+	r.Nil(pprofServer)
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const probeServerShutdownTimeout = time.Second * 5
+
+// ProbeServer serves the liveness and the readiness of the services over HTTP:
+//
+//   - /healthz responds 200 while the process is alive and 503 if any service stays
+//     unhealthy for longer than the health grace period.
+//   - /readyz responds 200 only after all of the services are running.
+//   - /status always responds 200 with the statuses, including the service details.
+type ProbeServer struct {
+	address  string
+	statuses *StatusRegistry
+	server   *http.Server
+}
+
+// NewProbeServer creates a new probe server which listens on the address. The statuses
+// of the process main context are used if the registry is nil.
+func NewProbeServer(address string, statuses *StatusRegistry) *ProbeServer {
+	probeServer := &ProbeServer{address: address, statuses: statuses}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", probeServer.handleHealthz)
+	mux.HandleFunc("/readyz", probeServer.handleReadyz)
+	mux.HandleFunc("/status", probeServer.handleStatus)
+	probeServer.server = &http.Server{Handler: mux}
+	return probeServer
+}
+
+// Start starts serving.
+func (probeServer *ProbeServer) Start(ctx context.Context) error {
+	listener, err := net.Listen("tcp", probeServer.address)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := probeServer.server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			log.WithError(err).Error("probe server failed")
+		}
+	}()
+	return nil
+}
+
+// Stop shuts the server down.
+func (probeServer *ProbeServer) Stop() error {
+	ctx, cancel := context.WithTimeout(context.Background(), probeServerShutdownTimeout)
+	defer cancel()
+	return probeServer.server.Shutdown(ctx)
+}
+
+// Name returns the name of the service.
+func (probeServer *ProbeServer) Name() string {
+	return "probe-server"
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (probeServer *ProbeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	probeServer.server.Handler.ServeHTTP(w, r)
+}
+
+func (probeServer *ProbeServer) getStatuses() []ServiceStatus {
+	if probeServer.statuses != nil {
+		return probeServer.statuses.Statuses()
+	}
+	return Statuses()
+}
+
+func (probeServer *ProbeServer) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	statuses := probeServer.getStatuses()
+	healthy := true
+	for _, status := range statuses {
+		if status.Unhealthy {
+			healthy = false
+		}
+	}
+	writeProbeResponse(w, healthy, statuses)
+}
+
+func (probeServer *ProbeServer) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	statuses := probeServer.getStatuses()
+	ready := len(statuses) > 0
+	for _, status := range statuses {
+		if status.State != StateRunning && !status.skipsReadiness() {
+			ready = false
+		}
+	}
+	writeProbeResponse(w, ready, statuses)
+}
+
+func (probeServer *ProbeServer) handleStatus(w http.ResponseWriter, r *http.Request) {
+	statuses := probeServer.statuses
+	if statuses == nil {
+		mainCtx := getProcessMainContext()
+		if mainCtx == nil {
+			writeProbeResponse(w, true, nil)
+			return
+		}
+		statuses = mainCtx.Statuses()
+	}
+	writeProbeResponse(w, true, statuses.StatusesWithDetails())
+}
+
+func writeProbeResponse(w http.ResponseWriter, ok bool, statuses []ServiceStatus) {
+	w.Header().Set("Content-Type", "application/json")
+	if ok {
+		w.WriteHeader(http.StatusOK)
+	} else {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(statuses)
+}
@@ -0,0 +1,154 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func probeStatusCode(t *testing.T, server *httptest.Server, path string) int {
+	resp, err := http.Get(server.URL + path)
+	require.NoError(t, err)
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+func TestProbeServer(t *testing.T) {
+	r := require.New(t)
+
+	statuses := NewStatusRegistry()
+	server := httptest.NewServer(NewProbeServer("", statuses))
+	defer server.Close()
+
+	// nothing started yet
+	r.Equal(http.StatusServiceUnavailable, probeStatusCode(t, server, "/readyz"))
+	r.Equal(http.StatusOK, probeStatusCode(t, server, "/healthz"))
+
+	// not ready yet
+	statuses.reset([]Service{&TestService{}, &blockingService{}})
+	statuses.set("test", StateRunning, nil)
+	statuses.set("blocking", StateStarting, nil)
+	r.Equal(http.StatusServiceUnavailable, probeStatusCode(t, server, "/readyz"))
+	r.Equal(http.StatusOK, probeStatusCode(t, server, "/healthz"))
+
+	// ready
+	statuses.set("blocking", StateRunning, nil)
+	r.Equal(http.StatusOK, probeStatusCode(t, server, "/readyz"))
+	r.Equal(http.StatusOK, probeStatusCode(t, server, "/healthz"))
+
+	// unhealthy within the grace period
+	statuses.setHealth("blocking", errors.New("stuck"), false)
+	r.Equal(http.StatusOK, probeStatusCode(t, server, "/healthz"))
+
+	// degraded
+	statuses.setHealth("blocking", errors.New("stuck"), true)
+	r.Equal(http.StatusOK, probeStatusCode(t, server, "/readyz"))
+	r.Equal(http.StatusServiceUnavailable, probeStatusCode(t, server, "/healthz"))
+
+	// healthy again
+	statuses.setHealth("blocking", nil, false)
+	r.Equal(http.StatusOK, probeStatusCode(t, server, "/healthz"))
+}
+
+func TestProbeServerHealthGracePeriod(t *testing.T) {
+	for _, testCase := range []struct {
+		name        string
+		gracePeriod time.Duration
+		statusCode  int
+	}{
+		{name: "blip", gracePeriod: time.Hour, statusCode: http.StatusOK},
+		{name: "sustained", gracePeriod: time.Millisecond * 30, statusCode: http.StatusServiceUnavailable},
+	} {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := require.New(t)
+
+			mainCtx := NewMainContext()
+			defer mainCtx.Cancel()
+
+			server := httptest.NewServer(NewProbeServer("", mainCtx.Statuses()))
+			defer server.Close()
+
+			svc := &flippingService{}
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc}, Options{
+					HealthCheckInterval: time.Millisecond * 10,
+					HealthGracePeriod:   testCase.gracePeriod,
+				})
+			}()
+			defer func() {
+				mainCtx.Cancel()
+				<-done
+			}()
+
+			r.Eventually(func() bool {
+				status, _ := mainCtx.Statuses().Status("flipping")
+				return status.HealthError != "" && atomic.LoadInt64(&svc.polls) > 5
+			}, time.Second, time.Millisecond*10)
+			r.Equal(testCase.statusCode, probeStatusCode(t, server, "/healthz"))
+		})
+	}
+}
+
+func TestProbeServerLifecycle(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	probeServer := NewProbeServer("127.0.0.1:0", nil)
+	r.NoError(probeServer.Start(mainCtx.Context()))
+	r.NoError(probeServer.Stop())
+
+	// the address is validated when starting
+	r.Error(NewProbeServer("127.0.0.1:-1", nil).Start(mainCtx.Context()))
+}
+
+type detailedService struct {
+	TestService
+	details map[string]string
+}
+
+func (s *detailedService) Name() string {
+	return "scanner"
+}
+
+func (s *detailedService) StatusDetails() map[string]string {
+	return s.details
+}
+
+func TestStatusDetails(t *testing.T) {
+	r := require.New(t)
+
+	statuses := NewStatusRegistry()
+	server := httptest.NewServer(NewProbeServer("", statuses))
+	defer server.Close()
+
+	scanner := &detailedService{details: map[string]string{"block": "12345", "lag": "2"}}
+	statuses.reset([]Service{scanner, &blockingService{}})
+	statuses.set("scanner", StateRunning, nil)
+
+	withDetails := statuses.StatusesWithDetails()
+	r.Len(withDetails, 2)
+	r.Equal(scanner.details, withDetails[0].Details)
+	r.Nil(withDetails[1].Details)
+	// the plain statuses do not call the services
+	r.Nil(statuses.Statuses()[0].Details)
+
+	resp, err := http.Get(server.URL + "/status")
+	r.NoError(err)
+	defer resp.Body.Close()
+	r.Equal(http.StatusOK, resp.StatusCode)
+	var rendered []ServiceStatus
+	r.NoError(json.NewDecoder(resp.Body).Decode(&rendered))
+	r.Equal("scanner", rendered[0].Name)
+	r.Equal(scanner.details, rendered[0].Details)
+}
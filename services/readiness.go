@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// DefaultReadinessProbeTimeout is the timeout of each HTTP readiness check.
+const DefaultReadinessProbeTimeout = time.Second * 5
+
+// ReadinessProbe checks if a service is ready. It returns an error until it is.
+type ReadinessProbe func(ctx context.Context) error
+
+// ReadinessProber is implemented by services which are ready some time after Start returns,
+// like the wrappers of the third-party services. Starting the service is completed after
+// the probe passes, so the dependents wait for it and the start timeout includes it.
+type ReadinessProber interface {
+	ReadinessProbe(ctx context.Context) error
+}
+
+// HTTPReadinessProbe makes a probe which passes when a GET request to the URL responds
+// with a status lower than 400.
+func HTTPReadinessProbe(url string, timeout time.Duration) ReadinessProbe {
+	if timeout <= 0 {
+		timeout = DefaultReadinessProbeTimeout
+	}
+	client := &http.Client{Timeout: timeout}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode >= http.StatusBadRequest {
+			return fmt.Errorf("readiness probe responded with status %d", resp.StatusCode)
+		}
+		return nil
+	}
+}
+
+func readinessProbesFromConfig(probesCfg map[string]config.ReadinessProbeConfig) map[string]ReadinessProbe {
+	if len(probesCfg) == 0 {
+		return nil
+	}
+	probes := make(map[string]ReadinessProbe)
+	for name, probeCfg := range probesCfg {
+		probes[name] = HTTPReadinessProbe(probeCfg.URL, time.Duration(probeCfg.TimeoutSeconds)*time.Second)
+	}
+	return probes
+}
+
+// readinessProbe returns the probe of the service. The probes in the options take
+// precedence over the probe of the service.
+func (opts Options) readinessProbe(service Service) (ReadinessProbe, bool) {
+	if probe, ok := opts.ReadinessProbes[service.Name()]; ok && probe != nil {
+		return probe, true
+	}
+	if prober, ok := underlying(service).(ReadinessProber); ok {
+		return prober.ReadinessProbe, true
+	}
+	return nil, false
+}
+
+// awaitReadiness polls the readiness probe of the started service until it passes.
+func (opts Options) awaitReadiness(ctx context.Context, logger *log.Entry, service Service) error {
+	probe, ok := opts.readinessProbe(service)
+	if !ok {
+		return nil
+	}
+	logger.Info("waiting for the readiness probe")
+	policy := opts.ReadinessPolicy
+	if policy.Clock == nil {
+		policy.Clock = opts.clock()
+	}
+	return WaitForReady(ctx, func() error {
+		return probe(ctx)
+	}, policy)
+}
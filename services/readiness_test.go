@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+// probedService becomes ready after its probe is called for the given number of times.
+type probedService struct {
+	dependentService
+	readyAfter int
+	probes     int
+	mu         sync.Mutex
+}
+
+func (s *probedService) ReadinessProbe(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.probes++
+	if s.probes < s.readyAfter {
+		return errors.New("not accepting connections")
+	}
+	return nil
+}
+
+func (s *probedService) getProbes() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.probes
+}
+
+func TestReadinessProbe(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	proxy := &probedService{dependentService: dependentService{name: "json-rpc-proxy"}, readyAfter: 3}
+	scanner := &dependentService{name: "scanner", deps: []string{"json-rpc-proxy"}}
+	probesOnStart := make(map[string]int)
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		proxy, scanner,
+	}, Options{
+		StartParallelism: 2,
+		ReadinessPolicy:  ReadyPolicy{BaseInterval: time.Millisecond * 5, MaxInterval: time.Millisecond * 10},
+		OnStart: func(name string) {
+			probesOnStart[name] = proxy.getProbes()
+			if name == "scanner" {
+				mainCtx.Cancel()
+			}
+		},
+	}))
+
+	// the proxy was started only after the probe passed and the scanner waited for it
+	r.Equal(3, probesOnStart["json-rpc-proxy"])
+	r.Equal(3, probesOnStart["scanner"])
+	r.True(scanner.started)
+}
+
+func TestReadinessProbeTimeout(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	err := StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&dependentService{name: "json-rpc-proxy"},
+	}, Options{
+		StartTimeout:    time.Millisecond * 50,
+		ReadinessPolicy: ReadyPolicy{BaseInterval: time.Millisecond * 5, MaxInterval: time.Millisecond * 10},
+		ReadinessProbes: map[string]ReadinessProbe{
+			"json-rpc-proxy": func(ctx context.Context) error {
+				return errors.New("not accepting connections")
+			},
+		},
+	})
+	r.ErrorIs(err, ErrStartTimeout)
+	status, _ := mainCtx.Statuses().Status("json-rpc-proxy")
+	r.Equal(StateFailed, status.State)
+}
+
+func TestHTTPReadinessProbe(t *testing.T) {
+	r := require.New(t)
+
+	var requests int
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests < 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	probe := HTTPReadinessProbe(server.URL, time.Second)
+	r.EqualError(probe(context.Background()), "readiness probe responded with status 503")
+	r.NoError(probe(context.Background()))
+
+	server.Close()
+	r.Error(probe(context.Background()))
+}
@@ -0,0 +1,79 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Default readiness wait values
+const (
+	DefaultReadyBaseInterval = time.Millisecond * 100
+	DefaultReadyMaxInterval  = time.Second * 5
+	DefaultReadyJitter       = 0.5
+)
+
+// jitterRand is seeded so that the processes do not retry in sync.
+var (
+	jitterRand   = rand.New(rand.NewSource(time.Now().UnixNano()))
+	jitterRandMu sync.Mutex
+)
+
+func randomDuration(max time.Duration) time.Duration {
+	jitterRandMu.Lock()
+	defer jitterRandMu.Unlock()
+	return time.Duration(jitterRand.Int63n(int64(max) + 1))
+}
+
+// ReadyPolicy describes how often the readiness is checked while waiting.
+type ReadyPolicy struct {
+	BaseInterval time.Duration
+	MaxInterval  time.Duration
+	// Jitter is the fraction of each interval which is randomized, between 0 and 1.
+	Jitter float64
+	// Clock times the intervals. The real clock is used if not set.
+	Clock Clock
+}
+
+// withDefaults fills in the zero values from the package defaults.
+func (policy ReadyPolicy) withDefaults() ReadyPolicy {
+	if policy.BaseInterval <= 0 {
+		policy.BaseInterval = DefaultReadyBaseInterval
+	}
+	if policy.MaxInterval <= 0 {
+		policy.MaxInterval = DefaultReadyMaxInterval
+	}
+	if policy.Jitter <= 0 || policy.Jitter > 1 {
+		policy.Jitter = DefaultReadyJitter
+	}
+	if policy.Clock == nil {
+		policy.Clock = RealClock
+	}
+	return policy
+}
+
+// delay calculates the jittered delay after the given check attempt which starts from 1.
+func (policy ReadyPolicy) delay(attempt int) time.Duration {
+	delay := RestartPolicy{BaseInterval: policy.BaseInterval, MaxInterval: policy.MaxInterval}.backoff(attempt)
+	jitter := time.Duration(float64(delay) * policy.Jitter)
+	return delay - jitter + randomDuration(jitter)
+}
+
+// WaitForReady calls the check with exponential backoff and jitter until it succeeds or
+// the context is done. Use it for waiting for a dependency which has to be polled.
+func WaitForReady(ctx context.Context, check func() error, policy ReadyPolicy) error {
+	policy = policy.withDefaults()
+	for attempt := 1; ; attempt++ {
+		err := check()
+		if err == nil {
+			return nil
+		}
+		select {
+		case <-policy.Clock.After(policy.delay(attempt)):
+		case <-ctx.Done():
+			return fmt.Errorf("%w while waiting for readiness after %d checks: %v", ctx.Err(), attempt, err)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitForReadyImmediately(t *testing.T) {
+	r := require.New(t)
+
+	var checks int
+	r.NoError(WaitForReady(context.Background(), func() error {
+		checks++
+		return nil
+	}, ReadyPolicy{BaseInterval: time.Hour}))
+	r.Equal(1, checks)
+}
+
+func TestWaitForReadyAfterRetries(t *testing.T) {
+	r := require.New(t)
+
+	var checks int
+	r.NoError(WaitForReady(context.Background(), func() error {
+		checks++
+		if checks < 4 {
+			return errors.New("not listening")
+		}
+		return nil
+	}, ReadyPolicy{BaseInterval: time.Millisecond, MaxInterval: time.Millisecond * 5}))
+	r.Equal(4, checks)
+}
+
+func TestWaitForReadyContextDone(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+	err := WaitForReady(ctx, func() error {
+		return errors.New("not listening")
+	}, ReadyPolicy{BaseInterval: time.Millisecond * 10})
+	r.ErrorIs(err, context.DeadlineExceeded)
+	r.Contains(err.Error(), "not listening")
+}
+
+func TestReadyPolicyDelay(t *testing.T) {
+	r := require.New(t)
+
+	policy := ReadyPolicy{BaseInterval: time.Second, MaxInterval: time.Second * 4, Jitter: 0.5}.withDefaults()
+	for i := 0; i < 100; i++ {
+		for attempt, max := range map[int]time.Duration{1: time.Second, 2: time.Second * 2, 3: time.Second * 4, 10: time.Second * 4} {
+			delay := policy.delay(attempt)
+			r.GreaterOrEqual(delay, max/2)
+			r.LessOrEqual(delay, max)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/clients/health"
+	registrydomain "github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+	registryclient "github.com/forta-network/forta-core-go/registry"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
+)
+
+// maxRefreshBackoffFactor limits how much longer than the interval the refresher waits after errors.
+const maxRefreshBackoffFactor = 8
+
+// ContractsChangeHandler receives the previous and the new contracts after a change.
+type ContractsChangeHandler func(oldContracts, newContracts registrydomain.RegistryContracts)
+
+// ResolvedContracts are the addresses which the node uses after resolving the registry
+// contracts and the ENS endpoint which they were resolved from.
+type ResolvedContracts struct {
+	Dispatch       common.Address `json:"dispatch"`
+	ScannerVersion common.Address `json:"scannerVersion"`
+	Agent          common.Address `json:"agent"`
+	Endpoint       string         `json:"endpoint"`
+}
+
+// Redacted returns a copy which is safe to log.
+func (resolved ResolvedContracts) Redacted() ResolvedContracts {
+	resolved.Endpoint = config.RedactURL(resolved.Endpoint)
+	return resolved
+}
+
+// ContractResolver resolves the registry contracts by using the ENS contract at the address
+// through the endpoint.
+type ContractResolver interface {
+	Resolve(ctx context.Context, endpoint, ensAddress string) (registrydomain.RegistryContracts, error)
+}
+
+// ensStoreResolver is the default contract resolver. The ENS store is already dialed with
+// the endpoints so the endpoint and the address are only used for logging.
+type ensStoreResolver struct {
+	ensStore ens.ENS
+}
+
+// NewENSStoreResolver creates a contract resolver which uses the ENS store.
+func NewENSStoreResolver(ensStore ens.ENS) ContractResolver {
+	return &ensStoreResolver{ensStore: ensStore}
+}
+
+// Resolve returns as soon as the context is done, e.g. after a shutdown signal, and leaves
+// the ENS store call behind.
+func (resolver *ensStoreResolver) Resolve(ctx context.Context, endpoint, ensAddress string) (registrydomain.RegistryContracts, error) {
+	type result struct {
+		contracts *registrydomain.RegistryContracts
+		err       error
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		contracts, err := resolver.ensStore.ResolveRegistryContracts()
+		resultCh <- result{contracts: contracts, err: err}
+	}()
+	select {
+	case res := <-resultCh:
+		if res.err != nil {
+			return registrydomain.RegistryContracts{}, res.err
+		}
+		return *res.contracts, nil
+	case <-ctx.Done():
+		log.WithField("endpoint", config.RedactURL(endpoint)).Warn("aborted resolving the registry contracts")
+		return registrydomain.RegistryContracts{}, fmt.Errorf("aborted resolving the registry contracts: %w", ctx.Err())
+	}
+}
+
+// ContractRefresher re-resolves the registry contracts periodically to pick up the migrations.
+type ContractRefresher struct {
+	resolver   ContractResolver
+	interval   time.Duration
+	onChange   ContractsChangeHandler
+	endpoint   string
+	ensAddress string
+	// jitter is the fraction which the refresh delays vary by and random is in [0, 1)
+	jitter float64
+	random func() float64
+
+	contracts   registrydomain.RegistryContracts
+	contractsMu sync.RWMutex
+	stop        chan struct{}
+	stopOnce    sync.Once
+	done        chan struct{}
+	started     bool
+	startedMu   sync.Mutex
+
+	lastChecked        health.TimeTracker
+	lastChangeDetected health.TimeTracker
+	lastErr            health.ErrorTracker
+}
+
+// NewContractRefresher creates a new contract refresher.
+func NewContractRefresher(ensStore ens.ENS, interval time.Duration, onChange ContractsChangeHandler) *ContractRefresher {
+	return NewContractRefresherWithResolver(NewENSStoreResolver(ensStore), interval, onChange)
+}
+
+// NewContractRefresherWithResolver creates a new contract refresher which uses the resolver.
+func NewContractRefresherWithResolver(
+	resolver ContractResolver, interval time.Duration, onChange ContractsChangeHandler,
+) *ContractRefresher {
+	return &ContractRefresher{
+		resolver: resolver,
+		interval: interval,
+		onChange: onChange,
+		random:   rand.Float64,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// NewContractRefresherFromConfig creates a contract refresher which uses the ENS store from the config.
+func NewContractRefresherFromConfig(
+	ctx context.Context, cfg config.Config, onChange ContractsChangeHandler,
+) (*ContractRefresher, error) {
+	ensStore, err := ensStoreFromConfig(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	interval := time.Duration(cfg.ENSConfig.RefreshIntervalSeconds) * time.Second
+	refresher := NewContractRefresher(ensStore, interval, onChange)
+	refresher.SetEndpoint(ensEndpoint(cfg), cfg.ENSConfig.ContractAddress)
+	refresher.SetJitter(cfg.ENSConfig.RefreshJitterPercent, nil)
+	return refresher, nil
+}
+
+func ensStoreFromConfig(ctx context.Context, cfg config.Config) (ens.ENS, error) {
+	return store.GetENSStore(ctx, cfg, registryclient.ClientConfig{
+		JsonRpcUrl: cfg.Registry.JsonRpc.Url,
+		ENSAddress: cfg.ENSConfig.ContractAddress,
+		Name:       "contract-refresher",
+	})
+}
+
+// SetEndpoint sets the endpoint and the ENS contract address which the contracts are resolved with.
+func (cr *ContractRefresher) SetEndpoint(endpoint, ensAddress string) {
+	cr.endpoint = endpoint
+	cr.ensAddress = ensAddress
+}
+
+// SetJitter makes the refresh delays vary randomly by up to the percentage so that the nodes do
+// not refresh in lockstep. The random source returns values in [0, 1) and it is the default
+// source if nil.
+func (cr *ContractRefresher) SetJitter(percent int, random func() float64) {
+	cr.jitter = float64(percent) / 100
+	if random != nil {
+		cr.random = random
+	}
+}
+
+// jittered returns the delay varied by the jitter.
+func (cr *ContractRefresher) jittered(delay time.Duration) time.Duration {
+	if cr.jitter <= 0 {
+		return delay
+	}
+	offset := (cr.random()*2 - 1) * cr.jitter * float64(delay)
+	return delay + time.Duration(offset)
+}
+
+// ensEndpoint returns the first endpoint which the ENS store is dialed with.
+func ensEndpoint(cfg config.Config) string {
+	if cfg.ENSConfig.Override {
+		return ""
+	}
+	if len(cfg.ENSConfig.JsonRpcUrls) > 0 {
+		return cfg.ENSConfig.JsonRpcUrls[0]
+	}
+	return cfg.Registry.JsonRpc.Url
+}
+
+// ResolvedContracts returns the latest resolved contracts.
+func (cr *ContractRefresher) ResolvedContracts() ResolvedContracts {
+	cr.contractsMu.RLock()
+	defer cr.contractsMu.RUnlock()
+	return newResolvedContracts(cr.contracts, cr.endpoint)
+}
+
+func newResolvedContracts(contracts registrydomain.RegistryContracts, endpoint string) ResolvedContracts {
+	return ResolvedContracts{
+		Dispatch:       contracts.Dispatch,
+		ScannerVersion: contracts.ScannerNodeVersion,
+		Agent:          contracts.AgentRegistry,
+		Endpoint:       endpoint,
+	}
+}
+
+func (cr *ContractRefresher) setContracts(contracts registrydomain.RegistryContracts) {
+	cr.contractsMu.Lock()
+	defer cr.contractsMu.Unlock()
+	cr.contracts = contracts
+}
+
+// Start resolves the initial contracts and starts refreshing them.
+func (cr *ContractRefresher) Start(ctx context.Context) error {
+	contracts, err := cr.resolver.Resolve(ctx, cr.endpoint, cr.ensAddress)
+	if err != nil {
+		return err
+	}
+	cr.setContracts(contracts)
+	cr.lastChecked.Set()
+	cr.startedMu.Lock()
+	cr.started = true
+	cr.startedMu.Unlock()
+	go cr.refreshLoop(ctx)
+	return nil
+}
+
+func (cr *ContractRefresher) refreshLoop(ctx context.Context) {
+	defer close(cr.done)
+	delay := cr.interval
+	for {
+		select {
+		case <-time.After(cr.jittered(delay)):
+		case <-cr.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		err := cr.refresh(ctx)
+		cr.lastErr.Set(err)
+		if err == nil {
+			delay = cr.interval
+			continue
+		}
+		// back off so that a failing endpoint is not called too often
+		delay *= 2
+		if maxDelay := cr.interval * maxRefreshBackoffFactor; delay > maxDelay {
+			delay = maxDelay
+		}
+		log.WithError(err).WithField("delay", delay.String()).Warn("failed to refresh the contracts")
+	}
+}
+
+func (cr *ContractRefresher) refresh(ctx context.Context) error {
+	cr.lastChecked.Set()
+	contracts, err := cr.resolver.Resolve(ctx, cr.endpoint, cr.ensAddress)
+	if err != nil {
+		return err
+	}
+	if contracts == cr.contracts {
+		return nil
+	}
+	oldContracts := cr.contracts
+	cr.setContracts(contracts)
+	cr.lastChangeDetected.Set()
+	log.WithFields(log.Fields{
+		"old": oldContracts,
+		"new": contracts,
+	}).Warn("registry contracts changed")
+	if cr.onChange != nil {
+		cr.onChange(oldContracts, contracts)
+	}
+	return nil
+}
+
+// Stop stops refreshing. It can be called after a failed start and more than once.
+func (cr *ContractRefresher) Stop() error {
+	cr.stopOnce.Do(func() {
+		close(cr.stop)
+	})
+	cr.startedMu.Lock()
+	started := cr.started
+	cr.startedMu.Unlock()
+	if started {
+		<-cr.done
+	}
+	return nil
+}
+
+// Name returns the name of the service.
+func (cr *ContractRefresher) Name() string {
+	return "contract-refresher"
+}
+
+// Health implements the health.Reporter interface.
+func (cr *ContractRefresher) Health() health.Reports {
+	return health.Reports{
+		cr.lastErr.GetReport("contracts.refresh.error"),
+		&health.Report{
+			Name:    "contracts.refresh.time",
+			Status:  health.StatusInfo,
+			Details: cr.lastChecked.String(),
+		},
+		&health.Report{
+			Name:    "contracts.change-detected.time",
+			Status:  health.StatusInfo,
+			Details: cr.lastChangeDetected.String(),
+		},
+	}
+}
@@ -0,0 +1,77 @@
+// Package registrytest has helpers for testing the code which resolves the registry contracts.
+package registrytest
+
+import (
+	"context"
+	"sync"
+
+	registrydomain "github.com/forta-network/forta-core-go/domain/registry"
+
+	"github.com/forta-network/forta-node/services/registry"
+)
+
+// ResolveCall is the input of a Resolve call.
+type ResolveCall struct {
+	Endpoint   string
+	ENSAddress string
+}
+
+// ResolveResult is returned from a Resolve call.
+type ResolveResult struct {
+	Contracts registrydomain.RegistryContracts
+	Err       error
+}
+
+// FakeResolver is a configurable contract resolver which records the calls. It is safe for
+// concurrent use.
+type FakeResolver struct {
+	results []ResolveResult
+	last    ResolveResult
+	calls   []ResolveCall
+	mu      sync.Mutex
+}
+
+var _ registry.ContractResolver = &FakeResolver{}
+
+// NewFakeResolver creates a new fake resolver which returns the contracts.
+func NewFakeResolver(contracts registrydomain.RegistryContracts) *FakeResolver {
+	return &FakeResolver{last: ResolveResult{Contracts: contracts}}
+}
+
+// Set sets what all of the next calls return.
+func (resolver *FakeResolver) Set(contracts registrydomain.RegistryContracts, err error) {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	resolver.results = nil
+	resolver.last = ResolveResult{Contracts: contracts, Err: err}
+}
+
+// Enqueue queues results which the next calls return in order. The last result keeps
+// being returned after the queue is drained.
+func (resolver *FakeResolver) Enqueue(results ...ResolveResult) {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	resolver.results = append(resolver.results, results...)
+}
+
+// Resolve implements the registry.ContractResolver interface.
+func (resolver *FakeResolver) Resolve(ctx context.Context, endpoint, ensAddress string) (registrydomain.RegistryContracts, error) {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	resolver.calls = append(resolver.calls, ResolveCall{Endpoint: endpoint, ENSAddress: ensAddress})
+	if err := ctx.Err(); err != nil {
+		return registrydomain.RegistryContracts{}, err
+	}
+	if len(resolver.results) > 0 {
+		resolver.last = resolver.results[0]
+		resolver.results = resolver.results[1:]
+	}
+	return resolver.last.Contracts, resolver.last.Err
+}
+
+// Calls returns the calls so far.
+func (resolver *FakeResolver) Calls() []ResolveCall {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	return append([]ResolveCall(nil), resolver.calls...)
+}
@@ -0,0 +1,116 @@
+package registrytest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	registrydomain "github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/services/registry"
+	"github.com/forta-network/forta-node/services/registry/registrytest"
+	"github.com/forta-network/forta-node/store"
+)
+
+func TestRefresherUsesResolver(t *testing.T) {
+	r := require.New(t)
+
+	oldContracts := registrydomain.RegistryContracts{Dispatch: common.HexToAddress("0x1")}
+	newContracts := registrydomain.RegistryContracts{Dispatch: common.HexToAddress("0x2")}
+	resolver := registrytest.NewFakeResolver(oldContracts)
+	resolver.Enqueue(
+		registrytest.ResolveResult{Contracts: oldContracts},
+		registrytest.ResolveResult{Err: errors.New("rpc is down")},
+		registrytest.ResolveResult{Contracts: newContracts},
+	)
+	changes := make(chan registrydomain.RegistryContracts, 1)
+	refresher := registry.NewContractRefresherWithResolver(resolver, time.Millisecond*10, func(_, newContracts registrydomain.RegistryContracts) {
+		changes <- newContracts
+	})
+	refresher.SetEndpoint("https://rpc.example.com", "0x08f42fcc52a9C2F391bF507C4E8688D0b53e1bd7")
+
+	r.NoError(refresher.Start(context.Background()))
+	defer refresher.Stop()
+	r.Equal(oldContracts.Dispatch, refresher.ResolvedContracts().Dispatch)
+
+	select {
+	case contracts := <-changes:
+		r.Equal(newContracts, contracts)
+	case <-time.After(time.Second * 2):
+		r.FailNow("change was not detected")
+	}
+	r.Equal(newContracts.Dispatch, refresher.ResolvedContracts().Dispatch)
+
+	calls := resolver.Calls()
+	r.GreaterOrEqual(len(calls), 3)
+	r.Equal(registrytest.ResolveCall{
+		Endpoint:   "https://rpc.example.com",
+		ENSAddress: "0x08f42fcc52a9C2F391bF507C4E8688D0b53e1bd7",
+	}, calls[0])
+}
+
+func TestRefresherFailsWithResolver(t *testing.T) {
+	r := require.New(t)
+
+	resolveErr := errors.New("no such name")
+	resolver := registrytest.NewFakeResolver(registrydomain.RegistryContracts{})
+	resolver.Set(registrydomain.RegistryContracts{}, resolveErr)
+	refresher := registry.NewContractRefresherWithResolver(resolver, time.Minute, nil)
+	r.ErrorIs(refresher.Start(context.Background()), resolveErr)
+	r.Len(resolver.Calls(), 1)
+}
+
+func testResolveConfig(r *require.Assertions) config.Config {
+	var cfg config.Config
+	r.NoError(config.ApplyDefaults(&cfg))
+	cfg.ENSConfig.JsonRpcUrls = []string{"https://rpc.example.com"}
+	cfg.ENSConfig.ContractAddress = "0x08f42fcc52a9C2F391bF507C4E8688D0b53e1bd7"
+	return cfg
+}
+
+func TestResolveAndReport(t *testing.T) {
+	r := require.New(t)
+
+	resolver := registrytest.NewFakeResolver(registrydomain.RegistryContracts{
+		Dispatch:           common.HexToAddress("0x1"),
+		AgentRegistry:      common.HexToAddress("0x2"),
+		ScannerNodeVersion: common.HexToAddress("0x3"),
+	})
+	resolved, err := registry.ResolveAndReportWithResolver(context.Background(), testResolveConfig(r), resolver)
+	r.NoError(err)
+	r.Equal(registry.ResolvedContracts{
+		Dispatch:       common.HexToAddress("0x1"),
+		Agent:          common.HexToAddress("0x2"),
+		ScannerVersion: common.HexToAddress("0x3"),
+		Endpoint:       "https://rpc.example.com",
+	}, resolved)
+	r.Equal([]registrytest.ResolveCall{{
+		Endpoint:   "https://rpc.example.com",
+		ENSAddress: "0x08f42fcc52a9C2F391bF507C4E8688D0b53e1bd7",
+	}}, resolver.Calls())
+}
+
+func TestResolveAndReportErrors(t *testing.T) {
+	r := require.New(t)
+
+	resolveErr := &store.ContractResolutionError{Stage: store.ResolutionStageCall, Err: errors.New("no such name")}
+	resolver := registrytest.NewFakeResolver(registrydomain.RegistryContracts{})
+	resolver.Set(registrydomain.RegistryContracts{}, resolveErr)
+	resolved, err := registry.ResolveAndReportWithResolver(context.Background(), testResolveConfig(r), resolver)
+	var resolutionErr *store.ContractResolutionError
+	r.True(errors.As(err, &resolutionErr))
+	r.Equal(store.ResolutionStageCall, resolutionErr.Stage)
+	r.Equal("https://rpc.example.com", resolved.Endpoint)
+
+	// the config is validated before resolving
+	cfg := testResolveConfig(r)
+	cfg.ENSConfig.JsonRpcUrls = []string{"rpc.example.com"}
+	_, err = registry.ResolveAndReportWithResolver(context.Background(), cfg, resolver)
+	r.Error(err)
+	r.Contains(err.Error(), "invalid config")
+	r.Len(resolver.Calls(), 1)
+}
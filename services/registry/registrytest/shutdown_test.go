@@ -0,0 +1,63 @@
+package registrytest_test
+
+import (
+	"context"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	registrydomain "github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/services"
+	"github.com/forta-network/forta-node/services/registry"
+	"github.com/forta-network/forta-node/services/servicetest"
+)
+
+// slowENSStore blocks resolving the contracts until it is released.
+type slowENSStore struct {
+	ens.ENS
+	resolving chan struct{}
+	release   chan struct{}
+}
+
+func (store *slowENSStore) ResolveRegistryContracts() (*registrydomain.RegistryContracts, error) {
+	close(store.resolving)
+	<-store.release
+	return &registrydomain.RegistryContracts{}, nil
+}
+
+func TestSignalDuringResolutionStopsContainer(t *testing.T) {
+	r := require.New(t)
+
+	level, formatter := logrus.GetLevel(), logrus.StandardLogger().Formatter
+	t.Cleanup(func() {
+		logrus.SetLevel(level)
+		logrus.SetFormatter(formatter)
+	})
+
+	var cfg config.Config
+	r.NoError(config.ApplyDefaults(&cfg))
+
+	ensStore := &slowENSStore{resolving: make(chan struct{}), release: make(chan struct{})}
+	defer close(ensStore.release)
+	scanner := servicetest.NewFakeService("scanner")
+	go func() {
+		<-ensStore.resolving
+		_ = syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	}()
+
+	begin := time.Now()
+	services.ContainerMainWithLoader("test", func() (config.Config, error) {
+		return cfg, nil
+	}, func(ctx context.Context, cfg config.Config) ([]services.Service, error) {
+		return []services.Service{registry.NewContractRefresher(ensStore, time.Minute, nil), scanner}, nil
+	})
+	r.Less(time.Since(begin), time.Second*2)
+	r.Equal(0, scanner.Starts())
+	r.True(services.IsGracefulShutdown())
+}
@@ -0,0 +1,40 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// ResolveAndReport resolves the registry contracts with the ENS config in the same way as
+// the node does when it starts, without starting the node. It is meant for debugging the
+// ENS issues.
+func ResolveAndReport(ctx context.Context, cfg config.Config) (ResolvedContracts, error) {
+	if err := cfg.Validate(); err != nil {
+		return ResolvedContracts{}, fmt.Errorf("invalid config: %w", err)
+	}
+	ensStore, err := ensStoreFromConfig(ctx, cfg)
+	if err != nil {
+		return ResolvedContracts{}, err
+	}
+	return resolveAndReport(ctx, cfg, NewENSStoreResolver(ensStore))
+}
+
+// ResolveAndReportWithResolver is like ResolveAndReport but it resolves the contracts with
+// the given resolver. The returned contracts have the endpoint even if resolving fails.
+func ResolveAndReportWithResolver(ctx context.Context, cfg config.Config, resolver ContractResolver) (ResolvedContracts, error) {
+	if err := cfg.Validate(); err != nil {
+		return ResolvedContracts{}, fmt.Errorf("invalid config: %w", err)
+	}
+	return resolveAndReport(ctx, cfg, resolver)
+}
+
+func resolveAndReport(ctx context.Context, cfg config.Config, resolver ContractResolver) (ResolvedContracts, error) {
+	endpoint := ensEndpoint(cfg)
+	contracts, err := resolver.Resolve(ctx, endpoint, cfg.ENSConfig.ContractAddress)
+	if err != nil {
+		return ResolvedContracts{Endpoint: endpoint}, err
+	}
+	return newResolvedContracts(contracts, endpoint), nil
+}
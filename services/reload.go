@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// ReloadSignal makes the config reload when the reload is enabled for the main context.
+const ReloadSignal = syscall.SIGHUP
+
+// Reloadable is implemented by services which can apply a reloaded config without restarting.
+type Reloadable interface {
+	Reload(cfg config.Config) error
+}
+
+// ReloadImpacter is implemented by services which need to be restarted when the reloaded
+// config changes the values which they use. The services which do not implement it keep
+// running after the reload.
+type ReloadImpacter interface {
+	ReloadImpact(old, new config.Config) bool
+}
+
+// EnableReload makes the reload signal reload the config by using the given loader,
+// instead of cancelling the main context.
+func (mainCtx *MainContext) EnableReload(loadConfig func() (config.Config, error)) {
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	mainCtx.loadConfig = loadConfig
+}
+
+// reloadCallback wraps a reload callback so that it can be found when it is unregistered.
+type reloadCallback struct {
+	callback func(cfg config.Config)
+}
+
+// OnReload registers a callback which receives the reloaded config. The returned func
+// unregisters the callback.
+func (mainCtx *MainContext) OnReload(callback func(cfg config.Config)) (unregister func()) {
+	registered := &reloadCallback{callback: callback}
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	mainCtx.reloadCallbacks = append(mainCtx.reloadCallbacks, registered)
+	return func() {
+		mainCtx.mu.Lock()
+		defer mainCtx.mu.Unlock()
+		for i, existing := range mainCtx.reloadCallbacks {
+			if existing == registered {
+				mainCtx.reloadCallbacks = append(mainCtx.reloadCallbacks[:i:i], mainCtx.reloadCallbacks[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+func (mainCtx *MainContext) canReload() bool {
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	return mainCtx.loadConfig != nil
+}
+
+// reload loads the config again and passes it to the reload callbacks.
+func (mainCtx *MainContext) reload() {
+	mainCtx.mu.RLock()
+	loadConfig := mainCtx.loadConfig
+	mainCtx.mu.RUnlock()
+
+	log.Info("reloading config")
+	cfg, err := loadConfig()
+	if err != nil {
+		log.WithError(err).Error("failed to reload config")
+		return
+	}
+	mainCtx.setConfig(cfg)
+	mainCtx.runReloadCallbacks(cfg)
+}
+
+// setConfig sets the config which the services are running with.
+func (mainCtx *MainContext) setConfig(cfg config.Config) {
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	mainCtx.cfg = &cfg
+}
+
+// getConfig returns the config which the services are running with, or nil if it is not known.
+func (mainCtx *MainContext) getConfig() *config.Config {
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	return mainCtx.cfg
+}
+
+func (mainCtx *MainContext) runReloadCallbacks(cfg config.Config) {
+	mainCtx.mu.RLock()
+	callbacks := make([]*reloadCallback, len(mainCtx.reloadCallbacks))
+	copy(callbacks, mainCtx.reloadCallbacks)
+	mainCtx.mu.RUnlock()
+
+	for _, registered := range callbacks {
+		registered.callback(cfg)
+	}
+}
+
+// reloadServices passes the reloaded config to the running reloadable services, except the
+// restarted ones.
+func reloadServices(logger *log.Entry, statuses *StatusRegistry, services []Service, cfg config.Config, restarted map[string]bool) {
+	for _, service := range services {
+		if restarted[service.Name()] {
+			continue
+		}
+		reloadable, ok := underlying(service).(Reloadable)
+		if !ok {
+			continue
+		}
+		if status, _ := statuses.Status(service.Name()); status.State != StateRunning {
+			continue
+		}
+		if err := reloadable.Reload(cfg); err != nil {
+			logger.WithField("service", service.Name()).WithError(err).Error("failed to reload service")
+		}
+	}
+}
+
+// restartImpacted restarts the running services which are affected by the config change in the
+// background and returns their names.
+func (opts Options) restartImpacted(
+	ctx context.Context, logger *log.Entry, services []Service, oldCfg, newCfg config.Config,
+	restarts *healthRestarts, pending *pendingStarts,
+) map[string]bool {
+	restarted := make(map[string]bool)
+	for _, service := range services {
+		impacter, ok := underlying(service).(ReloadImpacter)
+		if !ok || !impacter.ReloadImpact(oldCfg, newCfg) {
+			continue
+		}
+		if status, _ := opts.Statuses.Status(service.Name()); status.State != StateRunning {
+			continue
+		}
+		if !restarts.add() {
+			break
+		}
+		restarted[service.Name()] = true
+		logger := logger.WithField("service", service.Name())
+		logger.Info("reloaded config affects the service - restarting")
+		go func(service Service) {
+			defer restarts.done()
+			if err := opts.restartService(WithServiceName(ctx, service.Name()), logger, service, StopReasonReload, pending); err != nil {
+				logger.WithError(err).Error("failed to restart the service after the reload")
+				return
+			}
+			logger.Info("restarted the service after the reload")
+		}(service)
+	}
+	return restarted
+}
+
+// ReloadLogLevel applies the log levels from the reloaded config.
+func ReloadLogLevel(cfg config.Config) {
+	if err := SetLogLevels(cfg.Log); err != nil {
+		log.WithError(err).Error("could not reload log level")
+	}
+}
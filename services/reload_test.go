@@ -0,0 +1,157 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+type reloadableService struct {
+	reloaded chan config.Config
+}
+
+func (s *reloadableService) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *reloadableService) Stop() error {
+	return nil
+}
+
+func (s *reloadableService) Name() string {
+	return "reloadable"
+}
+
+func (s *reloadableService) Reload(cfg config.Config) error {
+	s.reloaded <- cfg
+	return nil
+}
+
+func TestSigHupReloadsConfig(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	newCfg := config.Config{Log: config.LogConfig{Level: "debug"}}
+	mainCtx.EnableReload(func() (config.Config, error) {
+		return newCfg, nil
+	})
+	var (
+		callbackCfg config.Config
+		mu          sync.Mutex
+	)
+	mainCtx.OnReload(func(cfg config.Config) {
+		mu.Lock()
+		defer mu.Unlock()
+		callbackCfg = cfg
+	})
+
+	svc := &reloadableService{reloaded: make(chan config.Config, 1)}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc})
+	}()
+
+	r.Eventually(func() bool {
+		status, _ := mainCtx.Statuses().Status(svc.Name())
+		return status.State == StateRunning
+	}, time.Second, time.Millisecond*10)
+	mainCtx.sigc <- syscall.SIGHUP
+
+	select {
+	case cfg := <-svc.reloaded:
+		r.Equal(newCfg, cfg)
+	case <-time.After(time.Second):
+		r.FailNow("service was not reloaded")
+	}
+	mu.Lock()
+	r.Equal(newCfg, callbackCfg)
+	mu.Unlock()
+	r.NoError(mainCtx.Context().Err())
+
+	mainCtx.sigc <- syscall.SIGTERM
+	r.NoError(<-errCh)
+	r.True(mainCtx.IsGracefulShutdown())
+}
+
+func TestSigHupCancelsWithoutReload(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	mainCtx.sigc <- syscall.SIGHUP
+	select {
+	case <-mainCtx.Context().Done():
+	case <-time.After(time.Second):
+		r.FailNow("context was not cancelled")
+	}
+}
+
+// urlService is restarted when the URL it uses changes.
+type urlService struct {
+	countingService
+	url func(cfg config.Config) string
+}
+
+func (s *urlService) ReloadImpact(old, new config.Config) bool {
+	return s.url(old) != s.url(new)
+}
+
+func TestReloadRestartsImpactedServices(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	oldCfg := config.Config{}
+	oldCfg.Scan.JsonRpc.Url = "https://scan.example.com"
+	oldCfg.Trace.JsonRpc.Url = "https://trace.example.com"
+	newCfg := oldCfg
+	newCfg.Scan.JsonRpc.Url = "https://scan-2.example.com"
+	mainCtx.setConfig(oldCfg)
+	mainCtx.EnableReload(func() (config.Config, error) {
+		return newCfg, nil
+	})
+
+	scanner := &urlService{countingService: countingService{name: "scanner"}, url: func(cfg config.Config) string {
+		return cfg.Scan.JsonRpc.Url
+	}}
+	tracer := &urlService{countingService: countingService{name: "tracer"}, url: func(cfg config.Config) string {
+		return cfg.Trace.JsonRpc.Url
+	}}
+	other := &countingService{name: "other"}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{scanner, tracer, other})
+	}()
+	requireRunning(r, mainCtx, "other")
+
+	mainCtx.sigc <- syscall.SIGHUP
+	r.Eventually(func() bool {
+		return atomic.LoadInt64(&scanner.starts) == 2
+	}, time.Second, time.Millisecond*10)
+	requireRunning(r, mainCtx, "scanner")
+	status, _ := mainCtx.Statuses().Status("scanner")
+	r.Equal(StopReasonReload, status.StopReason)
+	r.Equal(int64(1), atomic.LoadInt64(&scanner.stops))
+
+	// the services which are not affected keep running
+	for _, svc := range []*countingService{&tracer.countingService, other} {
+		r.Equal(int64(1), atomic.LoadInt64(&svc.starts), svc.name)
+		r.Equal(int64(0), atomic.LoadInt64(&svc.stops), svc.name)
+	}
+	r.NoError(mainCtx.Context().Err())
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+}
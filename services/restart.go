@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// Default restart policy values
+const (
+	DefaultRestartMaxAttempts  = 3
+	DefaultRestartBaseInterval = time.Second
+	DefaultRestartMaxInterval  = time.Second * 30
+)
+
+// NoRestarts is the max attempts of a restart policy which does not restart the service.
+const NoRestarts = -1
+
+// RestartPolicy describes how a service should be restarted after failing to start.
+type RestartPolicy struct {
+	// MaxAttempts is how many times the service is restarted. A negative value, like NoRestarts,
+	// disables the restarts.
+	MaxAttempts  int
+	BaseInterval time.Duration
+	MaxInterval  time.Duration
+	// RestartUnhealthy makes the service restarted when it stays unhealthy for longer than
+	// the health grace period, instead of only being reported.
+	RestartUnhealthy bool
+}
+
+// Restartable is implemented by services which opt into being restarted when
+// they fail to start. Zero values in the returned policy fall back to the
+// policy from the options.
+type Restartable interface {
+	RestartPolicy() RestartPolicy
+}
+
+// RestartPolicyFromConfig makes the default restart policy from the config.
+func RestartPolicyFromConfig(cfg config.Config) RestartPolicy {
+	restartCfg := cfg.Services.Restart
+	policy := RestartPolicy{
+		BaseInterval: time.Duration(restartCfg.BaseIntervalSeconds) * time.Second,
+		MaxInterval:  time.Duration(restartCfg.MaxIntervalSeconds) * time.Second,
+	}
+	if restartCfg.MaxAttempts != nil {
+		policy.MaxAttempts = *restartCfg.MaxAttempts
+		if policy.MaxAttempts == 0 {
+			policy.MaxAttempts = NoRestarts
+		}
+	}
+	return policy
+}
+
+// withDefaults fills in the zero values from the given policy and then from the package defaults.
+func (policy RestartPolicy) withDefaults(defaultPolicy RestartPolicy) RestartPolicy {
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = defaultPolicy.MaxAttempts
+	}
+	if policy.MaxAttempts == 0 {
+		policy.MaxAttempts = DefaultRestartMaxAttempts
+	}
+	if policy.BaseInterval <= 0 {
+		policy.BaseInterval = defaultPolicy.BaseInterval
+	}
+	if policy.BaseInterval <= 0 {
+		policy.BaseInterval = DefaultRestartBaseInterval
+	}
+	if policy.MaxInterval <= 0 {
+		policy.MaxInterval = defaultPolicy.MaxInterval
+	}
+	if policy.MaxInterval <= 0 {
+		policy.MaxInterval = DefaultRestartMaxInterval
+	}
+	return policy
+}
+
+// backoff calculates the delay before the given restart attempt which starts from 1.
+func (policy RestartPolicy) backoff(attempt int) time.Duration {
+	delay := policy.BaseInterval
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= policy.MaxInterval {
+			return policy.MaxInterval
+		}
+	}
+	if delay > policy.MaxInterval {
+		return policy.MaxInterval
+	}
+	return delay
+}
+
+func (opts Options) restartPolicy(service Service) (RestartPolicy, bool) {
+	restartable, ok := underlying(service).(Restartable)
+	if !ok {
+		return RestartPolicy{}, false
+	}
+	return restartable.RestartPolicy().withDefaults(opts.RestartPolicy), true
+}
+
+// startService starts the service and keeps restarting it with backoff if it is restartable.
+func (opts Options) startService(ctx context.Context, logger *log.Entry, service Service) error {
+	err := service.Start(ctx)
+	policy, ok := opts.restartPolicy(service)
+	if !ok {
+		return err
+	}
+	for attempt := 1; err != nil && attempt <= policy.MaxAttempts; attempt++ {
+		delay := policy.backoff(attempt)
+		logger.WithError(err).WithFields(log.Fields{
+			"attempt": attempt,
+			"delay":   delay.String(),
+		}).Warn("failed to start service - restarting")
+		select {
+		case <-opts.clock().After(delay):
+		case <-ctx.Done():
+			// the start was cancelled, the failure before the backoff is not the result
+			return ctx.Err()
+		}
+		opts.recordRestart(service)
+		err = service.Start(ctx)
+	}
+	return err
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+type flakyService struct {
+	failures  int
+	starts    int
+	policy    RestartPolicy
+	onStarted func()
+}
+
+func (s *flakyService) Start(ctx context.Context) error {
+	s.starts++
+	if s.starts <= s.failures {
+		return errors.New("failed to start")
+	}
+	if s.onStarted != nil {
+		s.onStarted()
+	}
+	return nil
+}
+
+func (s *flakyService) Stop() error {
+	return nil
+}
+
+func (s *flakyService) Name() string {
+	return "flaky"
+}
+
+func (s *flakyService) RestartPolicy() RestartPolicy {
+	return s.policy
+}
+
+func TestRestartSucceedsAfterFailures(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	// cancel shortly after the successful start to finish
+	svc := &flakyService{
+		failures: 2,
+		policy:   RestartPolicy{MaxAttempts: 3, BaseInterval: time.Millisecond * 10},
+		onStarted: func() {
+			time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+		},
+	}
+	r.NoError(StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc}))
+	r.Equal(3, svc.starts)
+}
+
+func TestRestartGivesUpAfterMaxAttempts(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	svc := &flakyService{
+		failures: 5,
+		policy:   RestartPolicy{MaxAttempts: 2, BaseInterval: time.Millisecond * 10},
+	}
+	_ = StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc})
+	r.Equal(3, svc.starts)
+	r.ErrorIs(mainCtx.Context().Err(), context.Canceled)
+}
+
+func TestRestartCancelledDuringBackoff(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	svc := &flakyService{
+		failures: 5,
+		policy:   RestartPolicy{MaxAttempts: 3, BaseInterval: time.Hour},
+	}
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc})
+	r.ErrorIs(err, context.Canceled)
+	r.True(isStartupCancelled(err))
+	r.Equal(1, svc.starts)
+
+	// the start itself returns the cancellation instead of the failure before the backoff
+	svc = &flakyService{
+		failures: 5,
+		policy:   RestartPolicy{MaxAttempts: 3, BaseInterval: time.Hour},
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(time.Millisecond*50, cancel)
+	r.ErrorIs(Options{}.startService(ctx, logrus.NewEntry(logrus.StandardLogger()), svc), context.Canceled)
+	r.Equal(1, svc.starts)
+}
+
+func TestRestartsDisabledInConfig(t *testing.T) {
+	r := require.New(t)
+
+	var cfg config.Config
+	r.NoError(config.ApplyDefaults(&cfg))
+	r.Equal(3, RestartPolicyFromConfig(cfg).MaxAttempts)
+
+	noRestarts := 0
+	cfg.Services.Restart.MaxAttempts = &noRestarts
+	r.NoError(config.ApplyDefaults(&cfg))
+	r.Equal(NoRestarts, RestartPolicyFromConfig(cfg).MaxAttempts)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	// the service policy falls back to the config which disables the restarts
+	svc := &flakyService{failures: 1, policy: RestartPolicy{BaseInterval: time.Millisecond * 10}}
+	err := StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc}, OptionsFromConfig(cfg))
+	r.Error(err)
+	r.Equal(1, svc.starts)
+}
+
+func TestRestartPolicyBackoff(t *testing.T) {
+	r := require.New(t)
+
+	policy := RestartPolicy{BaseInterval: time.Second, MaxInterval: time.Second * 5}.withDefaults(RestartPolicy{})
+	r.Equal(DefaultRestartMaxAttempts, policy.MaxAttempts)
+	r.Equal(time.Second, policy.backoff(1))
+	r.Equal(time.Second*2, policy.backoff(2))
+	r.Equal(time.Second*4, policy.backoff(3))
+	r.Equal(time.Second*5, policy.backoff(4))
+	r.Equal(time.Second*5, policy.backoff(10))
+}
@@ -5,187 +5,1094 @@ import (
 	"errors"
 	"fmt"
 	"os"
-	"os/signal"
+	"reflect"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/forta-network/forta-core-go/utils"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
 )
 
 const (
-	defaultServiceStartDelay = time.Minute * 10
+	// DefaultServiceStartTimeout is how long a service is given to start by default.
+	DefaultServiceStartTimeout = time.Minute * 10
+	// DefaultServiceStopTimeout is how long a service is given to stop by default.
+	DefaultServiceStopTimeout = time.Second * 30
+	// DefaultShutdownTimeout is how long the services are given to drain and stop by default.
+	DefaultShutdownTimeout = time.Minute * 2
 )
 
 const (
 	GracefulShutdownSignal = syscall.SIGTERM
 
 	ExitCodeTriggered = 77
+	ExitCodeForced    = 1
+
+	ExitCodeServiceFailed      = 2
+	ExitCodeConfigError        = 3
+	ExitCodeContractResolution = 4
 )
 
+// exitProcess is used for exiting with an error code at the end of ContainerMain.
+var exitProcess = os.Exit
+
+// exitCodeForError returns the exit code for the failure class of the error.
+func exitCodeForError(err error) int {
+	var resolutionErr *store.ContractResolutionError
+	if errors.As(err, &resolutionErr) {
+		return ExitCodeContractResolution
+	}
+	return ExitCodeServiceFailed
+}
+
 // Errors
 var (
 	ErrExitTriggered = errors.New("exit was triggered")
 )
 
-// Service is a service abstraction.
+// Service is a service abstraction. The context given to Start is cancelled
+// when the service should abort starting.
 type Service interface {
-	Start() error
+	Start(ctx context.Context) error
 	Stop() error
 	Name() string
 }
 
-var sigc = make(chan os.Signal)
+// Optional is implemented by the nice-to-have services. When an optional service fails
+// to start, the failure is logged and the rest of the services keep running.
+type Optional interface {
+	Optional() bool
+}
+
+func isOptional(service Service) bool {
+	optional, ok := underlying(service).(Optional)
+	return ok && optional.Optional()
+}
+
+// StartTimeouter is implemented by services which need a start timeout different
+// than the default. Returning zero falls back to the default.
+type StartTimeouter interface {
+	StartTimeout() time.Duration
+}
+
+// StopTimeouter is implemented by services which need a stop timeout different
+// than the default. Returning zero falls back to the default.
+type StopTimeouter interface {
+	StopTimeout() time.Duration
+}
+
+// StartGrouper is implemented by services which share a resource for starting, like the Docker
+// daemon, with the other services in the same group. The start group limits are applied to the
+// groups in addition to the start parallelism.
+type StartGrouper interface {
+	StartGroup() string
+}
+
+func startGroupOf(service Service) string {
+	grouper, ok := underlying(service).(StartGrouper)
+	if !ok {
+		return ""
+	}
+	return grouper.StartGroup()
+}
+
+// Options customize how the services are started.
+type Options struct {
+	// StartTimeout is used for the services which do not specify their own start timeout.
+	StartTimeout time.Duration
+	// StartupDeadline limits how long starting all of the services can take in total.
+	// There is no limit if it is zero.
+	StartupDeadline time.Duration
+	// StopTimeout is used for the services which do not specify their own stop timeout.
+	StopTimeout time.Duration
+	// RestartPolicy is used for filling in the restart policies of the restartable services.
+	RestartPolicy RestartPolicy
+	// HealthCheckInterval is how often the health of the services is checked.
+	HealthCheckInterval time.Duration
+	// HealthGracePeriod is how long a service can stay unhealthy before it is reported.
+	HealthGracePeriod time.Duration
+	// Metrics receives the start and stop durations of the services.
+	Metrics MetricsSink
+	// Panics receives the reports of the panics which are recovered from the services.
+	Panics PanicSink
+	// OnStart is called after each service starts successfully, in the order that they start.
+	OnStart func(name string)
+	// OnStop is called after each service is stopped.
+	OnStop func(name string, err error)
+	// Statuses receives the service status updates. The registry of the main context is used if not set.
+	Statuses *StatusRegistry
+	// Events receives the service lifecycle events. The event bus of the main context is used if not set.
+	Events *EventBus
+	// CollectStartErrors makes the startup continue after a service fails to start so that
+	// all of the start errors are returned together.
+	CollectStartErrors bool
+	// StartParallelism is how many services can be started at the same time. The services
+	// are started one by one if it is not more than one. Otherwise, each service is started
+	// as soon as its dependencies are started.
+	StartParallelism int
+	// StartGroupLimits are how many services of each start group can be started at the same time.
+	StartGroupLimits map[string]int
+	// ReadinessProbes are the readiness checks by service name. A service with a probe is started
+	// only after its probe passes.
+	ReadinessProbes map[string]ReadinessProbe
+	// ReadinessPolicy is how often the readiness probes are polled.
+	ReadinessPolicy ReadyPolicy
+	// DrainPeriod is how long the services are given to finish their in-flight work
+	// after the shutdown starts and before they are stopped.
+	DrainPeriod time.Duration
+	// ShutdownTimeout is how long the services are given to drain and stop after the context
+	// is done. The services which are still stopping after the timeout are left behind.
+	ShutdownTimeout time.Duration
+	// ShutdownCallbackTimeout is how long the shutdown callbacks of the main context are given
+	// in total after the services are stopped.
+	ShutdownCallbackTimeout time.Duration
+	// Clock is used for the timeouts and the intervals. The real clock is used if not set.
+	Clock Clock
+
+	// onStartupDone receives the start errors after all of the services are started.
+	onStartupDone func(err error)
+}
+
+// OptionsFromConfig makes the options from the config.
+func OptionsFromConfig(cfg config.Config) Options {
+	return Options{
+		StartTimeout:            time.Duration(cfg.Services.StartTimeoutSeconds) * time.Second,
+		StartupDeadline:         time.Duration(cfg.Services.StartupDeadlineSeconds) * time.Second,
+		StopTimeout:             time.Duration(cfg.Services.StopTimeoutSeconds) * time.Second,
+		RestartPolicy:           RestartPolicyFromConfig(cfg),
+		HealthCheckInterval:     time.Duration(cfg.Services.HealthCheckIntervalSeconds) * time.Second,
+		HealthGracePeriod:       time.Duration(cfg.Services.HealthGracePeriodSeconds) * time.Second,
+		CollectStartErrors:      cfg.Services.CollectStartErrors,
+		StartParallelism:        cfg.Services.StartParallelism,
+		StartGroupLimits:        cfg.Services.StartGroupLimits,
+		ReadinessProbes:         readinessProbesFromConfig(cfg.Services.ReadinessProbes),
+		DrainPeriod:             time.Duration(cfg.Services.DrainPeriodSeconds) * time.Second,
+		ShutdownTimeout:         time.Duration(cfg.Services.ShutdownTimeoutSeconds) * time.Second,
+		ShutdownCallbackTimeout: time.Duration(cfg.Services.ShutdownCallbackTimeoutSeconds) * time.Second,
+	}
+}
+
+func (opts Options) startTimeout(service Service) time.Duration {
+	if st, ok := underlying(service).(StartTimeouter); ok && st.StartTimeout() > 0 {
+		return st.StartTimeout()
+	}
+	if opts.StartTimeout > 0 {
+		return opts.StartTimeout
+	}
+	return DefaultServiceStartTimeout
+}
+
+func (opts Options) stopTimeout(service Service) time.Duration {
+	if st, ok := underlying(service).(StopTimeouter); ok && st.StopTimeout() > 0 {
+		return st.StopTimeout()
+	}
+	if opts.StopTimeout > 0 {
+		return opts.StopTimeout
+	}
+	return DefaultServiceStopTimeout
+}
+
+func (opts Options) shutdownTimeout() time.Duration {
+	if opts.ShutdownTimeout > 0 {
+		return opts.ShutdownTimeout
+	}
+	return DefaultShutdownTimeout
+}
 
 var execIDKey = struct{}{}
 
+// ExecID returns the exec ID from the context and panics if the context does not have it.
+// Use it only where the context is known to be derived from the main context.
 func ExecID(ctx context.Context) string {
-	execID := ctx.Value(execIDKey)
-	if execID == nil {
+	execID, ok := ExecIDOk(ctx)
+	if !ok {
 		panic("cannot get exec ID")
 	}
-	return execID.(string)
+	return execID
+}
+
+// ExecIDOk returns the exec ID from the context and tells if the context has it. Use this
+// in the library code and the background routines which can get any context.
+func ExecIDOk(ctx context.Context) (string, bool) {
+	execID, ok := ctx.Value(execIDKey).(string)
+	return execID, ok
+}
+
+// WithExecID returns a context which carries the given exec ID.
+func WithExecID(ctx context.Context, execID string) context.Context {
+	return context.WithValue(ctx, execIDKey, execID)
 }
 
+// initExecID uses the exec ID from the env so that the node containers share the same ID,
+// or generates a new ID if the env var is not set or invalid.
 func initExecID(ctx context.Context) context.Context {
+	if envExecID, ok := os.LookupEnv(config.EnvExecID); ok {
+		execID, err := uuid.Parse(envExecID)
+		if err == nil {
+			return WithExecID(ctx, execID.String())
+		}
+		log.WithError(err).WithField("execId", envExecID).Warn("invalid exec ID in env - generating new")
+	}
 	execID, err := uuid.NewUUID()
 	if err != nil {
 		panic(err)
 	}
-	return context.WithValue(ctx, execIDKey, execID.String())
+	return WithExecID(ctx, execID.String())
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns a context which carries a new request ID. Use it for scoping a single
+// operation, like scanning a block, so that its logs can be correlated. Unlike the exec ID,
+// the request ID changes for each operation.
+func WithRequestID(ctx context.Context) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, uuid.New().String())
+}
+
+// RequestID returns the request ID from the context and tells if the context has it.
+func RequestID(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDKey{}).(string)
+	return requestID, ok
+}
+
+type serviceNameKey struct{}
+
+// WithServiceName returns a context which carries the service name. The services get
+// such a context derived from the main context when they are started.
+func WithServiceName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, serviceNameKey{}, name)
+}
+
+// ServiceName returns the service name from the context and tells if the context has it.
+func ServiceName(ctx context.Context) (string, bool) {
+	name, ok := ctx.Value(serviceNameKey{}).(string)
+	return name, ok
 }
 
+type serviceDirectoryKey struct{}
+
+// withServiceDirectory returns a context which carries the names of the services in the same run.
+func withServiceDirectory(ctx context.Context, services []Service) context.Context {
+	names := make([]string, len(services))
+	for i, service := range services {
+		names[i] = service.Name()
+	}
+	return context.WithValue(ctx, serviceDirectoryKey{}, names)
+}
+
+// ServicesFrom returns the names of all of the services which are started together with
+// the service, in the start order. It returns nil if the context is not a service context.
+func ServicesFrom(ctx context.Context) []string {
+	names, _ := ctx.Value(serviceDirectoryKey{}).([]string)
+	if names == nil {
+		return nil
+	}
+	// copy so that the services cannot modify the directory
+	return append([]string(nil), names...)
+}
+
+// ContainerMain runs the services of a container with the config from the container config files.
 func ContainerMain(name string, getServices func(ctx context.Context, cfg config.Config) ([]Service, error)) {
+	ContainerMainWithLoader(name, config.GetConfigForContainer, getServices)
+}
+
+// ContainerMainWithLoader runs the services of a container with the config from the loader.
+// The loader is used again when the config is reloaded. It wires the logging, the OS signals
+// and the exit codes around the same run as Run.
+func ContainerMainWithLoader(
+	name string, loader func() (config.Config, error),
+	getServices func(ctx context.Context, cfg config.Config) ([]Service, error),
+) {
 	logger := log.WithField("container", name)
 
-	cfg, err := config.GetConfigForContainer()
+	cfg, err := loader()
 	if err != nil {
 		logger.WithError(err).Error("could not get config")
+		exitProcess(ExitCodeConfigError)
+		return
+	}
+	if err := cfg.Validate(); err != nil {
+		logger.WithError(err).Error("invalid config")
+		exitProcess(ExitCodeConfigError)
 		return
 	}
 
-	lvl, err := log.ParseLevel(cfg.Log.Level)
-	if err != nil {
+	log.SetFormatter(cfg.Log.NewFormatter(&log.JSONFormatter{}))
+	if err := SetLogLevels(cfg.Log); err != nil {
 		logger.WithError(err).Error("could not initialize log level")
+		exitProcess(ExitCodeConfigError)
 		return
 	}
-	log.SetLevel(lvl)
-	log.SetFormatter(&log.JSONFormatter{})
-	logger.Info("starting")
+	closeLogs := installAsyncLogging(log.StandardLogger(), cfg.Log.Async)
+	defer closeLogs()
+	// the deferred calls do not run when exiting with a code
+	exit := func(code int) {
+		closeLogs()
+		exitProcess(code)
+	}
+	logger.WithField("config", cfg.Redacted()).Debug("loaded config")
+	buildInfo := config.GetBuildInfo()
+	logger.WithFields(log.Fields{
+		"version":   buildInfo.Version,
+		"commit":    buildInfo.Commit,
+		"buildDate": buildInfo.BuildDate,
+	}).Info("starting")
 	defer logger.Info("exiting")
 
-	ctx, cancel := InitMainContext()
+	mainCtx := InitMainContext()
+	defer mainCtx.Cancel()
+	log.AddHook(&ContextHook{})
+	if len(cfg.Services.StackDumpFile) > 0 {
+		stackDumpFile, err := os.OpenFile(cfg.Services.StackDumpFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			logger.WithError(err).Warn("could not open the stack dump file - stack dumps will be logged")
+		} else {
+			defer stackDumpFile.Close()
+			mainCtx.SetStackDumpWriter(stackDumpFile)
+		}
+	}
+	mainCtx.EnableReload(loader)
+	mainCtx.OnReload(ReloadLogLevel)
+	if cfg.Services.RestartOnReload {
+		mainCtx.EnableRestart()
+	}
+	if cfg.Services.MaxRunSeconds > 0 {
+		mainCtx.ShutdownAfter(time.Duration(cfg.Services.MaxRunSeconds) * time.Second)
+	}
+
+	err = runInMainContext(context.Background(), mainCtx, logger, cfg, getServices)
+	if err == ErrExitTriggered {
+		logger.Info("exiting due to internal trigger")
+		exit(ExitCodeTriggered)
+		return
+	}
+	if errors.Is(err, ErrShutdownTimeout) {
+		logger.WithError(err).Error("forcing exit")
+		exit(ExitCodeForced)
+		return
+	}
+	// a shutdown during the startup is not a failure
+	if err != nil && !isStartupCancelled(err) {
+		exit(exitCodeForError(err))
+	}
+}
+
+// isStartupCancelled tells if the startup was cancelled by the shutdown without a service failure.
+func isStartupCancelled(err error) bool {
+	var serviceErr *ServiceError
+	return errors.Is(err, context.Canceled) && !errors.As(err, &serviceErr)
+}
+
+// Run runs the services with the config until the context is done or the services fail and
+// returns the final error. Unlike ContainerMain, it does not handle the OS signals, set up
+// the logging or exit the process.
+func Run(ctx context.Context, cfg config.Config, getServices func(ctx context.Context, cfg config.Config) ([]Service, error)) error {
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	return runInMainContext(ctx, mainCtx, log.NewEntry(log.StandardLogger()), cfg, getServices)
+}
+
+// runInMainContext is Run with the given main context, so that ContainerMain can run the
+// services in the main context which handles the OS signals.
+func runInMainContext(
+	ctx context.Context, mainCtx *MainContext, logger *log.Entry, cfg config.Config,
+	getServices func(ctx context.Context, cfg config.Config) ([]Service, error),
+) error {
+	if err := cfg.Validate(); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	go func() {
+		select {
+		case <-ctx.Done():
+			mainCtx.Cancel()
+		case <-mainCtx.Context().Done():
+		}
+	}()
+	// the entries without a context of their own get the exec ID of the main context
+	return runServices(mainCtx, logger.WithContext(mainCtx.Context()), cfg, getServices)
+}
+
+// ServiceNames returns the names of the services which getServices makes for the config, e.g.
+// for listing the names which can be enabled in the config. The services are not started and
+// the context which they are made with is cancelled before returning.
+func ServiceNames(ctx context.Context, cfg config.Config, getServices func(ctx context.Context, cfg config.Config) ([]Service, error)) ([]string, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
+	serviceList, err := getServices(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkNilServices(serviceList); err != nil {
+		return nil, err
+	}
+	names := make([]string, len(serviceList))
+	for i, service := range serviceList {
+		names[i] = service.Name()
+	}
+	return names, nil
+}
 
+// runServices initializes the services of the config together with the servers from the
+// config and runs them until the main context is done.
+func runServices(
+	mainCtx *MainContext, logger *log.Entry, cfg config.Config,
+	getServices func(ctx context.Context, cfg config.Config) ([]Service, error),
+) error {
+	servers, err := newServerSet(mainCtx, logger, cfg)
+	if err != nil {
+		return err
+	}
+	if mainCtx.canRestart() {
+		err = runWithRestarts(mainCtx, logger, cfg, servers, getServices)
+	} else {
+		err = runOnce(mainCtx, logger, cfg, servers, getServices)
+	}
+	if err == nil || err == ErrExitTriggered || errors.Is(err, ErrShutdownTimeout) {
+		return err
+	}
+	if isStartupCancelled(err) {
+		logger.Info("stopped before all of the services were started")
+		return err
+	}
+	var shutdownErr *ShutdownError
+	if errors.As(err, &shutdownErr) {
+		logger = logger.WithField("signal", shutdownErr.Signal.String())
+	}
+	var serviceErr *ServiceError
+	if errors.As(err, &serviceErr) {
+		logger.WithError(serviceErr.Err).WithFields(log.Fields{
+			"service": serviceErr.Name,
+			"phase":   serviceErr.Phase,
+		}).Error("service failed")
+	} else {
+		logger.WithError(err).Error("failed to start services")
+	}
+	return err
+}
+
+// runOnce runs the services together with the servers until the main context is done.
+func runOnce(
+	mainCtx *MainContext, logger *log.Entry, cfg config.Config, servers serverSet,
+	getServices func(ctx context.Context, cfg config.Config) ([]Service, error),
+) error {
+	serviceList, err := initServices(mainCtx.Context(), logger, cfg, getServices)
+	if err != nil {
+		return err
+	}
+	mainCtx.setConfig(cfg)
+	serviceList = servers.wrap(serviceList)
+	if isDryRun(logger, serviceList) {
+		return nil
+	}
+	return StartServicesWithOptions(mainCtx, logger, serviceList, servers.options(cfg))
+}
+
+// initServices gets the services of the config which are enabled.
+func initServices(
+	ctx context.Context, logger *log.Entry, cfg config.Config,
+	getServices func(ctx context.Context, cfg config.Config) ([]Service, error),
+) ([]Service, error) {
 	serviceList, err := getServices(ctx, cfg)
+	if err == nil {
+		err = checkNilServices(serviceList)
+	}
+	if err == nil {
+		serviceList, err = filterEnabled(logger, serviceList, cfg.Services)
+	}
+	if err == nil && len(serviceList) == 0 {
+		err = ErrNoServices
+	}
 	if err != nil {
 		logger.WithError(err).Error("could not initialize services")
-		return
+		return nil, err
+	}
+	return serviceList, nil
+}
+
+// isDryRun logs the services and tells if they should not be started.
+func isDryRun(logger *log.Entry, services []Service) bool {
+	if !utils.ParseBoolEnvVar(config.EnvDryRun) {
+		return false
+	}
+	var names []string
+	for _, service := range services {
+		names = append(names, service.Name())
 	}
+	logger.WithField("services", names).Info("dry run succeeded - not starting the services")
+	return true
+}
 
-	err = StartServices(ctx, cancel, logger, serviceList)
-	if err == ErrExitTriggered {
-		logger.Info("exiting due to internal trigger")
-		os.Exit(ExitCodeTriggered)
+// serverSet has the servers which are enabled in the config.
+type serverSet struct {
+	metrics  *MetricsServer
+	trailing []Service
+}
+
+// newServerSet creates the servers which serve the statuses of the main context.
+func newServerSet(mainCtx *MainContext, logger *log.Entry, cfg config.Config) (serverSet, error) {
+	var servers serverSet
+	if probeCfg := cfg.Services.ProbeServer; probeCfg.Enable {
+		servers.trailing = append(servers.trailing, NewProbeServer(probeCfg.Address, mainCtx.Statuses()))
+	}
+	if pprofServer, ok := PprofServerFromConfig(cfg); ok {
+		servers.trailing = append(servers.trailing, pprofServer)
+	}
+	if metricsCfg := cfg.Services.MetricsServer; metricsCfg.Enable {
+		metricsServer, err := NewMetricsServer(metricsCfg.Address, nil, mainCtx.Statuses())
+		if err != nil {
+			logger.WithError(err).Error("could not initialize the metrics server")
+			return serverSet{}, err
+		}
+		servers.metrics = metricsServer
 	}
+	return servers, nil
+}
+
+// wrap adds the servers to the services. The metrics server starts first so that the
+// metrics are available during the startup.
+func (servers serverSet) wrap(services []Service) []Service {
+	var wrapped []Service
+	if servers.metrics != nil {
+		wrapped = append(wrapped, servers.metrics)
+	}
+	wrapped = append(wrapped, services...)
+	return append(wrapped, servers.trailing...)
+}
+
+// all returns only the servers.
+func (servers serverSet) all() []Service {
+	return servers.wrap(nil)
+}
+
+// options makes the options from the config for running the services with the servers.
+func (servers serverSet) options(cfg config.Config) Options {
+	opts := OptionsFromConfig(cfg)
+	if servers.metrics != nil {
+		opts.Metrics = servers.metrics
+	}
+	return opts
+}
+
+// StartServices kicks off all services.
+func StartServices(mainCtx *MainContext, logger *log.Entry, services []Service) error {
+	return StartServicesWithOptions(mainCtx, logger, services, Options{})
+}
+
+// StartServicesWithOptions kicks off all services by using the options. If the services fail
+// during a shutdown which a signal started, the error is a ShutdownError.
+func StartServicesWithOptions(mainCtx *MainContext, logger *log.Entry, services []Service, opts Options) error {
+	mainCtx.beginRun()
+	err := mainCtx.withShutdownSignal(startServices(mainCtx, logger, services, opts))
+	mainCtx.endRun(err)
+	return err
+}
+
+func startServices(mainCtx *MainContext, logger *log.Entry, services []Service, opts Options) error {
+	if err := checkNilServices(services); err != nil {
+		return err
+	}
+	if err := checkDuplicateNames(services); err != nil {
+		return err
+	}
+	services, err := sortByDependencies(services)
 	if err != nil {
-		logger.WithError(err).Error("failed to start services")
+		return err
+	}
+	ctx := mainCtx.Context()
+	if opts.Statuses == nil {
+		opts.Statuses = mainCtx.Statuses()
 	}
+	if opts.Events == nil {
+		opts.Events = mainCtx.Events()
+	}
+	statuses := opts.Statuses
+	statuses.setClock(opts.clock())
+	statuses.reset(services)
+
+	var (
+		started   []Service
+		startErrs []error
+	)
+	// failStart records the start error and tells if the startup should be aborted
+	failStart := func(err error) bool {
+		startErrs = append(startErrs, err)
+		if opts.CollectStartErrors {
+			return false
+		}
+		mainCtx.Cancel()
+		return true
+	}
+
+	// the services can look up each other before any of them starts
+	directoryCtx := withServiceDirectory(ctx, services)
+	failures := &runFailures{cancel: mainCtx.Cancel, opts: opts}
+	directoryCtx = withRunFailures(directoryCtx, failures)
+
+	restarts := &healthRestarts{}
+	pending := newPendingStarts()
+	previousCfg := mainCtx.getConfig()
+	// the callback is removed when the services stop so that a later run does not reload them
+	unregisterReload := mainCtx.OnReload(func(cfg config.Config) {
+		var restarted map[string]bool
+		if previousCfg != nil {
+			restarted = opts.restartImpacted(directoryCtx, logger, services, *previousCfg, cfg, restarts, pending)
+		}
+		previousCfg = &cfg
+		reloadServices(logger, statuses, services, cfg, restarted)
+	})
+	defer unregisterReload()
+
+	// each service should be able to start successfully within reasonable time
+	stopDeadline := opts.watchStartupDeadline(mainCtx, logger, services)
+	signals := newStartSignals(services)
+	groupSems := opts.startGroupSemaphores()
+	// the start hooks are called one at a time in the order that the services start
+	var onStartMu sync.Mutex
+startLoop:
+	for _, batch := range startBatches(services, opts.StartParallelism) {
+		results := make([]startResult, len(batch))
+		sem := make(chan struct{}, len(batch))
+		if opts.StartParallelism > 0 && opts.StartParallelism < len(batch) {
+			sem = make(chan struct{}, opts.StartParallelism)
+		}
+		var wg sync.WaitGroup
+		for i, service := range batch {
+			serviceCtx, cancelService := context.WithCancel(WithServiceName(directoryCtx, service.Name()))
+			defer cancelService()
+			i, service := i, service
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				groupSem := groupSems[startGroupOf(service)]
+				results[i] = opts.startAfterDependencies(ctx, serviceCtx, cancelService, logger, service, signals, sem, groupSem, pending)
+				if results[i].err == nil && opts.OnStart != nil {
+					onStartMu.Lock()
+					opts.OnStart(service.Name())
+					onStartMu.Unlock()
+				}
+				signals[service.Name()].fire(results[i].err)
+				// abort the rest of the batch without waiting for them
+				if results[i].err != nil && !opts.CollectStartErrors && !isOptional(service) {
+					mainCtx.Cancel()
+				}
+			}()
+		}
+		wg.Wait()
+
+		// the successfully started services are collected from the whole batch so that they are stopped
+		var (
+			failed   bool
+			abortErr error
+		)
+		for i, result := range results {
+			service := batch[i]
+			if result.err == nil {
+				opts.recordPhaseDuration(service, PhaseStart, result.duration)
+				started = append(started, service)
+				continue
+			}
+			if _, ok := result.err.(*ServiceError); !ok {
+				abortErr = result.err
+				continue
+			}
+			if isOptional(service) {
+				logger.WithField("service", service.Name()).WithError(result.err).Warn("optional service failed to start - continuing")
+				continue
+			}
+			if failStart(result.err) {
+				failed = true
+			}
+		}
+		switch {
+		case failed:
+			break startLoop
+		case abortErr != nil:
+			startErrs = append(startErrs, abortErr)
+			break startLoop
+		}
+	}
+	if deadlineErr := stopDeadline(); deadlineErr != nil {
+		// drop the errors which are caused by cancelling the startup
+		startErrs = append(nonCancelErrors(startErrs), deadlineErr)
+	}
+	// all start errors are collected by now
+	if len(startErrs) > 0 {
+		mainCtx.Cancel()
+	}
+	if opts.onStartupDone != nil {
+		opts.onStartupDone(combineErrors(startErrs...))
+	}
+
+	go opts.superviseHealth(directoryCtx, logger, started, restarts, pending)
+
+	<-ctx.Done()
+	runErrs := failures.get()
+	stopReason := StopReasonServiceFailed
+	switch {
+	case len(runErrs) > 0:
+		logger.WithError(combineErrors(runErrs...)).Error("shutting down after a service failure")
+	case len(startErrs) > 0:
+		logger.Info("shutting down after the start failure")
+	default:
+		logger.Info("shutting down")
+		stopReason = StopReasonShutdown
+	}
+	statuses.setCancelledStopReasons(stopReason)
+	if !restarts.wait(opts.clock(), opts.shutdownTimeout()) {
+		logger.WithField("timeout", opts.shutdownTimeout().String()).Warn("the restarts did not finish before the shutdown timeout - stopping anyway")
+	}
+
+	stopErr := opts.shutdown(logger, started, stopReason)
+	opts.runShutdownCallbacks(logger, mainCtx.getShutdownCallbacks())
+	opts.waitForPendingStarts(logger, pending)
+
+	if mainCtx.isExitTriggered() {
+		return ErrExitTriggered
+	}
+
+	// collect again for the failures during the shutdown
+	runErrs = failures.get()
+	return combineErrors(append(append(startErrs, runErrs...), stopErr)...)
 }
 
-var (
-	gracefulShutdown bool
-	exitTriggered    bool
-)
+// checkNilServices rejects the nil services, including the nil pointers which are
+// returned as a service by the constructors and the adapted legacy services.
+func checkNilServices(services []Service) error {
+	for i, service := range services {
+		if service == nil {
+			return fmt.Errorf("%w at index %d", ErrNilService, i)
+		}
+		adapted := underlying(service)
+		if value := reflect.ValueOf(adapted); value.Kind() == reflect.Ptr && value.IsNil() {
+			return fmt.Errorf("%w at index %d (%T)", ErrNilService, i, adapted)
+		}
+	}
+	return nil
+}
 
-// IsGracefulShutdown tells if we have reached a graceful shutdown condition.
-func IsGracefulShutdown() bool {
-	return gracefulShutdown
+// checkDuplicateNames rejects the services which share names because the names identify
+// the services in the logs, the dependencies and the statuses.
+func checkDuplicateNames(services []Service) error {
+	counts := make(map[string]int)
+	var duplicates []string
+	for _, service := range services {
+		name := service.Name()
+		counts[name]++
+		if counts[name] == 2 {
+			duplicates = append(duplicates, fmt.Sprintf("'%s'", name))
+		}
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%w: %s", ErrDuplicateServiceName, strings.Join(duplicates, ", "))
+	}
+	return nil
 }
 
-func InitMainContext() (context.Context, context.CancelFunc) {
-	execIDCtx := initExecID(context.Background())
-	ctx, cancel := context.WithCancel(execIDCtx)
-	signal.Notify(sigc,
-		syscall.SIGHUP,
-		syscall.SIGINT,
-		syscall.SIGTERM,
-		syscall.SIGQUIT)
+// watchStartupDeadline cancels the main context if the services are not all started within the
+// startup deadline. The returned function stops watching and returns the error if the deadline was hit.
+func (opts Options) watchStartupDeadline(mainCtx *MainContext, logger *log.Entry, services []Service) func() error {
+	if opts.StartupDeadline <= 0 {
+		return func() error { return nil }
+	}
+	timer := opts.clock().NewTimer(opts.StartupDeadline)
+	done := make(chan struct{})
+	errCh := make(chan error, 1)
 	go func() {
-		sig := <-sigc
-		log.Infof("received signal: %s", sig.String())
-		gracefulShutdown = sig == GracefulShutdownSignal
-		cancel()
+		select {
+		case <-timer.C():
+		case <-done:
+			timer.Stop()
+			errCh <- nil
+			return
+		}
+		var waiting []string
+		for _, service := range services {
+			if status, _ := opts.Statuses.Status(service.Name()); status.State == StateStarting {
+				waiting = append(waiting, service.Name())
+			}
+		}
+		logger.WithFields(log.Fields{
+			"deadline": opts.StartupDeadline.String(),
+			"services": waiting,
+		}).Error("services did not start before the startup deadline")
+		err := fmt.Errorf("%w after %s", ErrStartupDeadline, opts.StartupDeadline)
+		if len(waiting) > 0 {
+			err = &ServiceError{
+				Name:  waiting[0],
+				Phase: PhaseStart,
+				Err:   fmt.Errorf("%w after %s - waiting for: %s", ErrStartupDeadline, opts.StartupDeadline, strings.Join(waiting, ", ")),
+			}
+		}
+		errCh <- err
+		mainCtx.Cancel()
 	}()
-	return ctx, cancel
+	return func() error {
+		close(done)
+		return <-errCh
+	}
 }
 
-// InterruptMainContext interrupts by sending a fake interrup signal from within runtime.
-func InterruptMainContext() {
+// nonCancelErrors returns the errors which are not caused by a context cancellation.
+func nonCancelErrors(errs []error) []error {
+	var filtered []error
+	for _, err := range errs {
+		if !errors.Is(err, context.Canceled) {
+			filtered = append(filtered, err)
+		}
+	}
+	return filtered
+}
+
+// pendingStartTimeout is how long the start routines which did not return are waited for after
+// the shutdown. Their services were cancelled so they should return soon.
+var pendingStartTimeout = time.Second * 5
+
+// pendingStarts tracks the start routines of the services so that the routines which
+// still run after the start timeout or the cancellation can be accounted for.
+type pendingStarts struct {
+	wg    sync.WaitGroup
+	names map[string]bool
+	mu    sync.Mutex
+}
+
+func newPendingStarts() *pendingStarts {
+	return &pendingStarts{names: make(map[string]bool)}
+}
+
+func (pending *pendingStarts) add(name string) {
+	pending.mu.Lock()
+	defer pending.mu.Unlock()
+	pending.wg.Add(1)
+	pending.names[name] = true
+}
+
+func (pending *pendingStarts) done(name string) {
+	pending.mu.Lock()
+	defer pending.mu.Unlock()
+	delete(pending.names, name)
+	pending.wg.Done()
+}
+
+func (pending *pendingStarts) running() []string {
+	pending.mu.Lock()
+	defer pending.mu.Unlock()
+	var names []string
+	for name := range pending.names {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// waitForPendingStarts waits for the start routines to return and warns about the ones which are leaked.
+func (opts Options) waitForPendingStarts(logger *log.Entry, pending *pendingStarts) {
+	done := make(chan struct{})
+	go func() {
+		pending.wg.Wait()
+		close(done)
+	}()
 	select {
-	case sigc <- syscall.SIGINT:
-	default:
+	case <-done:
+	case <-opts.clock().After(pendingStartTimeout):
+		logger.WithField("services", pending.running()).Warn("leaking the start routines which did not return after the shutdown")
 	}
 }
 
-// TriggerExit triggers exit internally.
-func TriggerExit(delay time.Duration) {
-	if delay > 0 {
-		log.WithField("timeout", fmt.Sprintf("%s", delay)).Info("waiting before triggering exit")
-		time.Sleep(delay)
-		log.WithField("timeout", fmt.Sprintf("%s", delay)).Info("done waiting before triggering exit")
+// startResult is the outcome of starting a service.
+type startResult struct {
+	duration time.Duration
+	err      error
+}
+
+// startGroupSemaphores makes the semaphores which limit starting the services of the start groups.
+func (opts Options) startGroupSemaphores() map[string]chan struct{} {
+	sems := make(map[string]chan struct{})
+	for group, limit := range opts.StartGroupLimits {
+		if group != "" && limit > 0 {
+			sems[group] = make(chan struct{}, limit)
+		}
 	}
-	exitTriggered = true
-	InterruptMainContext()
+	return sems
 }
 
-// StartServices kicks off all services.
-func StartServices(ctx context.Context, cancelMainCtx context.CancelFunc, logger *log.Entry, services []Service) error {
-	// each service should be able to start successfully within reasonable time
-	for _, service := range services {
-		serviceStartedCtx, serviceStarted := context.WithCancel(context.Background())
-		defer serviceStarted()
+// startAfterDependencies starts the service when its dependencies are started and
+// there is room in the start semaphore and in the semaphore of its start group, if any.
+func (opts Options) startAfterDependencies(
+	ctx, serviceCtx context.Context, cancelService context.CancelFunc, logger *log.Entry, service Service,
+	signals map[string]*startSignal, sem, groupSem chan struct{}, pending *pendingStarts,
+) startResult {
+	if err := awaitDependencies(ctx, signals, service); err != nil {
+		if _, ok := err.(*ServiceError); ok {
+			logger.WithField("service", service.Name()).WithError(err).Error("not starting service")
+			opts.setStopState(service.Name(), StateFailed, StopReasonDependencyFailed, err)
+		} else {
+			opts.setState(service.Name(), StateStopped, err)
+		}
+		return startResult{err: err}
+	}
+	// the group slot is taken first so that waiting for it does not hold a start slot
+	if groupSem != nil {
+		select {
+		case groupSem <- struct{}{}:
+		case <-ctx.Done():
+			opts.setState(service.Name(), StateStopped, ctx.Err())
+			return startResult{err: ctx.Err()}
+		}
+		defer func() { <-groupSem }()
+	}
+	select {
+	case sem <- struct{}{}:
+	case <-ctx.Done():
+		opts.setState(service.Name(), StateStopped, ctx.Err())
+		return startResult{err: ctx.Err()}
+	}
+	defer func() { <-sem }()
+	return opts.startWithTimeout(ctx, serviceCtx, cancelService, logger, service, pending)
+}
 
-		logger := logger.WithField("service", service.Name())
+// startWithTimeout starts the service and waits until it is started, the start timeout
+// is reached or the context is done. The start failures are returned as service errors.
+func (opts Options) startWithTimeout(
+	ctx, serviceCtx context.Context, cancelService context.CancelFunc, logger *log.Entry, service Service,
+	pending *pendingStarts,
+) startResult {
+	clock := opts.clock()
+	logger = logger.WithField("service", service.Name()).WithContext(serviceCtx)
 
-		go func() {
-			logger.Info("starting service")
-			if err := service.Start(); err != nil {
-				logger.WithError(err).Error("failed to start service")
-				cancelMainCtx()
-				return
+	opts.setState(service.Name(), StateStarting, nil)
+	startErrCh := make(chan error, 1)
+	startBegin := clock.Now()
+	pending.add(service.Name())
+	go func() {
+		defer pending.done(service.Name())
+		// recover so that the already started services are stopped gracefully
+		defer func() {
+			if r := recover(); r != nil {
+				panicErr := &PanicError{Value: r, Stack: debug.Stack()}
+				logger.WithField("stack", string(panicErr.Stack)).Errorf("recovered from panic: %v", r)
+				opts.reportPanic(serviceCtx, service.Name(), panicErr)
+				startErrCh <- panicErr
 			}
-			serviceStarted()
 		}()
+		logger.Info("starting service")
+		if err := opts.startService(serviceCtx, logger, service); err != nil {
+			startErrCh <- err
+			return
+		}
+		startErrCh <- opts.awaitReadiness(serviceCtx, logger, service)
+	}()
 
-		select {
-		case <-time.After(defaultServiceStartDelay):
-			logger.Error("took too long to start service")
-			cancelMainCtx()
-			break
-		case <-serviceStartedCtx.Done():
-			// ok - do nothing
-		case <-ctx.Done():
-			return ctx.Err()
+	startTimeout := opts.startTimeout(service)
+	select {
+	case <-clock.After(startTimeout):
+		elapsed := clock.Now().Sub(startBegin)
+		logger.WithFields(log.Fields{
+			"timeout": startTimeout.String(),
+			"elapsed": elapsed.String(),
+		}).Errorf("service '%s' did not become ready in %s", service.Name(), startTimeout)
+		timeoutErr := fmt.Errorf("%w after %s", ErrStartTimeout, elapsed.Round(time.Millisecond))
+		opts.setStopState(service.Name(), StateFailed, StopReasonError, timeoutErr)
+		cancelService()
+		return startResult{err: &ServiceError{Name: service.Name(), Phase: PhaseStart, Err: timeoutErr}}
+	case err := <-startErrCh:
+		if err != nil && ctx.Err() != nil && errors.Is(err, context.Canceled) {
+			// the service aborted starting because of the shutdown
+			opts.setState(service.Name(), StateStopped, ctx.Err())
+			return startResult{err: ctx.Err()}
 		}
+		if err != nil {
+			logger.WithError(err).Error("failed to start service")
+			opts.setStopState(service.Name(), StateFailed, StopReasonError, err)
+			return startResult{err: &ServiceError{Name: service.Name(), Phase: PhaseStart, Err: err}}
+		}
+		opts.setState(service.Name(), StateRunning, nil)
+		return startResult{duration: clock.Now().Sub(startBegin)}
+	case <-ctx.Done():
+		opts.setState(service.Name(), StateStopped, ctx.Err())
+		return startResult{err: ctx.Err()}
 	}
+}
 
-	<-ctx.Done()
-	logger.WithError(ctx.Err()).Info("context is done")
+// shutdown drains and stops the services within the shutdown timeout. If the timeout is
+// reached, it returns without waiting for the services which are still stopping.
+func (opts Options) shutdown(logger *log.Entry, services []Service, reason StopReason) error {
+	stopErrCh := make(chan error, 1)
+	go func() {
+		opts.drainServices(logger, services)
+		stopErrCh <- opts.stopServices(logger, services, reason)
+	}()
+
+	timeout := opts.shutdownTimeout()
+	select {
+	case err := <-stopErrCh:
+		return err
+	case <-opts.clock().After(timeout):
+	}
 
-	// stop all services
+	var stopping []string
 	for _, service := range services {
+		status, _ := opts.Statuses.Status(service.Name())
+		if status.State == StateRunning || status.State == StateStopping {
+			stopping = append(stopping, service.Name())
+		}
+	}
+	logger.WithFields(log.Fields{
+		"timeout":  timeout.String(),
+		"services": stopping,
+	}).Error("services did not stop before the shutdown timeout")
+	return fmt.Errorf("%w after %s - still stopping: %s", ErrShutdownTimeout, timeout, strings.Join(stopping, ", "))
+}
+
+// stopServices stops the services in the reverse order with the stop reason and collects the errors.
+func (opts Options) stopServices(logger *log.Entry, services []Service, reason StopReason) error {
+	var errs []error
+	for i := len(services) - 1; i >= 0; i-- {
+		service := services[i]
 		serviceLogger := logger.WithField("service", service.Name())
 		serviceLogger.Info("stopping service")
-		err := service.Stop()
+		// the services which failed while running keep their own reason
+		if status, _ := opts.Statuses.Status(service.Name()); status.State == StateFailed {
+			opts.setState(service.Name(), StateStopping, nil)
+		} else {
+			opts.setStopState(service.Name(), StateStopping, reason, nil)
+		}
+		stopBegin := opts.clock().Now()
+		err := opts.stopService(service)
+		opts.recordPhaseDuration(service, PhaseStop, opts.clock().Now().Sub(stopBegin))
 		serviceLogger.WithError(err).Info("stopped service")
+		if err != nil {
+			opts.setState(service.Name(), StateFailed, err)
+			errs = append(errs, &ServiceError{Name: service.Name(), Phase: PhaseStop, Err: err})
+		} else {
+			opts.setState(service.Name(), StateStopped, nil)
+		}
+		if opts.OnStop != nil {
+			opts.OnStop(service.Name(), err)
+		}
 	}
+	return combineErrors(errs...)
+}
 
-	if exitTriggered {
-		return ErrExitTriggered
+// stopService stops the service within its stop timeout. The service is left behind
+// if it does not stop in time.
+func (opts Options) stopService(service Service) error {
+	stopErrCh := make(chan error, 1)
+	go func() {
+		stopErrCh <- service.Stop()
+	}()
+	stopTimeout := opts.stopTimeout(service)
+	select {
+	case err := <-stopErrCh:
+		return err
+	case <-opts.clock().After(stopTimeout):
+		return fmt.Errorf("%w after %s", ErrStopTimeout, stopTimeout)
 	}
-
-	return nil
 }
@@ -2,23 +2,33 @@ package services
 
 import (
 	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
 	"os"
+	"path/filepath"
 	"syscall"
 	"testing"
 	"time"
 
+	"github.com/forta-network/forta-node/config"
+	"github.com/forta-network/forta-node/store"
+	"github.com/google/uuid"
 	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
 	"golang.org/x/sync/errgroup"
 )
 
 type TestService struct {
 	cancelled bool
-	ctx       context.Context
 }
 
-func (t *TestService) Start() error {
-	grp, ctx := errgroup.WithContext(t.ctx)
+func (t *TestService) Start(ctx context.Context) error {
+	grp, ctx := errgroup.WithContext(ctx)
 	grp.Go(func() error {
 		select {
 		case <-ctx.Done():
@@ -38,16 +48,1124 @@ func (t *TestService) Name() string {
 }
 
 func TestSigIntSignalCancelsService(t *testing.T) {
-	sigc = make(chan os.Signal, 1)
-	ctx, cancel := InitMainContext()
+	mainCtx := InitMainContext()
 
 	go func() {
 		time.Sleep(1 * time.Second)
-		sigc <- syscall.SIGINT
+		mainCtx.sigc <- syscall.SIGINT
 	}()
 
-	svc := &TestService{ctx: ctx}
-	err := StartServices(ctx, cancel, logrus.NewEntry(logrus.StandardLogger()), []Service{svc})
+	svc := &TestService{}
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc})
 	assert.Error(t, err, context.Canceled)
 	assert.True(t, svc.cancelled)
 }
+
+type blockingService struct {
+	returned chan struct{}
+}
+
+func (s *blockingService) Start(ctx context.Context) error {
+	defer close(s.returned)
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func (s *blockingService) Stop() error {
+	return nil
+}
+
+func (s *blockingService) Name() string {
+	return "blocking"
+}
+
+func TestSigTermAbortsBlockedStart(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := InitMainContext()
+	defer mainCtx.Cancel()
+
+	time.AfterFunc(time.Millisecond*50, func() {
+		mainCtx.sigc <- syscall.SIGTERM
+	})
+
+	svc := &blockingService{returned: make(chan struct{})}
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc})
+	r.ErrorIs(err, context.Canceled)
+	r.True(mainCtx.IsGracefulShutdown())
+
+	select {
+	case <-svc.returned:
+	case <-time.After(time.Second):
+		r.FailNow("start did not return")
+	}
+}
+
+type slowService struct {
+	startDelay   time.Duration
+	startTimeout time.Duration
+}
+
+func (s *slowService) Start(ctx context.Context) error {
+	time.Sleep(s.startDelay)
+	return nil
+}
+
+func (s *slowService) Stop() error {
+	return nil
+}
+
+func (s *slowService) Name() string {
+	return "slow"
+}
+
+func (s *slowService) StartTimeout() time.Duration {
+	return s.startTimeout
+}
+
+func TestServiceStartTimeout(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	svc := &slowService{startDelay: time.Second, startTimeout: time.Millisecond * 50}
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc})
+	r.ErrorIs(err, ErrStartTimeout)
+	r.ErrorIs(mainCtx.Context().Err(), context.Canceled)
+}
+
+func TestServiceStartTimeoutFromOptions(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	svc := &slowService{startDelay: time.Second}
+	err := StartServicesWithOptions(
+		mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc}, Options{StartTimeout: time.Millisecond * 50},
+	)
+	r.ErrorIs(err, ErrStartTimeout)
+	r.ErrorIs(mainCtx.Context().Err(), context.Canceled)
+}
+
+func TestServiceStartTimeoutDiagnostics(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	logger, hook := test.NewNullLogger()
+	svc := &blockingService{returned: make(chan struct{})}
+	err := StartServicesWithOptions(
+		mainCtx, logrus.NewEntry(logger), []Service{svc}, Options{StartTimeout: time.Millisecond * 50},
+	)
+	r.ErrorIs(err, ErrStartTimeout)
+
+	var entry *logrus.Entry
+	for _, e := range hook.AllEntries() {
+		if e.Level == logrus.ErrorLevel {
+			entry = e
+			break
+		}
+	}
+	r.NotNil(entry)
+	r.Equal("service 'blocking' did not become ready in 50ms", entry.Message)
+	r.Equal("blocking", entry.Data["service"])
+	r.Contains(entry.Data, "elapsed")
+
+	status, ok := mainCtx.Statuses().Status("blocking")
+	r.True(ok)
+	r.Equal(StateFailed, status.State)
+	r.Contains(status.Error, ErrStartTimeout.Error())
+}
+
+func TestServiceStartTimeoutDefault(t *testing.T) {
+	r := require.New(t)
+
+	var opts Options
+	r.Equal(DefaultServiceStartTimeout, opts.startTimeout(&slowService{}))
+	r.Equal(time.Second, opts.startTimeout(&slowService{startTimeout: time.Second}))
+
+	opts = OptionsFromConfig(config.Config{Services: config.ServicesConfig{StartTimeoutSeconds: 5}})
+	r.Equal(time.Second*5, opts.startTimeout(&slowService{}))
+	r.Equal(time.Second, opts.startTimeout(&slowService{startTimeout: time.Second}))
+}
+
+type orderedService struct {
+	name    string
+	stopErr error
+	stopped *[]string
+}
+
+func (s *orderedService) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *orderedService) Stop() error {
+	*s.stopped = append(*s.stopped, s.name)
+	return s.stopErr
+}
+
+func (s *orderedService) Name() string {
+	return s.name
+}
+
+func TestStopServicesInReverseOrder(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var stopped []string
+	svcs := []Service{
+		&orderedService{name: "json-rpc", stopped: &stopped},
+		&orderedService{name: "scanner", stopped: &stopped, stopErr: errors.New("stuck")},
+		&orderedService{name: "publisher", stopped: &stopped},
+	}
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), svcs)
+	r.Error(err)
+	r.Contains(err.Error(), "service 'scanner' failed to stop: stuck")
+	r.Equal([]string{"publisher", "scanner", "json-rpc"}, stopped)
+}
+
+func TestStopOnlyStartedServices(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var stopped []string
+	svcs := []Service{
+		&orderedService{name: "json-rpc", stopped: &stopped},
+		&slowService{startDelay: time.Second, startTimeout: time.Millisecond * 50},
+		&orderedService{name: "publisher", stopped: &stopped},
+	}
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), svcs)
+	r.ErrorIs(err, ErrStartTimeout)
+	r.Equal([]string{"json-rpc"}, stopped)
+}
+
+func TestLifecycleHooks(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var (
+		stopped  []string
+		hooks    []string
+		stopErrs = make(map[string]error)
+	)
+	stopErr := errors.New("stuck")
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	err := StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&orderedService{name: "json-rpc", stopped: &stopped},
+		&orderedService{name: "scanner", stopped: &stopped, stopErr: stopErr},
+	}, Options{
+		OnStart: func(name string) {
+			hooks = append(hooks, "start:"+name)
+		},
+		OnStop: func(name string, err error) {
+			hooks = append(hooks, "stop:"+name)
+			stopErrs[name] = err
+		},
+	})
+	r.ErrorIs(err, stopErr)
+	r.Equal([]string{"start:json-rpc", "start:scanner", "stop:scanner", "stop:json-rpc"}, hooks)
+	r.Equal(stopErr, stopErrs["scanner"])
+	r.NoError(stopErrs["json-rpc"])
+}
+
+func TestLifecycleHooksParallelStart(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	tracker := &startTracker{ready: make(map[string]bool), depsReady: make(map[string]bool)}
+	newService := func(name string, delay time.Duration) Service {
+		return &trackedService{dependentService: dependentService{name: name}, delay: delay, tracker: tracker}
+	}
+	var (
+		started  []string
+		fastHook time.Time
+	)
+	begin := time.Now()
+	startErr := errors.New("failed to listen")
+	err := StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		newService("scanner", time.Millisecond*150),
+		newService("json-rpc", time.Millisecond*50),
+		newService("health", time.Millisecond*10),
+		&delayedFailingService{name: "publisher", delay: time.Millisecond * 100, startErr: startErr},
+	}, Options{
+		StartParallelism: 4,
+		OnStart: func(name string) {
+			started = append(started, name)
+			if name == "health" {
+				fastHook = time.Now()
+			}
+		},
+	})
+	r.ErrorIs(err, startErr)
+
+	// the hooks fire in the order that the services start, without waiting for the slower
+	// services, and the services which started before the failure are included
+	r.Equal([]string{"health", "json-rpc"}, started)
+	r.Less(int64(fastHook.Sub(begin)), int64(time.Millisecond*100))
+}
+
+type delayedFailingService struct {
+	name     string
+	delay    time.Duration
+	startErr error
+}
+
+func (s *delayedFailingService) Start(ctx context.Context) error {
+	time.Sleep(s.delay)
+	return s.startErr
+}
+
+func (s *delayedFailingService) Stop() error {
+	return nil
+}
+
+func (s *delayedFailingService) Name() string {
+	return s.name
+}
+
+func TestExecID(t *testing.T) {
+	r := require.New(t)
+
+	ctx := initExecID(context.Background())
+	execID, ok := ExecIDOk(ctx)
+	r.True(ok)
+	r.NotEmpty(execID)
+	r.Equal(execID, ExecID(ctx))
+
+	// derived contexts have the same ID
+	childCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	r.Equal(execID, ExecID(childCtx))
+}
+
+func TestRequestID(t *testing.T) {
+	r := require.New(t)
+
+	ctx := initExecID(context.Background())
+	requestID, ok := RequestID(ctx)
+	r.False(ok)
+	r.Empty(requestID)
+
+	requestCtx := WithRequestID(ctx)
+	requestID, ok = RequestID(requestCtx)
+	r.True(ok)
+	r.NotEmpty(requestID)
+	r.NotEqual(ExecID(ctx), requestID)
+	r.Equal(ExecID(ctx), ExecID(requestCtx))
+
+	// each operation gets a new ID
+	otherID, _ := RequestID(WithRequestID(ctx))
+	r.NotEqual(requestID, otherID)
+	nestedID, _ := RequestID(WithRequestID(requestCtx))
+	r.NotEqual(requestID, nestedID)
+}
+
+func TestExecIDMissing(t *testing.T) {
+	r := require.New(t)
+
+	execID, ok := ExecIDOk(context.Background())
+	r.False(ok)
+	r.Empty(execID)
+	r.Panics(func() {
+		ExecID(context.Background())
+	})
+}
+
+func TestExecIDFromEnv(t *testing.T) {
+	r := require.New(t)
+
+	defer os.Unsetenv(config.EnvExecID)
+
+	envExecID := "8d4f5e2a-2c4b-11ee-be56-0242ac120002"
+	r.NoError(os.Setenv(config.EnvExecID, envExecID))
+	r.Equal(envExecID, ExecID(initExecID(context.Background())))
+
+	r.NoError(os.Setenv(config.EnvExecID, "not-a-uuid"))
+	execID := ExecID(initExecID(context.Background()))
+	r.NotEqual("not-a-uuid", execID)
+	_, err := uuid.Parse(execID)
+	r.NoError(err)
+
+	r.NoError(os.Unsetenv(config.EnvExecID))
+	execID1 := ExecID(initExecID(context.Background()))
+	execID2 := ExecID(initExecID(context.Background()))
+	r.NotEqual(execID1, execID2)
+}
+
+type contextRecordingService struct {
+	name string
+	ctx  context.Context
+}
+
+func (s *contextRecordingService) Start(ctx context.Context) error {
+	s.ctx = ctx
+	return nil
+}
+
+func (s *contextRecordingService) Stop() error {
+	return nil
+}
+
+func (s *contextRecordingService) Name() string {
+	return s.name
+}
+
+func TestServiceContext(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	svc1 := &contextRecordingService{name: "service-1"}
+	svc2 := &contextRecordingService{name: "service-2"}
+	time.AfterFunc(time.Millisecond*100, mainCtx.Cancel)
+	r.NoError(StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc1, svc2}))
+
+	for _, svc := range []*contextRecordingService{svc1, svc2} {
+		name, ok := ServiceName(svc.ctx)
+		r.True(ok)
+		r.Equal(svc.name, name)
+		r.Equal(ExecID(mainCtx.Context()), ExecID(svc.ctx))
+		// the service context is derived from the main context
+		r.Error(svc.ctx.Err())
+	}
+
+	_, ok := ServiceName(mainCtx.Context())
+	r.False(ok)
+}
+
+func TestServicesFromContext(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	svc1 := &contextRecordingService{name: "service-1"}
+	svc2 := &contextRecordingService{name: "service-2"}
+	time.AfterFunc(time.Millisecond*100, mainCtx.Cancel)
+	r.NoError(StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc1, svc2}))
+
+	// the first service sees the services which start after it
+	peers := ServicesFrom(svc1.ctx)
+	r.Equal([]string{"service-1", "service-2"}, peers)
+	r.Equal(peers, ServicesFrom(svc2.ctx))
+
+	// the directory cannot be modified through the returned slice
+	peers[0] = "modified"
+	r.Equal([]string{"service-1", "service-2"}, ServicesFrom(svc1.ctx))
+
+	r.Nil(ServicesFrom(mainCtx.Context()))
+}
+
+// runContainerMain runs ContainerMain with the config file content and returns the exit code.
+func runContainerMain(t *testing.T, cfgContent string, getServices func(ctx context.Context, cfg config.Config) ([]Service, error)) int {
+	cfgPath := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, ioutil.WriteFile(cfgPath, []byte(cfgContent), 0644))
+	os.Setenv(config.EnvConfigFiles, cfgPath)
+	level, formatter := logrus.GetLevel(), logrus.StandardLogger().Formatter
+	t.Cleanup(func() {
+		os.Unsetenv(config.EnvConfigFiles)
+		logrus.SetLevel(level)
+		logrus.SetFormatter(formatter)
+		exitProcess = os.Exit
+	})
+
+	exitCode := 0
+	exitProcess = func(code int) {
+		exitCode = code
+	}
+	ContainerMain("test", getServices)
+	return exitCode
+}
+
+func TestContainerMainExitCodes(t *testing.T) {
+	noServices := func(ctx context.Context, cfg config.Config) ([]Service, error) {
+		t.Fatal("services should not be initialized")
+		return nil, nil
+	}
+
+	tests := []struct {
+		name        string
+		cfg         string
+		getServices func(ctx context.Context, cfg config.Config) ([]Service, error)
+		exitCode    int
+	}{
+		{
+			name:        "unparseable config",
+			cfg:         "log: [",
+			getServices: noServices,
+			exitCode:    ExitCodeConfigError,
+		},
+		{
+			name:        "invalid config",
+			cfg:         "log:\n  level: loud\n",
+			getServices: noServices,
+			exitCode:    ExitCodeConfigError,
+		},
+		{
+			name: "contract resolution error",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				return nil, &store.ContractResolutionError{Stage: store.ResolutionStageDial, Err: errors.New("no route")}
+			},
+			exitCode: ExitCodeContractResolution,
+		},
+		{
+			name: "contract resolution error while starting",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				return []Service{&failingService{
+					name:     "resolver",
+					startErr: &store.ContractResolutionError{Stage: store.ResolutionStageCall, Err: errors.New("reverted")},
+				}}, nil
+			},
+			exitCode: ExitCodeContractResolution,
+		},
+		{
+			name: "service initialization error",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				return nil, errors.New("failed to init")
+			},
+			exitCode: ExitCodeServiceFailed,
+		},
+		{
+			name: "no services",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				return []Service{}, nil
+			},
+			exitCode: ExitCodeServiceFailed,
+		},
+		{
+			name: "nil service",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				return []Service{nil}, nil
+			},
+			exitCode: ExitCodeServiceFailed,
+		},
+		{
+			name: "nil legacy service with enabled services",
+			cfg:  "services:\n  enabled: [legacy]\n",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				return LegacyServices(&legacyTestService{}, nil), nil
+			},
+			exitCode: ExitCodeServiceFailed,
+		},
+		{
+			name: "nil legacy pointer with disabled services",
+			cfg:  "services:\n  disabled: [scanner]\n",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				var nilPtr *legacyTestService
+				return LegacyServices(nilPtr), nil
+			},
+			exitCode: ExitCodeServiceFailed,
+		},
+		{
+			name: "service start error",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				return []Service{&failingService{name: "failing", startErr: errors.New("failed to start")}}, nil
+			},
+			exitCode: ExitCodeServiceFailed,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.exitCode, runContainerMain(t, test.cfg, test.getServices))
+		})
+	}
+}
+
+func TestNilServices(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var stopped []string
+	first := &orderedService{name: "first", stopped: &stopped}
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{first, nil})
+	r.ErrorIs(err, ErrNilService)
+	r.Contains(err.Error(), "at index 1")
+
+	var nilPtr *orderedService
+	err = StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{nilPtr, first})
+	r.ErrorIs(err, ErrNilService)
+	r.Contains(err.Error(), "at index 0 (*services.orderedService)")
+
+	// nothing is started
+	r.Empty(stopped)
+	r.NoError(mainCtx.Context().Err())
+}
+
+func TestDuplicateServiceNames(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var stopped []string
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&orderedService{name: "scanner", stopped: &stopped},
+		&orderedService{name: "publisher", stopped: &stopped},
+		&orderedService{name: "scanner", stopped: &stopped},
+		&orderedService{name: "publisher", stopped: &stopped},
+		&orderedService{name: "scanner", stopped: &stopped},
+	})
+	r.ErrorIs(err, ErrDuplicateServiceName)
+	r.EqualError(err, "duplicate service names: 'scanner', 'publisher'")
+
+	// nothing is started
+	r.Empty(stopped)
+	r.Empty(mainCtx.Statuses().Statuses())
+	r.NoError(mainCtx.Context().Err())
+}
+
+func TestContainerMainWithLoaderError(t *testing.T) {
+	r := require.New(t)
+
+	t.Cleanup(func() {
+		exitProcess = os.Exit
+	})
+	exitCode := 0
+	exitProcess = func(code int) {
+		exitCode = code
+	}
+
+	var initialized bool
+	ContainerMainWithLoader("test", func() (config.Config, error) {
+		return config.Config{}, errors.New("failed to load")
+	}, func(ctx context.Context, cfg config.Config) ([]Service, error) {
+		initialized = true
+		return nil, nil
+	})
+	r.Equal(ExitCodeConfigError, exitCode)
+	r.False(initialized)
+}
+
+func TestContainerMainWithLoader(t *testing.T) {
+	r := require.New(t)
+
+	level, formatter := logrus.GetLevel(), logrus.StandardLogger().Formatter
+	t.Cleanup(func() {
+		logrus.SetLevel(level)
+		logrus.SetFormatter(formatter)
+		exitProcess = os.Exit
+	})
+	exitCode := -1
+	exitProcess = func(code int) {
+		exitCode = code
+	}
+
+	var cfg config.Config
+	r.NoError(config.ApplyDefaults(&cfg))
+	cfg.Log.Level = "warn"
+
+	svc := &contextRecordingService{name: "loaded"}
+	ContainerMainWithLoader("test", func() (config.Config, error) {
+		return cfg, nil
+	}, func(ctx context.Context, loaded config.Config) ([]Service, error) {
+		r.Equal("warn", loaded.Log.Level)
+		time.AfterFunc(time.Millisecond*20, func() {
+			getProcessMainContext().Cancel()
+		})
+		return []Service{svc}, nil
+	})
+	r.Equal(-1, exitCode)
+	r.Equal(logrus.WarnLevel, logrus.GetLevel())
+}
+
+func TestContainerMainMaxRun(t *testing.T) {
+	r := require.New(t)
+
+	level, formatter := logrus.GetLevel(), logrus.StandardLogger().Formatter
+	t.Cleanup(func() {
+		logrus.SetLevel(level)
+		logrus.SetFormatter(formatter)
+		exitProcess = os.Exit
+	})
+	exitCode := -1
+	exitProcess = func(code int) {
+		exitCode = code
+	}
+
+	var cfg config.Config
+	r.NoError(config.ApplyDefaults(&cfg))
+	cfg.Services.MaxRunSeconds = 1
+
+	var stopped []string
+	begin := time.Now()
+	ContainerMainWithLoader("test", func() (config.Config, error) {
+		return cfg, nil
+	}, func(ctx context.Context, loaded config.Config) ([]Service, error) {
+		return []Service{&orderedService{name: "scanner", stopped: &stopped}}, nil
+	})
+	elapsed := time.Since(begin)
+	r.GreaterOrEqual(elapsed, time.Second)
+	r.Less(elapsed, time.Second*3)
+	r.Equal([]string{"scanner"}, stopped)
+	r.Equal(-1, exitCode)
+	// as if the graceful shutdown signal was received
+	r.True(getProcessMainContext().IsGracefulShutdown())
+}
+
+func TestServiceNames(t *testing.T) {
+	r := require.New(t)
+
+	var servicesCtx context.Context
+	jsonRPC := &countingService{name: "json-rpc"}
+	scanner := &countingService{name: "scanner"}
+	names, err := ServiceNames(context.Background(), config.Config{ChainID: 137}, func(ctx context.Context, cfg config.Config) ([]Service, error) {
+		r.Equal(137, cfg.ChainID)
+		servicesCtx = ctx
+		return []Service{jsonRPC, scanner}, nil
+	})
+	r.NoError(err)
+	r.Equal([]string{"json-rpc", "scanner"}, names)
+	r.Zero(jsonRPC.starts + scanner.starts)
+	r.Error(servicesCtx.Err())
+
+	initErr := errors.New("failed to dial")
+	_, err = ServiceNames(context.Background(), config.Config{}, func(ctx context.Context, cfg config.Config) ([]Service, error) {
+		return nil, initErr
+	})
+	r.ErrorIs(err, initErr)
+}
+
+type hangingStopService struct {
+	name string
+}
+
+func (s *hangingStopService) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *hangingStopService) Stop() error {
+	select {}
+}
+
+func (s *hangingStopService) Name() string {
+	return s.name
+}
+
+func TestShutdownTimeout(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	statuses := NewStatusRegistry()
+	first := &contextRecordingService{name: "first"}
+	hanging := &hangingStopService{name: "hanging"}
+
+	time.AfterFunc(time.Millisecond*20, mainCtx.Cancel)
+	shutdownTimeout := time.Millisecond * 100
+	begin := time.Now()
+	err := StartServicesWithOptions(
+		mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{first, hanging},
+		Options{ShutdownTimeout: shutdownTimeout, Statuses: statuses},
+	)
+	r.ErrorIs(err, ErrShutdownTimeout)
+	r.Contains(err.Error(), "still stopping: first, hanging")
+	r.GreaterOrEqual(time.Since(begin), shutdownTimeout)
+	r.Less(time.Since(begin), time.Second)
+
+	status, _ := statuses.Status("hanging")
+	r.Equal(StateStopping, status.State)
+}
+
+type slowStopService struct {
+	name    string
+	delay   time.Duration
+	stopped chan struct{}
+}
+
+func (s *slowStopService) Start(ctx context.Context) error {
+	return nil
+}
+
+func (s *slowStopService) Stop() error {
+	time.Sleep(s.delay)
+	close(s.stopped)
+	return nil
+}
+
+func (s *slowStopService) Name() string {
+	return s.name
+}
+
+func TestStopTimeout(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	statuses := NewStatusRegistry()
+	fast := &slowStopService{name: "fast", stopped: make(chan struct{})}
+	slow := &slowStopService{name: "slow", delay: time.Millisecond * 50, stopped: make(chan struct{})}
+	hanging := &hangingStopService{name: "hanging"}
+
+	time.AfterFunc(time.Millisecond*20, mainCtx.Cancel)
+	err := StartServicesWithOptions(
+		mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{fast, slow, hanging},
+		Options{StopTimeout: time.Millisecond * 100, Statuses: statuses},
+	)
+	r.ErrorIs(err, ErrStopTimeout)
+	var serviceErr *ServiceError
+	r.True(errors.As(err, &serviceErr))
+	r.Equal("hanging", serviceErr.Name)
+	r.Equal(PhaseStop, serviceErr.Phase)
+
+	// the rest are stopped after the hanging service times out
+	for _, svc := range []*slowStopService{fast, slow} {
+		<-svc.stopped
+		status, _ := statuses.Status(svc.name)
+		r.Equal(StateStopped, status.State, svc.name)
+	}
+	status, _ := statuses.Status("hanging")
+	r.Equal(StateFailed, status.State)
+}
+
+type optionalService struct {
+	failingService
+}
+
+func (s *optionalService) Optional() bool {
+	return true
+}
+
+func TestOptionalServiceFailure(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	statuses := NewStatusRegistry()
+	optional := &optionalService{failingService{name: "metrics", startErr: errors.New("address in use")}}
+	required := &contextRecordingService{name: "scanner"}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{optional, required},
+			Options{Statuses: statuses})
+	}()
+	r.NoError(statuses.WaitReady(context.Background()))
+
+	// the node keeps running without the optional service
+	select {
+	case err := <-errCh:
+		r.FailNow("stopped after the optional service failed", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+	r.NoError(mainCtx.Context().Err())
+	status, _ := statuses.Status("metrics")
+	r.Equal(StateFailed, status.State)
+	r.True(status.Optional)
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+}
+
+func TestRequiredServiceFailure(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	startErr := errors.New("failed to dial")
+	optional := &optionalService{failingService{name: "metrics"}}
+	required := &failingService{name: "scanner", startErr: startErr}
+
+	err := StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{optional, required})
+	r.ErrorIs(err, startErr)
+	r.Error(mainCtx.Context().Err())
+}
+
+func TestContainerMainDryRun(t *testing.T) {
+	os.Setenv(config.EnvDryRun, "true")
+	t.Cleanup(func() {
+		os.Unsetenv(config.EnvDryRun)
+	})
+
+	t.Run("passing", func(t *testing.T) {
+		r := require.New(t)
+
+		svc := &failingService{name: "scanner", startErr: errors.New("should not be started")}
+		var constructed bool
+		exitCode := runContainerMain(t, "", func(ctx context.Context, cfg config.Config) ([]Service, error) {
+			constructed = true
+			return []Service{svc}, nil
+		})
+		r.Equal(0, exitCode)
+		r.True(constructed)
+		status, ok := getProcessMainContext().Statuses().Status("scanner")
+		r.False(ok, status)
+	})
+
+	tests := []struct {
+		name        string
+		cfg         string
+		getServices func(ctx context.Context, cfg config.Config) ([]Service, error)
+		exitCode    int
+	}{
+		{
+			name: "bad config",
+			cfg:  "log:\n  level: loud\n",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				return nil, nil
+			},
+			exitCode: ExitCodeConfigError,
+		},
+		{
+			name: "resolution error",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				return nil, &store.ContractResolutionError{Stage: store.ResolutionStageCall, Err: errors.New("reverted")}
+			},
+			exitCode: ExitCodeContractResolution,
+		},
+		{
+			name: "service construction error",
+			getServices: func(ctx context.Context, cfg config.Config) ([]Service, error) {
+				return nil, errors.New("failed to create the client")
+			},
+			exitCode: ExitCodeServiceFailed,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			require.Equal(t, test.exitCode, runContainerMain(t, test.cfg, test.getServices))
+		})
+	}
+}
+
+type delayedService struct {
+	name    string
+	delay   time.Duration
+	stopped *[]string
+}
+
+func (s *delayedService) Start(ctx context.Context) error {
+	select {
+	case <-time.After(s.delay):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *delayedService) Stop() error {
+	*s.stopped = append(*s.stopped, s.name)
+	return nil
+}
+
+func (s *delayedService) Name() string {
+	return s.name
+}
+
+func TestStartupDeadline(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	// each service starts within its own timeout but not all of them within the deadline
+	var stopped []string
+	svcs := []Service{
+		&delayedService{name: "first", delay: time.Millisecond * 100, stopped: &stopped},
+		&delayedService{name: "second", delay: time.Millisecond * 100, stopped: &stopped},
+		&delayedService{name: "third", delay: time.Millisecond * 100, stopped: &stopped},
+	}
+	err := StartServicesWithOptions(
+		mainCtx, logrus.NewEntry(logrus.StandardLogger()), svcs,
+		Options{StartTimeout: time.Second, StartupDeadline: time.Millisecond * 250},
+	)
+	r.ErrorIs(err, ErrStartupDeadline)
+	r.NotErrorIs(err, context.Canceled)
+	var serviceErr *ServiceError
+	r.ErrorAs(err, &serviceErr)
+	r.Equal("third", serviceErr.Name)
+	r.Contains(err.Error(), "waiting for: third")
+	r.Equal([]string{"second", "first"}, stopped)
+}
+
+func TestStartupDeadlineNotReached(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var stopped []string
+	svcs := []Service{
+		&delayedService{name: "first", delay: time.Millisecond * 10, stopped: &stopped},
+		&delayedService{name: "second", delay: time.Millisecond * 10, stopped: &stopped},
+	}
+	time.AfterFunc(time.Millisecond*200, mainCtx.Cancel)
+	r.NoError(StartServicesWithOptions(
+		mainCtx, logrus.NewEntry(logrus.StandardLogger()), svcs, Options{StartupDeadline: time.Millisecond * 100},
+	))
+	r.Equal([]string{"second", "first"}, stopped)
+}
+
+func TestStartTimeoutDoesNotLeakGoroutines(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	defer goleak.VerifyNone(t, goleak.IgnoreCurrent())
+
+	svc := &blockingService{returned: make(chan struct{})}
+	err := StartServicesWithOptions(
+		mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc}, Options{StartTimeout: time.Millisecond * 50},
+	)
+	r.ErrorIs(err, ErrStartTimeout)
+
+	// the start routine is waited for before returning
+	select {
+	case <-svc.returned:
+	default:
+		r.FailNow("returned before the start routine")
+	}
+}
+
+type stuckService struct {
+	release chan struct{}
+}
+
+func (s *stuckService) Start(ctx context.Context) error {
+	<-s.release
+	return nil
+}
+
+func (s *stuckService) Stop() error {
+	return nil
+}
+
+func (s *stuckService) Name() string {
+	return "stuck"
+}
+
+func TestLeakedStartIsReported(t *testing.T) {
+	r := require.New(t)
+
+	logger, hook := test.NewNullLogger()
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	defer func(timeout time.Duration) {
+		pendingStartTimeout = timeout
+	}(pendingStartTimeout)
+	pendingStartTimeout = time.Millisecond * 50
+
+	svc := &stuckService{release: make(chan struct{})}
+	defer close(svc.release)
+	err := StartServicesWithOptions(
+		mainCtx, logrus.NewEntry(logger), []Service{svc}, Options{StartTimeout: time.Millisecond * 50},
+	)
+	r.ErrorIs(err, ErrStartTimeout)
+	entry := hook.LastEntry()
+	r.Contains(entry.Message, "leaking the start routines")
+	r.Equal([]string{"stuck"}, entry.Data["services"])
+}
+
+func TestRun(t *testing.T) {
+	r := require.New(t)
+
+	var cfg config.Config
+	r.NoError(config.ApplyDefaults(&cfg))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	svc := &contextRecordingService{name: "embedded"}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Run(ctx, cfg, func(ctx context.Context, cfg config.Config) ([]Service, error) {
+			return []Service{svc}, nil
+		})
+	}()
+
+	select {
+	case err := <-errCh:
+		r.FailNow("returned before the context is cancelled", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+	cancel()
+	select {
+	case err := <-errCh:
+		r.NoError(err)
+	case <-time.After(time.Second):
+		r.FailNow("did not return after the context is cancelled")
+	}
+	r.Error(svc.ctx.Err())
+}
+
+func TestRunProbeServer(t *testing.T) {
+	r := require.New(t)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	r.NoError(err)
+	address := listener.Addr().String()
+	r.NoError(listener.Close())
+
+	var cfg config.Config
+	r.NoError(config.ApplyDefaults(&cfg))
+	cfg.Services.ProbeServer.Enable = true
+	cfg.Services.ProbeServer.Address = address
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- Run(ctx, cfg, func(ctx context.Context, cfg config.Config) ([]Service, error) {
+			return []Service{&contextRecordingService{name: "embedded"}}, nil
+		})
+	}()
+
+	// the probe server serves the statuses of the services which Run started
+	r.Eventually(func() bool {
+		resp, err := http.Get("http://" + address + "/readyz")
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return resp.StatusCode == http.StatusOK
+	}, time.Second*5, time.Millisecond*20)
+
+	cancel()
+	r.NoError(<-errCh)
+}
+
+func TestRunErrors(t *testing.T) {
+	r := require.New(t)
+
+	var cfg config.Config
+	r.NoError(config.ApplyDefaults(&cfg))
+
+	startErr := errors.New("failed to start")
+	err := Run(context.Background(), cfg, func(ctx context.Context, cfg config.Config) ([]Service, error) {
+		return []Service{&failingService{name: "failing", startErr: startErr}}, nil
+	})
+	r.ErrorIs(err, startErr)
+
+	initErr := errors.New("failed to init")
+	err = Run(context.Background(), cfg, func(ctx context.Context, cfg config.Config) ([]Service, error) {
+		return nil, initErr
+	})
+	r.ErrorIs(err, initErr)
+
+	cfg.Log.Level = "loud"
+	err = Run(context.Background(), cfg, func(ctx context.Context, cfg config.Config) ([]Service, error) {
+		t.Fatal("services should not be initialized")
+		return nil, nil
+	})
+	r.Error(err)
+	r.Contains(err.Error(), "invalid config")
+}
@@ -0,0 +1,123 @@
+package servicetest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-node/services"
+)
+
+// FakeClock is a clock which moves only when it is advanced. It is safe for concurrent use.
+type FakeClock struct {
+	now     time.Time
+	waiters []*fakeTimer
+	mu      sync.Mutex
+	cond    *sync.Cond
+}
+
+var _ services.Clock = &FakeClock{}
+
+// NewFakeClock creates a new fake clock which starts from the given time.
+func NewFakeClock(now time.Time) *FakeClock {
+	clock := &FakeClock{now: now}
+	clock.cond = sync.NewCond(&clock.mu)
+	return clock
+}
+
+// Now implements the services.Clock interface.
+func (clock *FakeClock) Now() time.Time {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	return clock.now
+}
+
+// After implements the services.Clock interface.
+func (clock *FakeClock) After(d time.Duration) <-chan time.Time {
+	return clock.NewTimer(d).C()
+}
+
+// NewTimer implements the services.Clock interface.
+func (clock *FakeClock) NewTimer(d time.Duration) services.Timer {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	timer := &fakeTimer{clock: clock, ch: make(chan time.Time, 1)}
+	clock.schedule(timer, d)
+	return timer
+}
+
+// Advance moves the clock forward and fires the timers which are due.
+func (clock *FakeClock) Advance(d time.Duration) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	clock.now = clock.now.Add(d)
+	var pending []*fakeTimer
+	for _, timer := range clock.waiters {
+		if timer.deadline.After(clock.now) {
+			pending = append(pending, timer)
+			continue
+		}
+		select {
+		case timer.ch <- clock.now:
+		default:
+		}
+	}
+	clock.waiters = pending
+}
+
+// BlockUntil blocks until there are at least n timers waiting for the clock to be advanced.
+func (clock *FakeClock) BlockUntil(n int) {
+	clock.mu.Lock()
+	defer clock.mu.Unlock()
+	for len(clock.waiters) < n {
+		clock.cond.Wait()
+	}
+}
+
+// schedule adds the timer to the waiters. The lock must be held.
+func (clock *FakeClock) schedule(timer *fakeTimer, d time.Duration) {
+	timer.deadline = clock.now.Add(d)
+	if d <= 0 {
+		select {
+		case timer.ch <- clock.now:
+		default:
+		}
+		return
+	}
+	clock.waiters = append(clock.waiters, timer)
+	clock.cond.Broadcast()
+}
+
+// unschedule removes the timer from the waiters and tells if it was waiting. The lock must be held.
+func (clock *FakeClock) unschedule(timer *fakeTimer) bool {
+	for i, waiter := range clock.waiters {
+		if waiter == timer {
+			clock.waiters = append(clock.waiters[:i], clock.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	ch       chan time.Time
+}
+
+func (timer *fakeTimer) C() <-chan time.Time {
+	return timer.ch
+}
+
+func (timer *fakeTimer) Stop() bool {
+	timer.clock.mu.Lock()
+	defer timer.clock.mu.Unlock()
+	return timer.clock.unschedule(timer)
+}
+
+func (timer *fakeTimer) Reset(d time.Duration) bool {
+	timer.clock.mu.Lock()
+	defer timer.clock.mu.Unlock()
+	active := timer.clock.unschedule(timer)
+	timer.clock.schedule(timer, d)
+	return active
+}
@@ -0,0 +1,130 @@
+// Package servicetest has helpers for testing the code which starts and stops services.
+package servicetest
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/forta-network/forta-node/services"
+)
+
+// FakeService is a configurable service which counts the Start and Stop calls. It is safe
+// for concurrent use. Set the fields before the service is started.
+type FakeService struct {
+	// ServiceName is returned by Name(). The default is "fake".
+	ServiceName string
+	// StartDelay is how long Start blocks, unless the context is done before.
+	StartDelay time.Duration
+	// StartErr is returned by Start.
+	StartErr error
+	// StopErr is returned by Stop.
+	StopErr error
+	// OnReady is called after each successful Start with the service context.
+	OnReady func(ctx context.Context)
+
+	starts int
+	stops  int
+	ready  chan struct{}
+	mu     sync.Mutex
+	once   sync.Once
+}
+
+var _ services.Service = &FakeService{}
+
+// NewFakeService creates a new fake service with the name.
+func NewFakeService(name string) *FakeService {
+	return &FakeService{ServiceName: name}
+}
+
+func (s *FakeService) readyCh() chan struct{} {
+	s.once.Do(func() {
+		s.ready = make(chan struct{})
+	})
+	return s.ready
+}
+
+// Start implements the services.Service interface.
+func (s *FakeService) Start(ctx context.Context) error {
+	s.mu.Lock()
+	s.starts++
+	s.mu.Unlock()
+
+	if s.StartDelay > 0 {
+		select {
+		case <-time.After(s.StartDelay):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if s.StartErr != nil {
+		return s.StartErr
+	}
+
+	if s.OnReady != nil {
+		s.OnReady(ctx)
+	}
+	s.mu.Lock()
+	select {
+	case <-s.readyCh():
+	default:
+		close(s.readyCh())
+	}
+	s.mu.Unlock()
+	return nil
+}
+
+// Stop implements the services.Service interface.
+func (s *FakeService) Stop() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stops++
+	return s.StopErr
+}
+
+// Name implements the services.Service interface.
+func (s *FakeService) Name() string {
+	if len(s.ServiceName) > 0 {
+		return s.ServiceName
+	}
+	return "fake"
+}
+
+// Starts returns how many times Start was called.
+func (s *FakeService) Starts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.starts
+}
+
+// Stops returns how many times Stop was called.
+func (s *FakeService) Stops() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stops
+}
+
+// Ready is closed after the first successful Start.
+func (s *FakeService) Ready() <-chan struct{} {
+	return s.readyCh()
+}
+
+// ServiceFunc adapts a start function to the services.Service interface.
+type ServiceFunc func(ctx context.Context) error
+
+var _ services.Service = ServiceFunc(nil)
+
+// Start implements the services.Service interface.
+func (f ServiceFunc) Start(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Stop implements the services.Service interface.
+func (f ServiceFunc) Stop() error {
+	return nil
+}
+
+// Name implements the services.Service interface.
+func (f ServiceFunc) Name() string {
+	return "func"
+}
@@ -0,0 +1,301 @@
+package servicetest_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/services"
+	"github.com/forta-network/forta-node/services/servicetest"
+)
+
+func TestStartAndStop(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := services.NewMainContext()
+	defer mainCtx.Cancel()
+
+	first := servicetest.NewFakeService("first")
+	second := servicetest.NewFakeService("second")
+	second.StartDelay = time.Millisecond * 20
+	second.OnReady = func(ctx context.Context) {
+		name, _ := services.ServiceName(ctx)
+		r.Equal("second", name)
+	}
+
+	go func() {
+		<-second.Ready()
+		mainCtx.Cancel()
+	}()
+	r.NoError(services.StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []services.Service{first, second}))
+
+	for _, svc := range []*servicetest.FakeService{first, second} {
+		r.Equal(1, svc.Starts())
+		r.Equal(1, svc.Stops())
+	}
+}
+
+func TestStartError(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := services.NewMainContext()
+	defer mainCtx.Cancel()
+
+	startErr := errors.New("failed to start")
+	first := servicetest.NewFakeService("first")
+	failing := servicetest.NewFakeService("failing")
+	failing.StartErr = startErr
+	never := servicetest.NewFakeService("never")
+
+	err := services.StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []services.Service{first, failing, never})
+	r.ErrorIs(err, startErr)
+	r.Equal(1, first.Stops())
+	r.Equal(1, failing.Starts())
+	r.Equal(0, never.Starts())
+}
+
+func TestServiceFunc(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := services.NewMainContext()
+	defer mainCtx.Cancel()
+
+	var started bool
+	svc := servicetest.ServiceFunc(func(ctx context.Context) error {
+		started = true
+		time.AfterFunc(time.Millisecond*20, mainCtx.Cancel)
+		return nil
+	})
+	r.NoError(services.StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []services.Service{svc}))
+	r.True(started)
+}
+
+func TestFakeClockStartTimeout(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := services.NewMainContext()
+	defer mainCtx.Cancel()
+
+	clock := servicetest.NewFakeClock(time.Now())
+	slow := servicetest.NewFakeService("slow")
+	slow.StartDelay = time.Hour
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- services.StartServicesWithOptions(
+			mainCtx, logrus.NewEntry(logrus.StandardLogger()), []services.Service{slow},
+			services.Options{StartTimeout: time.Minute, Clock: clock},
+		)
+	}()
+
+	// the start timeout is the only timer until the service times out
+	clock.BlockUntil(1)
+	clock.Advance(time.Second * 59)
+	select {
+	case err := <-errCh:
+		r.FailNow("returned before the timeout", err)
+	case <-time.After(time.Millisecond * 20):
+	}
+
+	clock.Advance(time.Second)
+	select {
+	case err := <-errCh:
+		r.ErrorIs(err, services.ErrStartTimeout)
+		r.Contains(err.Error(), "after 1m0s")
+	case <-time.After(time.Second):
+		r.FailNow("start timeout did not fire")
+	}
+	r.Equal(1, slow.Starts())
+	r.Equal(0, slow.Stops())
+}
+
+func TestFakeClockUptime(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := services.NewMainContext()
+	defer mainCtx.Cancel()
+
+	startTime := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := servicetest.NewFakeClock(startTime)
+	scanner := servicetest.NewFakeService("scanner")
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- services.StartServicesWithOptions(
+			mainCtx, logrus.NewEntry(logrus.StandardLogger()), []services.Service{scanner},
+			services.Options{Clock: clock},
+		)
+	}()
+	<-scanner.Ready()
+	r.Eventually(func() bool {
+		status, _ := mainCtx.Statuses().Status("scanner")
+		return status.State == services.StateRunning
+	}, time.Second, time.Millisecond*10)
+
+	status, _ := mainCtx.Statuses().Status("scanner")
+	r.Equal(startTime, status.StartedAt)
+	r.True(status.StoppedAt.IsZero())
+
+	clock.Advance(time.Hour*3 + time.Minute*12)
+	status, _ = mainCtx.Statuses().Status("scanner")
+	r.Equal(time.Hour*3+time.Minute*12, status.Uptime(clock.Now()))
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+	status, _ = mainCtx.Statuses().Status("scanner")
+	r.Equal(services.StateStopped, status.State)
+	r.Equal(startTime.Add(time.Hour*3+time.Minute*12), status.StoppedAt)
+
+	// the uptime does not grow after the service is stopped
+	clock.Advance(time.Hour)
+	r.Equal(time.Hour*3+time.Minute*12, status.Uptime(clock.Now()))
+}
+
+type stuckService struct {
+	*servicetest.FakeService
+}
+
+func (s *stuckService) Healthy() error {
+	return errors.New("stuck")
+}
+
+func TestFakeClockHealthGracePeriod(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := services.NewMainContext()
+	defer mainCtx.Cancel()
+
+	startTime := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := servicetest.NewFakeClock(startTime)
+	scanner := &stuckService{FakeService: servicetest.NewFakeService("scanner")}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- services.StartServicesWithOptions(
+			mainCtx, logrus.NewEntry(logrus.StandardLogger()), []services.Service{scanner},
+			services.Options{
+				Clock:               clock,
+				StartTimeout:        time.Hour * 24,
+				HealthCheckInterval: time.Minute,
+				HealthGracePeriod:   time.Minute * 2,
+			},
+		)
+	}()
+	<-scanner.Ready()
+	status, _ := mainCtx.Statuses().Status("scanner")
+	r.Equal(startTime, status.UpdatedAt)
+
+	checkHealth := func() services.ServiceStatus {
+		// the start timeout and the health check are waiting
+		clock.BlockUntil(2)
+		clock.Advance(time.Minute)
+		var status services.ServiceStatus
+		r.Eventually(func() bool {
+			status, _ = mainCtx.Statuses().Status("scanner")
+			return status.HealthCheckedAt.Equal(clock.Now())
+		}, time.Second, time.Millisecond*10)
+		return status
+	}
+
+	// unhealthy within the grace period which starts from the first failed check
+	status = checkHealth()
+	r.Equal("stuck", status.HealthError)
+	r.False(status.Unhealthy)
+	for i := 0; i < 2; i++ {
+		r.False(checkHealth().Unhealthy)
+	}
+
+	// unhealthy for longer than the grace period
+	status = checkHealth()
+	r.True(status.Unhealthy)
+	r.Equal(startTime.Add(time.Minute*4), status.HealthCheckedAt)
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+}
+
+type drainingService struct {
+	*servicetest.FakeService
+	deadline chan time.Time
+}
+
+func (s *drainingService) Drain(ctx context.Context) error {
+	deadline, _ := ctx.Deadline()
+	s.deadline <- deadline
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestFakeClockDrainPeriod(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := services.NewMainContext()
+	defer mainCtx.Cancel()
+
+	startTime := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := servicetest.NewFakeClock(startTime)
+	publisher := &drainingService{FakeService: servicetest.NewFakeService("publisher"), deadline: make(chan time.Time, 1)}
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- services.StartServicesWithOptions(
+			mainCtx, logrus.NewEntry(logrus.StandardLogger()), []services.Service{publisher},
+			services.Options{Clock: clock, StartTimeout: time.Hour * 24, DrainPeriod: time.Minute},
+		)
+	}()
+	<-publisher.Ready()
+	mainCtx.Cancel()
+	r.Equal(startTime.Add(time.Minute), <-publisher.deadline)
+
+	// the service is stopped only after the drain period on the clock
+	select {
+	case err := <-errCh:
+		r.FailNow("stopped before the drain period", err)
+	case <-time.After(time.Millisecond * 20):
+	}
+	r.Equal(0, publisher.Stops())
+	clock.Advance(time.Minute)
+	select {
+	case err := <-errCh:
+		r.NoError(err)
+	case <-time.After(time.Second):
+		r.FailNow("the drain period did not end")
+	}
+	r.Equal(1, publisher.Stops())
+}
+
+func TestFakeClockWaitForReady(t *testing.T) {
+	r := require.New(t)
+
+	clock := servicetest.NewFakeClock(time.Now())
+	var checks int64
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- services.WaitForReady(context.Background(), func() error {
+			if atomic.AddInt64(&checks, 1) < 3 {
+				return errors.New("not ready")
+			}
+			return nil
+		}, services.ReadyPolicy{BaseInterval: time.Hour, MaxInterval: time.Hour * 2, Clock: clock})
+	}()
+
+	// the delays grow with the backoff and they are timed by the clock
+	clock.BlockUntil(1)
+	r.Equal(int64(1), atomic.LoadInt64(&checks))
+	clock.Advance(time.Hour)
+	clock.BlockUntil(1)
+	r.Equal(int64(2), atomic.LoadInt64(&checks))
+	clock.Advance(time.Hour * 2)
+	select {
+	case err := <-errCh:
+		r.NoError(err)
+	case <-time.After(time.Second):
+		r.FailNow("did not become ready")
+	}
+	r.Equal(int64(3), atomic.LoadInt64(&checks))
+}
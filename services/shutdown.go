@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultShutdownCallbackTimeout is how long the shutdown callbacks are given in total by default.
+const DefaultShutdownCallbackTimeout = time.Second * 10
+
+// ShutdownCallback flushes or cleans up the process-level state after the services are stopped.
+// The context is done when the shutdown callback timeout is reached.
+type ShutdownCallback func(ctx context.Context) error
+
+// OnShutdown registers a callback which runs after all of the services are stopped. The
+// callbacks run in the reverse registration order.
+func (mainCtx *MainContext) OnShutdown(callback ShutdownCallback) {
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	mainCtx.shutdownCallbacks = append(mainCtx.shutdownCallbacks, callback)
+}
+
+func (mainCtx *MainContext) getShutdownCallbacks() []ShutdownCallback {
+	mainCtx.mu.RLock()
+	defer mainCtx.mu.RUnlock()
+	callbacks := make([]ShutdownCallback, len(mainCtx.shutdownCallbacks))
+	copy(callbacks, mainCtx.shutdownCallbacks)
+	return callbacks
+}
+
+func (opts Options) shutdownCallbackTimeout() time.Duration {
+	if opts.ShutdownCallbackTimeout > 0 {
+		return opts.ShutdownCallbackTimeout
+	}
+	return DefaultShutdownCallbackTimeout
+}
+
+// runShutdownCallbacks runs the callbacks in the reverse order within the shutdown callback
+// timeout. If the timeout is reached, it returns without waiting for the rest of the callbacks.
+func (opts Options) runShutdownCallbacks(logger *log.Entry, callbacks []ShutdownCallback) {
+	if len(callbacks) == 0 {
+		return
+	}
+	timeout := opts.shutdownCallbackTimeout()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	timeoutCh := opts.clock().After(timeout)
+
+	for i := len(callbacks) - 1; i >= 0; i-- {
+		callback := callbacks[i]
+		errCh := make(chan error, 1)
+		go func() {
+			errCh <- callback(ctx)
+		}()
+		select {
+		case err := <-errCh:
+			if err != nil {
+				logger.WithError(err).Warn("shutdown callback failed")
+			}
+		case <-timeoutCh:
+			logger.WithFields(log.Fields{
+				"timeout": timeout.String(),
+				"pending": i + 1,
+			}).Error("shutdown callbacks did not finish before the timeout")
+			return
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShutdownCallbacks(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var calls []string
+	svc := &orderedService{name: "exporter", stopped: &calls}
+	mainCtx.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "first")
+		return nil
+	})
+	mainCtx.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "second")
+		return errors.New("failed to flush")
+	})
+
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	r.NoError(StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc}))
+	r.Equal([]string{"exporter", "second", "first"}, calls)
+}
+
+func TestShutdownCallbackTimeout(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	var calls []string
+	svc := &orderedService{name: "exporter", stopped: &calls}
+	mainCtx.OnShutdown(func(ctx context.Context) error {
+		calls = append(calls, "never")
+		return nil
+	})
+	hangingCtx := make(chan context.Context, 1)
+	release := make(chan struct{})
+	defer close(release)
+	mainCtx.OnShutdown(func(ctx context.Context) error {
+		hangingCtx <- ctx
+		<-release
+		return nil
+	})
+
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServicesWithOptions(
+			mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc},
+			Options{ShutdownCallbackTimeout: time.Millisecond * 50},
+		)
+	}()
+	select {
+	case err := <-errCh:
+		r.NoError(err)
+	case <-time.After(time.Second):
+		r.FailNow("hanging callback blocked the shutdown")
+	}
+	r.Equal([]string{"exporter"}, calls)
+	r.Error((<-hangingCtx).Err())
+}
@@ -0,0 +1,40 @@
+package services
+
+import (
+	"bytes"
+	"io"
+	"runtime/pprof"
+	"syscall"
+)
+
+// StackDumpSignal makes the goroutine stacks dumped without cancelling the main context.
+const StackDumpSignal = syscall.SIGUSR2
+
+// SetStackDumpWriter sets the writer which receives the goroutine stack dumps. The dumps
+// are logged by the status logger if no writer is set.
+func (mainCtx *MainContext) SetStackDumpWriter(w io.Writer) {
+	mainCtx.mu.Lock()
+	defer mainCtx.mu.Unlock()
+	mainCtx.stackDumpWriter = w
+}
+
+func (mainCtx *MainContext) dumpStacks() {
+	mainCtx.mu.RLock()
+	w, logger := mainCtx.stackDumpWriter, mainCtx.statusLogger
+	mainCtx.mu.RUnlock()
+
+	var buf bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buf, 2); err != nil {
+		logger.WithError(err).Error("failed to dump goroutine stacks")
+		return
+	}
+	if w == nil {
+		logger.WithField("stacks", buf.String()).Warn("goroutine stack dump")
+		return
+	}
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		logger.WithError(err).Error("failed to write goroutine stack dump")
+		return
+	}
+	logger.Info("dumped goroutine stacks")
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+type syncBuffer struct {
+	buf bytes.Buffer
+	mu  sync.Mutex
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestStackDumpSignal(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	var buf syncBuffer
+	mainCtx.SetStackDumpWriter(&buf)
+
+	mainCtx.sigc <- StackDumpSignal
+	r.Eventually(func() bool {
+		return len(buf.String()) > 0
+	}, time.Second, time.Millisecond*10)
+	r.Contains(buf.String(), "goroutine ")
+	r.Contains(buf.String(), "handleSignals")
+	r.NoError(mainCtx.Context().Err())
+}
+
+func TestStackDumpSignalLogged(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	logger, hook := test.NewNullLogger()
+	mainCtx.SetStatusLogger(logrus.NewEntry(logger))
+
+	mainCtx.sigc <- StackDumpSignal
+	r.Eventually(func() bool {
+		return hasLogEntry(hook, "goroutine stack dump")
+	}, time.Second, time.Millisecond*10)
+	stacks, _ := hook.LastEntry().Data["stacks"].(string)
+	r.True(strings.Contains(stacks, "goroutine "))
+	r.NoError(mainCtx.Context().Err())
+}
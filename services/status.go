@@ -0,0 +1,343 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ServiceState is the lifecycle state of a service.
+type ServiceState string
+
+// Service states
+const (
+	StatePending  ServiceState = "pending"
+	StateStarting ServiceState = "starting"
+	StateRunning  ServiceState = "running"
+	StateStopping ServiceState = "stopping"
+	StateStopped  ServiceState = "stopped"
+	StateFailed   ServiceState = "failed"
+)
+
+// StopReason tells why a service was stopped.
+type StopReason string
+
+// Stop reasons
+const (
+	// StopReasonShutdown is used when the services are shut down normally.
+	StopReasonShutdown StopReason = "shutdown"
+	// StopReasonServiceFailed is used when the services are shut down because another service failed.
+	StopReasonServiceFailed StopReason = "serviceFailed"
+	// StopReasonDependencyFailed is used when a service is not started because its dependency failed.
+	StopReasonDependencyFailed StopReason = "dependencyFailed"
+	// StopReasonUnhealthy is used when a service is restarted because it stayed unhealthy.
+	StopReasonUnhealthy StopReason = "unhealthy"
+	// StopReasonReload is used when a service is restarted because the reloaded config affects it.
+	StopReasonReload StopReason = "reload"
+	// StopReasonError is used when a service fails on its own.
+	StopReasonError StopReason = "error"
+)
+
+// ServiceStatus is the latest known status of a service.
+type ServiceStatus struct {
+	Name      string       `json:"name"`
+	State     ServiceState `json:"state"`
+	Optional  bool         `json:"optional,omitempty"`
+	Error     string       `json:"error,omitempty"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+	StartedAt time.Time    `json:"startedAt"`
+	StoppedAt time.Time    `json:"stoppedAt"`
+	// StopReason is the reason of the latest stop. It is kept after a service is restarted.
+	StopReason StopReason `json:"stopReason,omitempty"`
+
+	HealthCheckedAt time.Time `json:"healthCheckedAt"`
+	HealthError     string    `json:"healthError,omitempty"`
+	// Unhealthy is set when the service stays unhealthy for longer than the health grace period.
+	Unhealthy bool `json:"unhealthy,omitempty"`
+
+	// Details are filled in only by StatusesWithDetails.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// StatusDetailer is implemented by the services which can describe their internal state,
+// e.g. the current block, in the status. It is called concurrently with the service.
+type StatusDetailer interface {
+	StatusDetails() map[string]string
+}
+
+// StatusRegistry keeps the statuses of the services and is safe for concurrent use.
+type StatusRegistry struct {
+	statuses  []ServiceStatus
+	detailers map[string]StatusDetailer
+	// updated is closed and replaced whenever a service state changes
+	updated chan struct{}
+	// clock is the clock of the services which the statuses are updated by
+	clock Clock
+	mu    sync.RWMutex
+}
+
+// NewStatusRegistry creates a new status registry.
+func NewStatusRegistry() *StatusRegistry {
+	return &StatusRegistry{}
+}
+
+// setClock sets the clock which the update times are taken from.
+func (reg *StatusRegistry) setClock(clock Clock) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.clock = clock
+}
+
+// now returns the current time from the clock. It should be called with the lock.
+func (reg *StatusRegistry) now() time.Time {
+	if reg.clock != nil {
+		return reg.clock.Now()
+	}
+	return RealClock.Now()
+}
+
+// clockNow returns the current time from the clock.
+func (reg *StatusRegistry) clockNow() time.Time {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	return reg.now()
+}
+
+// Statuses returns the service statuses from the registry of the process main context.
+func Statuses() []ServiceStatus {
+	mainCtx := getProcessMainContext()
+	if mainCtx == nil {
+		return nil
+	}
+	return mainCtx.statuses.Statuses()
+}
+
+// Statuses returns a copy of the service statuses in the start order.
+func (reg *StatusRegistry) Statuses() []ServiceStatus {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	statuses := make([]ServiceStatus, len(reg.statuses))
+	copy(statuses, reg.statuses)
+	return statuses
+}
+
+// StatusesWithDetails returns the statuses with the details from the services which
+// implement the StatusDetailer interface.
+func (reg *StatusRegistry) StatusesWithDetails() []ServiceStatus {
+	statuses := reg.Statuses()
+	reg.mu.RLock()
+	detailers := reg.detailers
+	reg.mu.RUnlock()
+	// get the details outside of the lock so that the services can take their time
+	for i := range statuses {
+		if detailer, ok := detailers[statuses[i].Name]; ok {
+			statuses[i].Details = detailer.StatusDetails()
+		}
+	}
+	return statuses
+}
+
+// Status returns the status of a service.
+func (reg *StatusRegistry) Status(name string) (ServiceStatus, bool) {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+	for _, status := range reg.statuses {
+		if status.Name == name {
+			return status, true
+		}
+	}
+	return ServiceStatus{}, false
+}
+
+// reset makes all given services pending.
+func (reg *StatusRegistry) reset(services []Service) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	now := reg.now()
+	reg.statuses = make([]ServiceStatus, len(services))
+	reg.detailers = make(map[string]StatusDetailer)
+	for i, service := range services {
+		if detailer, ok := underlying(service).(StatusDetailer); ok {
+			reg.detailers[service.Name()] = detailer
+		}
+		reg.statuses[i] = ServiceStatus{
+			Name:      service.Name(),
+			State:     StatePending,
+			Optional:  isOptional(service),
+			UpdatedAt: now,
+		}
+	}
+	reg.notify()
+}
+
+// set updates the state of a service.
+func (reg *StatusRegistry) set(name string, state ServiceState, err error) {
+	reg.setAt(name, state, err, reg.clockNow())
+}
+
+// setAt updates the state of a service with the given update time.
+func (reg *StatusRegistry) setAt(name string, state ServiceState, err error, now time.Time) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	status := reg.get(name)
+	status.State = state
+	status.Error = ""
+	if err != nil {
+		status.Error = err.Error()
+	}
+	status.UpdatedAt = now
+	switch {
+	case state == StateRunning:
+		status.StartedAt = now
+		status.StoppedAt = time.Time{}
+	case (state == StateStopped || state == StateFailed) && !status.StartedAt.IsZero() && status.StoppedAt.IsZero():
+		status.StoppedAt = now
+	}
+	reg.notify()
+}
+
+// setStopReason records why the service is stopping or stopped.
+func (reg *StatusRegistry) setStopReason(name string, reason StopReason) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	reg.get(name).StopReason = reason
+}
+
+// setCancelledStopReasons records the reason for the services which were stopped by
+// cancelling their start and have no reason yet.
+func (reg *StatusRegistry) setCancelledStopReasons(reason StopReason) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	for i := range reg.statuses {
+		if reg.statuses[i].State == StateStopped && reg.statuses[i].StopReason == "" {
+			reg.statuses[i].StopReason = reason
+		}
+	}
+}
+
+// notify wakes up the waiters. It should be called with the lock.
+func (reg *StatusRegistry) notify() {
+	if reg.updated != nil {
+		close(reg.updated)
+		reg.updated = nil
+	}
+}
+
+// WaitReady blocks until all of the services are running. It returns an error if a service
+// fails or stops before all of them are running, or if the context is done first.
+func (reg *StatusRegistry) WaitReady(ctx context.Context) error {
+	for {
+		reg.mu.Lock()
+		ready, err := reg.checkReady()
+		if ready || err != nil {
+			reg.mu.Unlock()
+			return err
+		}
+		if reg.updated == nil {
+			reg.updated = make(chan struct{})
+		}
+		updated := reg.updated
+		reg.mu.Unlock()
+
+		select {
+		case <-updated:
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %v", ErrNotReady, ctx.Err())
+		}
+	}
+}
+
+// checkReady tells if all of the services are running. It should be called with the lock.
+func (reg *StatusRegistry) checkReady() (bool, error) {
+	ready := len(reg.statuses) > 0
+	for _, status := range reg.statuses {
+		if status.skipsReadiness() {
+			continue
+		}
+		switch status.State {
+		case StateRunning:
+		case StatePending, StateStarting:
+			ready = false
+		default:
+			err := fmt.Errorf("%w: service '%s' is %s", ErrNotReady, status.Name, status.State)
+			if status.Error != "" {
+				err = fmt.Errorf("%w: %s", err, status.Error)
+			}
+			return false, err
+		}
+	}
+	return ready, nil
+}
+
+// setHealth updates the last health check result of a service and tells if the service
+// is unhealthy for longer than the grace period.
+func (reg *StatusRegistry) setHealth(name string, err error, unhealthy bool) {
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+	status := reg.get(name)
+	status.HealthCheckedAt = reg.now()
+	status.HealthError = ""
+	if err != nil {
+		status.HealthError = err.Error()
+	}
+	status.Unhealthy = err != nil && unhealthy
+}
+
+// get finds the status of a service and adds it if it is not found. It should be called with the lock.
+func (reg *StatusRegistry) get(name string) *ServiceStatus {
+	for i := range reg.statuses {
+		if reg.statuses[i].Name == name {
+			return &reg.statuses[i]
+		}
+	}
+	reg.statuses = append(reg.statuses, ServiceStatus{Name: name})
+	return &reg.statuses[len(reg.statuses)-1]
+}
+
+// Uptime returns how long the service has been running, or how long it ran until it was
+// stopped. It returns zero if the service was never started.
+func (status ServiceStatus) Uptime(now time.Time) time.Duration {
+	switch {
+	case status.StartedAt.IsZero():
+		return 0
+	case !status.StoppedAt.IsZero():
+		return status.StoppedAt.Sub(status.StartedAt)
+	default:
+		return now.Sub(status.StartedAt)
+	}
+}
+
+// skipsReadiness tells if the service does not affect the readiness because it is an
+// optional service which failed to start.
+func (status ServiceStatus) skipsReadiness() bool {
+	return status.Optional && status.State == StateFailed
+}
+
+// logStatuses logs a snapshot of the service statuses with the uptimes at the given time.
+func logStatuses(logger *log.Entry, statuses []ServiceStatus, now time.Time) {
+	for _, status := range statuses {
+		fields := log.Fields{
+			"service": status.Name,
+			"state":   status.State,
+		}
+		if status.State == StateRunning {
+			fields["uptime"] = status.Uptime(now).Round(time.Second).String()
+		}
+		if status.Error != "" {
+			fields["error"] = status.Error
+		}
+		if status.State != StateRunning && status.StopReason != "" {
+			fields["stopReason"] = status.StopReason
+		}
+		if !status.HealthCheckedAt.IsZero() {
+			fields["health"] = "healthy"
+			if status.HealthError != "" {
+				fields["health"] = status.HealthError
+			}
+		}
+		logger.WithFields(fields).Info("service status")
+	}
+}
@@ -0,0 +1,269 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/require"
+)
+
+type statusRecordingService struct {
+	name     string
+	statuses *StatusRegistry
+	states   []ServiceState
+	startErr error
+}
+
+func (s *statusRecordingService) record() {
+	status, _ := s.statuses.Status(s.name)
+	s.states = append(s.states, status.State)
+}
+
+func (s *statusRecordingService) Start(ctx context.Context) error {
+	s.record()
+	return s.startErr
+}
+
+func (s *statusRecordingService) Stop() error {
+	s.record()
+	return nil
+}
+
+func (s *statusRecordingService) Name() string {
+	return s.name
+}
+
+func TestStatusTransitions(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	statuses := NewStatusRegistry()
+	svc := &statusRecordingService{name: "scanner", statuses: statuses}
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	r.NoError(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{svc}, Options{
+		Statuses: statuses,
+		OnStart: func(name string) {
+			svc.record()
+		},
+	}))
+	svc.record()
+
+	r.Equal([]ServiceState{StateStarting, StateRunning, StateStopping, StateStopped}, svc.states)
+}
+
+func TestStatusFailedAndPending(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	statuses := NewStatusRegistry()
+	svcs := []Service{
+		&statusRecordingService{name: "json-rpc", statuses: statuses, startErr: errors.New("bad config")},
+		&statusRecordingService{name: "scanner", statuses: statuses},
+	}
+	r.Error(StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), svcs, Options{
+		Statuses: statuses,
+	}))
+
+	result := statuses.Statuses()
+	r.Len(result, 2)
+	r.Equal("json-rpc", result[0].Name)
+	r.Equal(StateFailed, result[0].State)
+	r.Equal("bad config", result[0].Error)
+	r.Equal("scanner", result[1].Name)
+	r.Equal(StatePending, result[1].State)
+}
+
+func TestStatusDumpSignal(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+	logger, hook := test.NewNullLogger()
+	mainCtx.SetStatusLogger(logrus.NewEntry(logger))
+
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+			&flippingService{healthyPolls: 100},
+		}, Options{HealthCheckInterval: time.Millisecond * 10})
+	}()
+	r.Eventually(func() bool {
+		status, _ := mainCtx.Statuses().Status("flipping")
+		return !status.HealthCheckedAt.IsZero()
+	}, time.Second, time.Millisecond*10)
+
+	mainCtx.sigc <- syscall.SIGUSR1
+	r.Eventually(func() bool {
+		return hasLogEntry(hook, "service status")
+	}, time.Second, time.Millisecond*10)
+	entry := hook.LastEntry()
+	r.Equal("flipping", entry.Data["service"])
+	r.Equal(StateRunning, entry.Data["state"])
+	r.Equal("healthy", entry.Data["health"])
+	r.Contains(entry.Data, "uptime")
+	r.NoError(mainCtx.Context().Err())
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+}
+
+type gatedService struct {
+	name    string
+	release chan struct{}
+}
+
+func (s *gatedService) Start(ctx context.Context) error {
+	<-s.release
+	return nil
+}
+
+func (s *gatedService) Stop() error {
+	return nil
+}
+
+func (s *gatedService) Name() string {
+	return s.name
+}
+
+func TestWaitReady(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	first := &gatedService{name: "first", release: make(chan struct{})}
+	last := &gatedService{name: "last", release: make(chan struct{})}
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{first, last})
+	}()
+
+	readyCh := make(chan error, 1)
+	go func() {
+		readyCh <- mainCtx.WaitReady(context.Background())
+	}()
+
+	close(first.release)
+	select {
+	case err := <-readyCh:
+		r.FailNow("ready before the last service", err)
+	case <-time.After(time.Millisecond * 50):
+	}
+
+	close(last.release)
+	select {
+	case err := <-readyCh:
+		r.NoError(err)
+	case <-time.After(time.Millisecond * 100):
+		r.FailNow("not ready after the last service")
+	}
+
+	mainCtx.Cancel()
+	r.NoError(<-errCh)
+}
+
+func TestWaitReadyStartFailure(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	statuses := NewStatusRegistry()
+	readyCh := make(chan error, 1)
+	go func() {
+		readyCh <- statuses.WaitReady(context.Background())
+	}()
+
+	err := StartServicesWithOptions(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&failingService{name: "failing", startErr: errors.New("failed to listen")},
+	}, Options{Statuses: statuses})
+	r.Error(err)
+
+	err = <-readyCh
+	r.ErrorIs(err, ErrNotReady)
+	r.EqualError(err, "services are not ready: service 'failing' is failed: failed to listen")
+}
+
+func TestWaitReadyContextDone(t *testing.T) {
+	r := require.New(t)
+
+	statuses := NewStatusRegistry()
+	statuses.reset([]Service{&gatedService{name: "pending"}})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+	defer cancel()
+	err := statuses.WaitReady(ctx)
+	r.ErrorIs(err, ErrNotReady)
+	r.Contains(err.Error(), context.DeadlineExceeded.Error())
+}
+
+func TestServiceUptime(t *testing.T) {
+	r := require.New(t)
+
+	now := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	statuses := NewStatusRegistry()
+	statuses.reset([]Service{&gatedService{name: "scanner"}})
+	status, _ := statuses.Status("scanner")
+	r.Zero(status.Uptime(now))
+
+	statuses.setAt("scanner", StateRunning, nil, now.Add(-time.Hour))
+	status, _ = statuses.Status("scanner")
+	r.Equal(time.Hour, status.Uptime(now))
+
+	statuses.setAt("scanner", StateStopping, nil, now)
+	statuses.setAt("scanner", StateStopped, nil, now.Add(time.Minute))
+	status, _ = statuses.Status("scanner")
+	r.Equal(time.Hour+time.Minute, status.Uptime(now.Add(time.Hour)))
+
+	// restarting clears the stop time
+	statuses.setAt("scanner", StateRunning, nil, now.Add(time.Hour))
+	status, _ = statuses.Status("scanner")
+	r.True(status.StoppedAt.IsZero())
+	r.Equal(time.Minute, status.Uptime(now.Add(time.Hour+time.Minute)))
+}
+
+func TestStopReasonShutdown(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	time.AfterFunc(time.Millisecond*50, mainCtx.Cancel)
+	r.NoError(StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&failingService{name: "json-rpc"},
+		&failingService{name: "scanner"},
+	}))
+	for _, name := range []string{"json-rpc", "scanner"} {
+		status, _ := mainCtx.Statuses().Status(name)
+		r.Equal(StateStopped, status.State)
+		r.Equal(StopReasonShutdown, status.StopReason, name)
+	}
+}
+
+func TestStopReasonServiceFailure(t *testing.T) {
+	r := require.New(t)
+
+	mainCtx := NewMainContext()
+	defer mainCtx.Cancel()
+
+	runErr := errors.New("lost the connection")
+	r.ErrorIs(StartServices(mainCtx, logrus.NewEntry(logrus.StandardLogger()), []Service{
+		&failingService{name: "publisher"},
+		&runFailingService{name: "scanner", runErr: runErr},
+	}), runErr)
+
+	status, _ := mainCtx.Statuses().Status("scanner")
+	r.Equal(StopReasonError, status.StopReason)
+	status, _ = mainCtx.Statuses().Status("publisher")
+	r.Equal(StateStopped, status.State)
+	r.Equal(StopReasonServiceFailed, status.StopReason)
+}
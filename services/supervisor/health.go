@@ -18,17 +18,18 @@ const defaultHealthCheckInterval = time.Second * 5
 const maxAttempts = 10
 
 func (sup *SupervisorService) healthCheck() {
-	ticker := time.NewTicker(defaultHealthCheckInterval)
+	timer := sup.getClock().NewTimer(defaultHealthCheckInterval)
 	for {
 		select {
 		case <-sup.ctx.Done():
-			ticker.Stop()
+			timer.Stop()
 			return
 
-		case <-ticker.C:
+		case <-timer.C():
 			if err := sup.doHealthCheck(); err != nil {
 				log.Errorf("failed to do health check: %v", err)
 			}
+			timer.Reset(defaultHealthCheckInterval)
 		}
 	}
 }
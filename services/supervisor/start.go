@@ -69,6 +69,8 @@ type SupervisorService struct {
 	agentLogsClient agentlogs.Client
 	prevAgentLogs   agentlogs.Agents
 	inspectionCh    chan *protocol.InspectionResults
+
+	clock services.Clock
 }
 
 type SupervisorServiceConfig struct {
@@ -209,6 +211,7 @@ func (sup *SupervisorService) start() error {
 			Name:  config.DockerJSONRPCProxyContainerName,
 			Image: commonNodeImage,
 			Cmd:   []string{config.DefaultFortaNodeBinaryPath, "json-rpc"},
+			Env:   sup.nodeContainerEnv(nil),
 			Volumes: map[string]string{
 				// give access to host docker
 				"/var/run/docker.sock": "/var/run/docker.sock",
@@ -245,6 +248,7 @@ func (sup *SupervisorService) start() error {
 			Name:  config.DockerInspectorContainerName,
 			Image: commonNodeImage,
 			Cmd:   []string{config.DefaultFortaNodeBinaryPath, "inspector"},
+			Env:   sup.nodeContainerEnv(nil),
 			Volumes: map[string]string{
 				hostFortaDir: config.DefaultContainerFortaDirPath,
 			},
@@ -278,9 +282,9 @@ func (sup *SupervisorService) start() error {
 			Name:  config.DockerScannerContainerName,
 			Image: commonNodeImage,
 			Cmd:   []string{config.DefaultFortaNodeBinaryPath, "scanner"},
-			Env: map[string]string{
+			Env: sup.nodeContainerEnv(map[string]string{
 				config.EnvReleaseInfo: releaseInfo.String(),
-			},
+			}),
 			Volumes: map[string]string{
 				hostFortaDir: config.DefaultContainerFortaDirPath,
 			},
@@ -307,9 +311,9 @@ func (sup *SupervisorService) start() error {
 			Name:  config.DockerJWTProviderContainerName,
 			Image: commonNodeImage,
 			Cmd:   []string{config.DefaultFortaNodeBinaryPath, "jwt-provider"},
-			Env: map[string]string{
+			Env: sup.nodeContainerEnv(map[string]string{
 				config.EnvReleaseInfo: releaseInfo.String(),
-			},
+			}),
 			Volumes: map[string]string{
 				// give access to host docker
 				"/var/run/docker.sock": "/var/run/docker.sock",
@@ -651,5 +655,24 @@ func NewSupervisorService(ctx context.Context, cfg SupervisorServiceConfig) (*Su
 		healthClient:     health.NewClient(),
 		agentLogsClient:  agentlogs.NewClient(cfg.Config.AgentLogsConfig.URL),
 		inspectionCh:     make(chan *protocol.InspectionResults),
+		clock:            services.RealClock,
 	}, nil
 }
+
+func (sup *SupervisorService) getClock() services.Clock {
+	if sup.clock != nil {
+		return sup.clock
+	}
+	return services.RealClock
+}
+
+// nodeContainerEnv adds the env vars which are common to the node containers.
+func (sup *SupervisorService) nodeContainerEnv(env map[string]string) map[string]string {
+	if env == nil {
+		env = make(map[string]string)
+	}
+	if execID, ok := services.ExecIDOk(sup.ctx); ok {
+		env[config.EnvExecID] = execID
+	}
+	return env
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+	"github.com/goccy/go-json"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+const ensCacheFileName = "ens-cache.json"
+
+type ensCache struct {
+	ENSAddress string                     `json:"ensAddress"`
+	Contracts  registry.RegistryContracts `json:"contracts"`
+	UpdatedAt  time.Time                  `json:"updatedAt"`
+}
+
+// cachingENSStore caches the resolved contracts to disk and falls back to the cached
+// contracts when the resolution fails.
+type cachingENSStore struct {
+	ens.ENS
+	ensAddress string
+	cachePath  string
+}
+
+// NewCachingENSStore wraps the ENS store so that the contracts resolved from the ENS contract
+// at the given address are cached in the Forta dir.
+func NewCachingENSStore(cfg config.Config, ensStore ens.ENS, ensAddress string) *cachingENSStore {
+	return &cachingENSStore{
+		ENS:        ensStore,
+		ensAddress: ensAddress,
+		cachePath:  path.Join(cfg.FortaDir, ensCacheFileName),
+	}
+}
+
+func (store *cachingENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	contracts, err := store.ENS.ResolveRegistryContracts()
+	if err == nil {
+		if err := store.writeCache(contracts); err != nil {
+			log.WithError(err).Warn("failed to write the ens cache")
+		}
+		return contracts, nil
+	}
+
+	cache, cacheErr := store.readCache()
+	if cacheErr != nil {
+		log.WithError(cacheErr).Debug("could not use the ens cache")
+		return nil, err
+	}
+	log.WithError(err).WithField("cachedAt", cache.UpdatedAt.Format(time.RFC3339)).Warn(
+		"failed to resolve the contracts - using the cached contracts",
+	)
+	return &cache.Contracts, nil
+}
+
+func (store *cachingENSStore) writeCache(contracts *registry.RegistryContracts) error {
+	b, err := json.Marshal(&ensCache{
+		ENSAddress: store.ensAddress,
+		Contracts:  *contracts,
+		UpdatedAt:  time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(store.cachePath, b, 0644)
+}
+
+func (store *cachingENSStore) readCache() (*ensCache, error) {
+	b, err := ioutil.ReadFile(store.cachePath)
+	if err != nil {
+		return nil, err
+	}
+	var cache ensCache
+	if err := json.Unmarshal(b, &cache); err != nil {
+		return nil, err
+	}
+	// the cache is invalid for a different ENS contract
+	if !strings.EqualFold(cache.ENSAddress, store.ensAddress) {
+		return nil, fmt.Errorf("cache is for ens contract '%s'", cache.ENSAddress)
+	}
+	return &cache, nil
+}
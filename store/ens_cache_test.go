@@ -0,0 +1,101 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+const (
+	testENSAddress1 = "0x08f42fcc52a9C2F391bF507C4E8688D0b53e1bd7"
+	testENSAddress2 = "0x1111111111111111111111111111111111111111"
+)
+
+var testContracts = registry.RegistryContracts{
+	Dispatch:           common.HexToAddress("0x2222222222222222222222222222222222222222"),
+	AgentRegistry:      common.HexToAddress("0x3333333333333333333333333333333333333333"),
+	ScannerRegistry:    common.HexToAddress("0x4444444444444444444444444444444444444444"),
+	ScannerNodeVersion: common.HexToAddress("0x5555555555555555555555555555555555555555"),
+	FortaStaking:       common.HexToAddress("0x6666666666666666666666666666666666666666"),
+	Forta:              common.HexToAddress("0x7777777777777777777777777777777777777777"),
+}
+
+type fakeENSStore struct {
+	contracts *registry.RegistryContracts
+	err       error
+	failures  int
+	calls     int
+}
+
+func (store *fakeENSStore) Resolve(input string) (common.Address, error) {
+	return common.Address{}, errors.New("not implemented")
+}
+
+func (store *fakeENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	store.calls++
+	if store.err != nil && (store.failures == 0 || store.calls <= store.failures) {
+		return nil, store.err
+	}
+	return store.contracts, nil
+}
+
+func TestENSCacheWrite(t *testing.T) {
+	r := require.New(t)
+
+	cfg := config.Config{FortaDir: t.TempDir()}
+	fake := &fakeENSStore{contracts: &testContracts}
+	store := NewCachingENSStore(cfg, fake, testENSAddress1)
+
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(testContracts, *contracts)
+
+	cache, err := store.readCache()
+	r.NoError(err)
+	r.Equal(testENSAddress1, cache.ENSAddress)
+	r.Equal(testContracts, cache.Contracts)
+	r.False(cache.UpdatedAt.IsZero())
+}
+
+func TestENSCacheFallback(t *testing.T) {
+	r := require.New(t)
+
+	cfg := config.Config{FortaDir: t.TempDir()}
+	fake := &fakeENSStore{contracts: &testContracts}
+	store := NewCachingENSStore(cfg, fake, testENSAddress1)
+	_, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+
+	fake.err = errors.New("rpc is down")
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(testContracts, *contracts)
+}
+
+func TestENSCacheInvalidatedByENSAddress(t *testing.T) {
+	r := require.New(t)
+
+	cfg := config.Config{FortaDir: t.TempDir()}
+	fake := &fakeENSStore{contracts: &testContracts}
+	_, err := NewCachingENSStore(cfg, fake, testENSAddress1).ResolveRegistryContracts()
+	r.NoError(err)
+
+	resolveErr := errors.New("rpc is down")
+	fake.err = resolveErr
+	_, err = NewCachingENSStore(cfg, fake, testENSAddress2).ResolveRegistryContracts()
+	r.ErrorIs(err, resolveErr)
+}
+
+func TestENSCacheMissing(t *testing.T) {
+	r := require.New(t)
+
+	resolveErr := errors.New("rpc is down")
+	fake := &fakeENSStore{err: resolveErr}
+	_, err := NewCachingENSStore(config.Config{FortaDir: t.TempDir()}, fake, testENSAddress1).ResolveRegistryContracts()
+	r.ErrorIs(err, resolveErr)
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ErrENSChainMismatch is used when an ENS endpoint is not on the expected chain.
+var ErrENSChainMismatch = errors.New("ens endpoint is on an unexpected chain")
+
+// checkENSChainID makes sure that the endpoint is on the expected chain so that the
+// contracts are not resolved from a wrong network.
+func checkENSChainID(ctx context.Context, url string, expectedChainID int) error {
+	client, err := ethclient.DialContext(ctx, url)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	chainID, err := client.ChainID(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get the chain ID: %v", err)
+	}
+	if !chainID.IsInt64() || chainID.Int64() != int64(expectedChainID) {
+		return fmt.Errorf("%w: expected %d, got %s", ErrENSChainMismatch, expectedChainID, chainID)
+	}
+	return nil
+}
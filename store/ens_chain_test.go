@@ -0,0 +1,99 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/ens"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+// newChainIDServer creates a JSON-RPC endpoint which responds to eth_chainId with the chain ID.
+func newChainIDServer(t *testing.T, chainID int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     json.RawMessage `json:"id"`
+			Method string          `json:"method"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Method != "eth_chainId" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"jsonrpc":"2.0","id":%s,"result":"0x%x"}`, req.ID, chainID)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCheckENSChainID(t *testing.T) {
+	r := require.New(t)
+
+	server := newChainIDServer(t, 137)
+	r.NoError(checkENSChainID(context.Background(), server.URL, 137))
+
+	err := checkENSChainID(context.Background(), server.URL, 1)
+	r.ErrorIs(err, ErrENSChainMismatch)
+	r.Contains(err.Error(), "expected 1, got 137")
+}
+
+func TestDialFailoverENSStoreChecksChainID(t *testing.T) {
+	r := require.New(t)
+
+	polygon := newChainIDServer(t, 137)
+	goerli := newChainIDServer(t, 5)
+
+	store := dialFailoverENSStore(context.Background(), testDeadline(time.Second), 137, testENSAddress1, goerli.URL)
+	_, err := store.ResolveRegistryContracts()
+	var resolutionErr *ContractResolutionError
+	r.ErrorAs(err, &resolutionErr)
+	r.Equal(goerli.URL, resolutionErr.Endpoint)
+	r.Equal(ResolutionStageChainID, resolutionErr.Stage)
+	r.ErrorIs(err, ErrENSChainMismatch)
+
+	// the endpoint on the wrong chain is skipped
+	var dialed []string
+	store = dialFailoverENSStore(context.Background(), testDeadline(time.Second), 137, testENSAddress1, goerli.URL, polygon.URL)
+	store.dial = func(url, ensAddress string) (ens.ENS, error) {
+		dialed = append(dialed, url)
+		return &fakeENSStore{contracts: &testContracts}, nil
+	}
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(testContracts, *contracts)
+	r.Equal([]string{polygon.URL}, dialed)
+}
+
+func TestDialFailoverENSStoreSkipsUnreachableEndpoint(t *testing.T) {
+	r := require.New(t)
+
+	unreachable := newChainIDServer(t, 137)
+	unreachable.Close()
+	polygon := newChainIDServer(t, 137)
+
+	var dialed []string
+	store := dialFailoverENSStore(context.Background(), testDeadline(time.Second), 137, testENSAddress1, unreachable.URL, polygon.URL)
+	store.dial = func(url, ensAddress string) (ens.ENS, error) {
+		dialed = append(dialed, url)
+		return &fakeENSStore{contracts: &testContracts}, nil
+	}
+
+	failures := testutil.ToFloat64(ensResolutionFailures.WithLabelValues(ResolutionStageChainID))
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(testContracts, *contracts)
+	r.Equal([]string{polygon.URL}, dialed)
+	r.Equal(failures+1, testutil.ToFloat64(ensResolutionFailures.WithLabelValues(ResolutionStageChainID)))
+
+	// the dialed endpoint is reused and the unreachable one is checked again
+	_, err = store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal([]string{polygon.URL}, dialed)
+	r.Equal(failures+2, testutil.ToFloat64(ensResolutionFailures.WithLabelValues(ResolutionStageChainID)))
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"sync"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// contractsOverridingENSStore uses the contract addresses from the config and resolves
+// the rest from ENS. ENS is dialed only if some of the contracts are not overridden.
+type contractsOverridingENSStore struct {
+	overrides map[string]common.Address
+	dial      func() (ens.ENS, error)
+
+	ensStore ens.ENS
+	mu       sync.Mutex
+}
+
+func newContractsOverridingENSStore(
+	contractsCfg config.ContractsConfig, dial func() (ens.ENS, error),
+) (*contractsOverridingENSStore, error) {
+	overrides := make(map[string]common.Address)
+	for name, address := range map[string]string{
+		ens.DispatchContract:           contractsCfg.Dispatch,
+		ens.AgentRegistryContract:      contractsCfg.AgentRegistry,
+		ens.ScannerRegistryContract:    contractsCfg.ScannerRegistry,
+		ens.ScannerNodeVersionContract: contractsCfg.ScannerNodeVersion,
+		ens.StakingContract:            contractsCfg.FortaStaking,
+		ens.FortaContract:              contractsCfg.Forta,
+	} {
+		if len(address) == 0 {
+			continue
+		}
+		parsed, err := parseContractAddress(name, address)
+		if err != nil {
+			return nil, err
+		}
+		overrides[name] = parsed
+	}
+	return &contractsOverridingENSStore{overrides: overrides, dial: dial}, nil
+}
+
+func (store *contractsOverridingENSStore) getENSStore() (ens.ENS, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.ensStore != nil {
+		return store.ensStore, nil
+	}
+	ensStore, err := store.dial()
+	if err != nil {
+		return nil, err
+	}
+	store.ensStore = ensStore
+	return ensStore, nil
+}
+
+func (store *contractsOverridingENSStore) Resolve(input string) (common.Address, error) {
+	if address, ok := store.overrides[input]; ok {
+		return address, nil
+	}
+	ensStore, err := store.getENSStore()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return ensStore.Resolve(input)
+}
+
+func (store *contractsOverridingENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	var contracts registry.RegistryContracts
+	fields := registryContractFields(&contracts)
+	if len(store.overrides) < len(fields) {
+		ensStore, err := store.getENSStore()
+		if err != nil {
+			return nil, err
+		}
+		resolved, err := ensStore.ResolveRegistryContracts()
+		if err != nil {
+			return nil, err
+		}
+		contracts = *resolved
+	}
+	for name, address := range store.overrides {
+		*fields[name] = address
+	}
+	return &contracts, nil
+}
+
+// registryContractFields maps the ENS names to the registry contract fields.
+func registryContractFields(contracts *registry.RegistryContracts) map[string]*common.Address {
+	return map[string]*common.Address{
+		ens.DispatchContract:           &contracts.Dispatch,
+		ens.AgentRegistryContract:      &contracts.AgentRegistry,
+		ens.ScannerRegistryContract:    &contracts.ScannerRegistry,
+		ens.ScannerNodeVersionContract: &contracts.ScannerNodeVersion,
+		ens.StakingContract:            &contracts.FortaStaking,
+		ens.FortaContract:              &contracts.Forta,
+	}
+}
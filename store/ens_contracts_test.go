@@ -0,0 +1,78 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/ens"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+var testContractsConfig = config.ContractsConfig{
+	Dispatch:           "0x8888888888888888888888888888888888888888",
+	AgentRegistry:      "0x9999999999999999999999999999999999999999",
+	ScannerRegistry:    "0xaAaAaAaaAaAaAaaAaAAAAAAAAaaaAaAaAaaAaaAa",
+	ScannerNodeVersion: "0xbBbBBBBbbBBBbbbBbbBbbbbBBbBbbbbBbBbbBBbB",
+	FortaStaking:       "0xcCcCCCCCcCCCcCCCCcccCCcCCcCCCCccCccCCcCc",
+	Forta:              "0xdDdDddDdDdddDDddDDddDDDDdDdDDdDDdDDDDDDd",
+}
+
+type countingDialer struct {
+	ensStore ens.ENS
+	dials    int
+}
+
+func (dialer *countingDialer) dial() (ens.ENS, error) {
+	dialer.dials++
+	return dialer.ensStore, nil
+}
+
+func TestContractsFullOverride(t *testing.T) {
+	r := require.New(t)
+
+	dialer := &countingDialer{ensStore: &fakeENSStore{err: errors.New("should not be called")}}
+	store, err := newContractsOverridingENSStore(testContractsConfig, dialer.dial)
+	r.NoError(err)
+
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(common.HexToAddress(testContractsConfig.Dispatch), contracts.Dispatch)
+	r.Equal(common.HexToAddress(testContractsConfig.Forta), contracts.Forta)
+	address, err := store.Resolve(ens.AgentRegistryContract)
+	r.NoError(err)
+	r.Equal(common.HexToAddress(testContractsConfig.AgentRegistry), address)
+	r.Equal(0, dialer.dials)
+}
+
+func TestContractsPartialOverride(t *testing.T) {
+	r := require.New(t)
+
+	dialer := &countingDialer{ensStore: &fakeENSStore{contracts: &testContracts}}
+	store, err := newContractsOverridingENSStore(config.ContractsConfig{
+		Dispatch: testContractsConfig.Dispatch,
+	}, dialer.dial)
+	r.NoError(err)
+
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(common.HexToAddress(testContractsConfig.Dispatch), contracts.Dispatch)
+	r.Equal(testContracts.AgentRegistry, contracts.AgentRegistry)
+	r.Equal(testContracts.ScannerNodeVersion, contracts.ScannerNodeVersion)
+	r.Equal(1, dialer.dials)
+}
+
+func TestContractsNoOverride(t *testing.T) {
+	r := require.New(t)
+
+	dialer := &countingDialer{ensStore: &fakeENSStore{contracts: &testContracts}}
+	store, err := newContractsOverridingENSStore(config.ContractsConfig{}, dialer.dial)
+	r.NoError(err)
+
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(testContracts, *contracts)
+	r.Equal(1, dialer.dials)
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Contract resolution stages
+const (
+	ResolutionStageDial    = "dial"
+	ResolutionStageChainID = "chain-id"
+	ResolutionStageCall    = "call"
+	ResolutionStageDecode  = "decode"
+)
+
+// ContractResolutionError is returned when the registry contracts cannot be resolved.
+type ContractResolutionError struct {
+	Endpoint   string
+	ENSAddress string
+	Stage      string
+	Err        error
+}
+
+func (err *ContractResolutionError) Error() string {
+	var details []string
+	if len(err.Endpoint) > 0 {
+		details = append(details, fmt.Sprintf("endpoint: %s", err.Endpoint))
+	}
+	if len(err.ENSAddress) > 0 {
+		details = append(details, fmt.Sprintf("ens: %s", err.ENSAddress))
+	}
+	msg := fmt.Sprintf("failed to resolve contracts at %s stage", err.Stage)
+	if len(details) > 0 {
+		msg = fmt.Sprintf("%s (%s)", msg, strings.Join(details, ", "))
+	}
+	return fmt.Sprintf("%s: %v", msg, err.Err)
+}
+
+// Unwrap returns the underlying error.
+func (err *ContractResolutionError) Unwrap() error {
+	return err.Err
+}
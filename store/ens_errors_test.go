@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestContractResolutionErrorFromCall(t *testing.T) {
+	r := require.New(t)
+
+	callErr := errors.New("bad gateway")
+	store := newFailoverENSStore(context.Background(), testDeadline(time.Second), testENSAddress1,
+		ensEndpoint{url: "http://first", store: &fakeENSStore{err: callErr}},
+	)
+
+	_, err := store.ResolveRegistryContracts()
+	var resolutionErr *ContractResolutionError
+	r.ErrorAs(err, &resolutionErr)
+	r.Equal("http://first", resolutionErr.Endpoint)
+	r.Equal(testENSAddress1, resolutionErr.ENSAddress)
+	r.Equal(ResolutionStageCall, resolutionErr.Stage)
+	r.ErrorIs(err, callErr)
+	r.EqualError(err, "failed to resolve contracts at call stage (endpoint: http://first, ens: "+testENSAddress1+"): bad gateway")
+}
+
+func TestContractResolutionErrorFromDial(t *testing.T) {
+	r := require.New(t)
+
+	store := dialFailoverENSStore(context.Background(), testDeadline(time.Second), 0, testENSAddress1, "unknown://endpoint")
+	_, err := store.ResolveRegistryContracts()
+	var resolutionErr *ContractResolutionError
+	r.ErrorAs(err, &resolutionErr)
+	r.Equal("unknown://endpoint", resolutionErr.Endpoint)
+	r.Equal(ResolutionStageDial, resolutionErr.Stage)
+}
@@ -0,0 +1,215 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	log "github.com/sirupsen/logrus"
+)
+
+var errENSEndpointTimeout = errors.New("ens resolution timed out")
+
+// ensResolutionFailures counts the failed dials and calls to the ENS endpoints.
+var ensResolutionFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "forta",
+	Name:      "ens_resolution_failures_total",
+	Help:      "How many times resolving the contracts from an ENS endpoint failed.",
+}, []string{"stage"})
+
+type ensEndpoint struct {
+	url   string
+	store ens.ENS
+}
+
+// failoverENSStore tries the endpoints in order until one of them succeeds or the context is done.
+// The endpoints without a store are checked and dialed when they are tried, so that an endpoint
+// which is down or on a wrong chain does not prevent using the others.
+type failoverENSStore struct {
+	ctx             context.Context
+	ensAddress      string
+	endpoints       []ensEndpoint
+	deadline        deadlineFunc
+	expectedChainID int
+	dial            func(url, ensAddress string) (ens.ENS, error)
+	// mu is shared by the copies which are made for the calls
+	mu *sync.Mutex
+}
+
+func newFailoverENSStore(
+	ctx context.Context, deadline deadlineFunc, ensAddress string, endpoints ...ensEndpoint,
+) *failoverENSStore {
+	return &failoverENSStore{
+		ctx:        ctx,
+		ensAddress: ensAddress,
+		endpoints:  endpoints,
+		deadline:   deadline,
+		dial:       dialENSEndpoint,
+		mu:         &sync.Mutex{},
+	}
+}
+
+// withContext returns a copy of the store which stops trying the endpoints when the context
+// is done. The copy shares the dialed endpoints with the store.
+func (store *failoverENSStore) withContext(ctx context.Context) *failoverENSStore {
+	withCtx := *store
+	withCtx.ctx = ctx
+	return &withCtx
+}
+
+func dialENSEndpoint(url, ensAddress string) (ens.ENS, error) {
+	ensStore, err := ens.DialENSStoreAt(url, ensAddress)
+	if err != nil {
+		return nil, err
+	}
+	return ensStore, nil
+}
+
+// dialFailoverENSStore creates a store which dials the endpoints when they are tried. The chain ID
+// of each endpoint is checked before dialing if the expected chain ID is not zero.
+func dialFailoverENSStore(
+	ctx context.Context, deadline deadlineFunc, expectedChainID int, ensAddress string, urls ...string,
+) *failoverENSStore {
+	var endpoints []ensEndpoint
+	for _, url := range urls {
+		endpoints = append(endpoints, ensEndpoint{url: url})
+	}
+	store := newFailoverENSStore(ctx, deadline, ensAddress, endpoints...)
+	store.expectedChainID = expectedChainID
+	return store
+}
+
+// endpointStore returns the store of the endpoint and dials it first if needed.
+func (store *failoverENSStore) endpointStore(i int) (ens.ENS, error) {
+	store.mu.Lock()
+	endpoint := store.endpoints[i]
+	store.mu.Unlock()
+	if endpoint.store != nil {
+		return endpoint.store, nil
+	}
+
+	if store.expectedChainID > 0 {
+		ctx, cancel := store.deadline(store.ctx)
+		err := checkENSChainID(ctx, endpoint.url, store.expectedChainID)
+		cancel()
+		if err != nil {
+			return nil, &ContractResolutionError{
+				Endpoint:   endpoint.url,
+				ENSAddress: store.ensAddress,
+				Stage:      ResolutionStageChainID,
+				Err:        err,
+			}
+		}
+	}
+	ensStore, err := store.dial(endpoint.url, store.ensAddress)
+	if err != nil {
+		return nil, &ContractResolutionError{
+			Endpoint:   endpoint.url,
+			ENSAddress: store.ensAddress,
+			Stage:      ResolutionStageDial,
+			Err:        err,
+		}
+	}
+
+	store.mu.Lock()
+	store.endpoints[i].store = ensStore
+	store.mu.Unlock()
+	return ensStore, nil
+}
+
+func (store *failoverENSStore) Resolve(input string) (common.Address, error) {
+	result, err := store.try(func(ensStore ens.ENS) (interface{}, error) {
+		return ensStore.Resolve(input)
+	})
+	if err != nil {
+		return common.Address{}, err
+	}
+	return result.(common.Address), nil
+}
+
+func (store *failoverENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	result, err := store.try(func(ensStore ens.ENS) (interface{}, error) {
+		return ensStore.ResolveRegistryContracts()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*registry.RegistryContracts), nil
+}
+
+type ensResult struct {
+	value interface{}
+	err   error
+}
+
+// try calls the endpoints in order and returns the last error if all of them fail.
+func (store *failoverENSStore) try(call func(ensStore ens.ENS) (interface{}, error)) (interface{}, error) {
+	err := errors.New("no ens endpoints")
+	for i := range store.endpoints {
+		// only the store of the endpoint is updated after dialing
+		url := store.endpoints[i].url
+		logger := log.WithField("endpoint", url)
+		var ensStore ens.ENS
+		ensStore, err = store.endpointStore(i)
+		if err != nil {
+			logger.WithError(err).Warn("skipping ens endpoint")
+			ensResolutionFailures.WithLabelValues(err.(*ContractResolutionError).Stage).Inc()
+			if store.ctx.Err() != nil {
+				break
+			}
+			continue
+		}
+		var value interface{}
+		value, err = store.callWithTimeout(ensStore, call)
+		if err != nil {
+			err = &ContractResolutionError{
+				Endpoint:   url,
+				ENSAddress: store.ensAddress,
+				Stage:      ResolutionStageCall,
+				Err:        err,
+			}
+		}
+		if err == nil {
+			if len(store.endpoints) > 1 {
+				logger.Info("resolved from ens endpoint")
+			}
+			return value, nil
+		}
+		logger.WithError(err).Warn("failed to resolve from ens endpoint")
+		ensResolutionFailures.WithLabelValues(ResolutionStageCall).Inc()
+		if store.ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+func (store *failoverENSStore) callWithTimeout(
+	ensStore ens.ENS, call func(ensStore ens.ENS) (interface{}, error),
+) (interface{}, error) {
+	begin := time.Now()
+	ctx, cancel := store.deadline(store.ctx)
+	defer cancel()
+
+	resultCh := make(chan ensResult, 1)
+	go func() {
+		value, err := call(ensStore)
+		resultCh <- ensResult{value: value, err: err}
+	}()
+	select {
+	case result := <-resultCh:
+		return result.value, result.err
+	case <-ctx.Done():
+		if store.ctx.Err() != nil {
+			return nil, fmt.Errorf("ens resolution aborted: %w", store.ctx.Err())
+		}
+		return nil, fmt.Errorf("%w after %s", errENSEndpointTimeout, timeoutOf(ctx, begin))
+	}
+}
@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+)
+
+type hangingENSStore struct {
+	release chan struct{}
+}
+
+func (store *hangingENSStore) Resolve(input string) (common.Address, error) {
+	<-store.release
+	return common.Address{}, nil
+}
+
+func (store *hangingENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	<-store.release
+	return &registry.RegistryContracts{}, nil
+}
+
+func TestENSFailoverToSecondEndpoint(t *testing.T) {
+	r := require.New(t)
+
+	first := &fakeENSStore{err: errors.New("bad gateway")}
+	second := &fakeENSStore{contracts: &testContracts}
+	store := newFailoverENSStore(context.Background(), testDeadline(time.Second), testENSAddress1,
+		ensEndpoint{url: "http://first", store: first},
+		ensEndpoint{url: "http://second", store: second},
+	)
+
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(testContracts, *contracts)
+	r.Equal(1, first.calls)
+	r.Equal(1, second.calls)
+}
+
+func TestENSFailoverAllFail(t *testing.T) {
+	r := require.New(t)
+
+	lastErr := errors.New("bad gateway")
+	store := newFailoverENSStore(context.Background(), testDeadline(time.Second), testENSAddress1,
+		ensEndpoint{url: "http://first", store: &fakeENSStore{err: errors.New("rate limited")}},
+		ensEndpoint{url: "http://second", store: &fakeENSStore{err: lastErr}},
+	)
+
+	failures := testutil.ToFloat64(ensResolutionFailures.WithLabelValues(ResolutionStageCall))
+	_, err := store.ResolveRegistryContracts()
+	r.ErrorIs(err, lastErr)
+	r.Equal(failures+2, testutil.ToFloat64(ensResolutionFailures.WithLabelValues(ResolutionStageCall)))
+}
+
+func TestENSFailoverEndpointTimeout(t *testing.T) {
+	r := require.New(t)
+
+	hanging := &hangingENSStore{release: make(chan struct{})}
+	defer close(hanging.release)
+	store := newFailoverENSStore(context.Background(), testDeadline(time.Millisecond*50), testENSAddress1,
+		ensEndpoint{url: "http://hanging", store: hanging},
+		ensEndpoint{url: "http://second", store: &fakeENSStore{contracts: &testContracts}},
+	)
+
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(testContracts, *contracts)
+
+	store = newFailoverENSStore(context.Background(), testDeadline(time.Millisecond*50), testENSAddress1,
+		ensEndpoint{url: "http://hanging", store: hanging},
+	)
+	_, err = store.ResolveRegistryContracts()
+	r.ErrorIs(err, errENSEndpointTimeout)
+	r.Contains(err.Error(), "http://hanging")
+}
+
+func TestENSFailoverContextCancel(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hanging := &hangingENSStore{release: make(chan struct{})}
+	defer close(hanging.release)
+	second := &fakeENSStore{contracts: &testContracts}
+	store := newFailoverENSStore(ctx, testDeadline(time.Hour), testENSAddress1,
+		ensEndpoint{url: "http://hanging", store: hanging},
+		ensEndpoint{url: "http://second", store: second},
+	)
+
+	time.AfterFunc(time.Millisecond*50, cancel)
+	begin := time.Now()
+	_, err := store.ResolveRegistryContracts()
+	r.ErrorIs(err, context.Canceled)
+	r.Contains(err.Error(), "http://hanging")
+	r.Less(int64(time.Since(begin)), int64(time.Second))
+	r.Equal(0, second.calls)
+}
@@ -0,0 +1,26 @@
+package store
+
+import (
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+	log "github.com/sirupsen/logrus"
+)
+
+// lenientENSStore ignores the contract resolution errors so that the node can
+// start without the contracts during offline development.
+type lenientENSStore struct {
+	ens.ENS
+}
+
+func newLenientENSStore(ensStore ens.ENS) *lenientENSStore {
+	return &lenientENSStore{ENS: ensStore}
+}
+
+func (store *lenientENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	contracts, err := store.ENS.ResolveRegistryContracts()
+	if err != nil {
+		log.WithError(err).Warn("failed to resolve the contracts - continuing without them as allowed")
+		return &registry.RegistryContracts{}, nil
+	}
+	return contracts, nil
+}
@@ -0,0 +1,48 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/forta-network/forta-core-go/domain/registry"
+	registryclient "github.com/forta-network/forta-core-go/registry"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+func TestUnresolvedContractsAreFatal(t *testing.T) {
+	r := require.New(t)
+
+	var cfg config.Config
+	cfg.ENSConfig.EndpointTimeoutSeconds = 1
+	cfg.ENSConfig.ResolveTimeoutSeconds = 1
+	ensStore, err := GetENSStore(context.Background(), cfg, registryclient.ClientConfig{
+		JsonRpcUrl: "http://127.0.0.1:1",
+	})
+	r.NoError(err)
+
+	_, err = ensStore.ResolveRegistryContracts()
+	r.Error(err)
+}
+
+func TestUnresolvedContractsAllowed(t *testing.T) {
+	r := require.New(t)
+
+	store := newLenientENSStore(&fakeENSStore{err: errors.New("rpc is down")})
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(registry.RegistryContracts{}, *contracts)
+
+	var cfg config.Config
+	cfg.ENSConfig.AllowUnresolved = true
+	cfg.ENSConfig.EndpointTimeoutSeconds = 1
+	cfg.ENSConfig.ResolveTimeoutSeconds = 1
+	ensStore, err := GetENSStore(context.Background(), cfg, registryclient.ClientConfig{
+		JsonRpcUrl: "http://127.0.0.1:1",
+	})
+	r.NoError(err)
+	_, err = ensStore.ResolveRegistryContracts()
+	r.NoError(err)
+}
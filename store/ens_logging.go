@@ -0,0 +1,52 @@
+package store
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// loggingENSStore logs the contracts after they are resolved for the first time so that
+// every container which resolves them shows which addresses it is using.
+type loggingENSStore struct {
+	ens.ENS
+	endpoints []string
+	logged    bool
+	mu        sync.Mutex
+}
+
+func newLoggingENSStore(ensStore ens.ENS, endpoints ...string) *loggingENSStore {
+	return &loggingENSStore{ENS: ensStore, endpoints: endpoints}
+}
+
+func (store *loggingENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	contracts, err := store.ENS.ResolveRegistryContracts()
+	if err != nil {
+		return nil, err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	if store.logged {
+		return contracts, nil
+	}
+	store.logged = true
+	fields := log.Fields{
+		"dispatch":       contracts.Dispatch.Hex(),
+		"scannerVersion": contracts.ScannerNodeVersion.Hex(),
+		"agentRegistry":  contracts.AgentRegistry.Hex(),
+	}
+	if len(store.endpoints) > 0 {
+		redacted := make([]string, len(store.endpoints))
+		for i, endpoint := range store.endpoints {
+			redacted[i] = config.RedactURL(endpoint)
+		}
+		fields["endpoints"] = strings.Join(redacted, ", ")
+	}
+	log.WithFields(fields).Info("resolved the registry contracts")
+	return contracts, nil
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+	log "github.com/sirupsen/logrus"
+)
+
+// contractConfigNames maps the optional contract names in the config to the ENS names.
+var contractConfigNames = map[string]string{
+	"agentRegistry":      ens.AgentRegistryContract,
+	"scannerRegistry":    ens.ScannerRegistryContract,
+	"scannerNodeVersion": ens.ScannerNodeVersionContract,
+	"fortaStaking":       ens.StakingContract,
+	"forta":              ens.FortaContract,
+}
+
+// ContractResult is the outcome of resolving a registry contract by its ENS name.
+type ContractResult struct {
+	Name    string
+	Address common.Address
+	Err     error
+}
+
+// ResolveContracts resolves each of the registry contracts independently so that a failing
+// contract does not prevent resolving the others.
+func ResolveContracts(ensStore ens.ENS) (registry.RegistryContracts, []ContractResult) {
+	var contracts registry.RegistryContracts
+	var results []ContractResult
+	for name, field := range registryContractFields(&contracts) {
+		address, err := ensStore.Resolve(name)
+		if err == nil {
+			*field = address
+		}
+		results = append(results, ContractResult{Name: name, Address: address, Err: err})
+	}
+	return contracts, results
+}
+
+// optionalContractsENSStore resolves the contracts one by one if resolving all of them
+// fails and continues without the optional contracts which cannot be resolved.
+type optionalContractsENSStore struct {
+	ens.ENS
+	optional map[string]bool
+}
+
+func newOptionalContractsENSStore(ensStore ens.ENS, optionalContracts []string) *optionalContractsENSStore {
+	optional := make(map[string]bool)
+	for _, configName := range optionalContracts {
+		optional[contractConfigNames[configName]] = true
+	}
+	return &optionalContractsENSStore{ENS: ensStore, optional: optional}
+}
+
+func (store *optionalContractsENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	contracts, err := store.ENS.ResolveRegistryContracts()
+	if err == nil {
+		return contracts, nil
+	}
+	log.WithError(err).Warn("failed to resolve the contracts - resolving one by one")
+
+	resolved, results := ResolveContracts(store.ENS)
+	for _, result := range results {
+		if result.Err == nil {
+			continue
+		}
+		if !store.optional[result.Name] {
+			return nil, result.Err
+		}
+		log.WithError(result.Err).WithField("contract", result.Name).Warn("continuing without the optional contract")
+	}
+	return &resolved, nil
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+	"github.com/stretchr/testify/require"
+)
+
+// namedENSStore resolves the names from the map and fails for the rest.
+type namedENSStore struct {
+	addresses map[string]common.Address
+}
+
+func (store *namedENSStore) Resolve(input string) (common.Address, error) {
+	address, ok := store.addresses[input]
+	if !ok {
+		return common.Address{}, errors.New("failed to resolve " + input)
+	}
+	return address, nil
+}
+
+func (store *namedENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	contracts, results := ResolveContracts(store)
+	for _, result := range results {
+		if result.Err != nil {
+			return nil, result.Err
+		}
+	}
+	return &contracts, nil
+}
+
+func allContractsExcept(names ...string) map[string]common.Address {
+	addresses := map[string]common.Address{
+		ens.DispatchContract:           common.HexToAddress("0x1"),
+		ens.AgentRegistryContract:      common.HexToAddress("0x2"),
+		ens.ScannerRegistryContract:    common.HexToAddress("0x3"),
+		ens.ScannerNodeVersionContract: common.HexToAddress("0x4"),
+		ens.StakingContract:            common.HexToAddress("0x5"),
+		ens.FortaContract:              common.HexToAddress("0x6"),
+	}
+	for _, name := range names {
+		delete(addresses, name)
+	}
+	return addresses
+}
+
+func TestResolveContracts(t *testing.T) {
+	r := require.New(t)
+
+	contracts, results := ResolveContracts(&namedENSStore{addresses: allContractsExcept(ens.ScannerNodeVersionContract)})
+	r.Len(results, 6)
+	for _, result := range results {
+		if result.Name == ens.ScannerNodeVersionContract {
+			r.Error(result.Err)
+			continue
+		}
+		r.NoError(result.Err)
+	}
+	r.Equal(common.HexToAddress("0x1"), contracts.Dispatch)
+	r.Equal(common.Address{}, contracts.ScannerNodeVersion)
+}
+
+func TestOptionalContractFails(t *testing.T) {
+	r := require.New(t)
+
+	ensStore := &namedENSStore{addresses: allContractsExcept(ens.ScannerNodeVersionContract)}
+	_, err := ensStore.ResolveRegistryContracts()
+	r.Error(err)
+
+	store := newOptionalContractsENSStore(ensStore, []string{"scannerNodeVersion"})
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(common.HexToAddress("0x1"), contracts.Dispatch)
+	r.Equal(common.HexToAddress("0x3"), contracts.ScannerRegistry)
+	r.Equal(common.Address{}, contracts.ScannerNodeVersion)
+}
+
+func TestRequiredContractFails(t *testing.T) {
+	r := require.New(t)
+
+	ensStore := &namedENSStore{addresses: allContractsExcept(ens.ScannerNodeVersionContract, ens.DispatchContract)}
+	store := newOptionalContractsENSStore(ensStore, []string{"scannerNodeVersion"})
+	_, err := store.ResolveRegistryContracts()
+	r.Error(err)
+	r.Contains(err.Error(), ens.DispatchContract)
+}
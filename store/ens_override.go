@@ -25,6 +25,11 @@ func NewENSOverrideStore(cfg config.Config) (*ensOverrideStore, error) {
 	if err := json.Unmarshal(b, &store.contractsMap); err != nil {
 		return nil, err
 	}
+	for name, address := range store.contractsMap {
+		if _, err := parseContractAddress(name, address); err != nil {
+			return nil, err
+		}
+	}
 	store.contracts.Dispatch = common.HexToAddress(store.contractsMap[ens.DispatchContract])
 	store.contracts.AgentRegistry = common.HexToAddress(store.contractsMap[ens.AgentRegistryContract])
 	store.contracts.ScannerRegistry = common.HexToAddress(store.contractsMap[ens.ScannerRegistryContract])
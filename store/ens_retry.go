@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+	log "github.com/sirupsen/logrus"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// retryingENSStore retries the contract resolution with exponential backoff.
+type retryingENSStore struct {
+	ens.ENS
+	ctx          context.Context
+	maxRetries   int
+	baseInterval time.Duration
+	maxInterval  time.Duration
+}
+
+// NewRetryingENSStore wraps the ENS store so that the contract resolution is retried
+// as configured until the context is done.
+func NewRetryingENSStore(ctx context.Context, cfg config.Config, ensStore ens.ENS) ens.ENS {
+	retryCfg := cfg.ENSConfig.Retry
+	var maxRetries int
+	if retryCfg.MaxRetries != nil {
+		maxRetries = *retryCfg.MaxRetries
+	}
+	return &retryingENSStore{
+		ENS:          ensStore,
+		ctx:          ctx,
+		maxRetries:   maxRetries,
+		baseInterval: time.Duration(retryCfg.BaseIntervalSeconds) * time.Second,
+		maxInterval:  time.Duration(retryCfg.MaxIntervalSeconds) * time.Second,
+	}
+}
+
+// ResolveRegistryContracts resolves the contracts and retries if it fails. If the context is
+// done while waiting to retry, the context error is returned together with the last failure.
+//
+// Resolve is not retried: it resolves the contracts one by one after this has already used
+// up the retries, e.g. to skip the optional contracts, and the names which do not exist
+// would only be retried until the resolve deadline.
+func (store *retryingENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	contracts, err := store.ENS.ResolveRegistryContracts()
+	delay := store.baseInterval
+	for retry := 1; err != nil && retry <= store.maxRetries; retry++ {
+		log.WithError(err).WithFields(log.Fields{
+			"retry": retry,
+			"delay": delay.String(),
+		}).Warn("failed to resolve the contracts - retrying")
+		select {
+		case <-time.After(delay):
+		case <-store.ctx.Done():
+			return nil, fmt.Errorf("%w (last error: %v)", store.ctx.Err(), err)
+		}
+		contracts, err = store.ENS.ResolveRegistryContracts()
+		delay *= 2
+		if store.maxInterval > 0 && delay > store.maxInterval {
+			delay = store.maxInterval
+		}
+	}
+	return contracts, err
+}
@@ -0,0 +1,75 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+func testRetryConfig(maxRetries, baseIntervalSeconds int) config.Config {
+	var cfg config.Config
+	cfg.ENSConfig.Retry = config.ENSRetryConfig{
+		MaxRetries:          &maxRetries,
+		BaseIntervalSeconds: baseIntervalSeconds,
+	}
+	return cfg
+}
+
+func TestENSRetrySucceeds(t *testing.T) {
+	r := require.New(t)
+
+	fake := &fakeENSStore{contracts: &testContracts, err: errors.New("rate limited"), failures: 2}
+	store := NewRetryingENSStore(context.Background(), testRetryConfig(3, 0), fake)
+
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(testContracts, *contracts)
+	r.Equal(3, fake.calls)
+}
+
+func TestENSRetryGivesUp(t *testing.T) {
+	r := require.New(t)
+
+	resolveErr := errors.New("rate limited")
+	fake := &fakeENSStore{contracts: &testContracts, err: resolveErr, failures: 5}
+	store := NewRetryingENSStore(context.Background(), testRetryConfig(2, 0), fake)
+
+	_, err := store.ResolveRegistryContracts()
+	r.ErrorIs(err, resolveErr)
+	r.Equal(3, fake.calls)
+}
+
+func TestENSRetryDisabled(t *testing.T) {
+	r := require.New(t)
+
+	resolveErr := errors.New("rate limited")
+	fake := &fakeENSStore{contracts: &testContracts, err: resolveErr, failures: 1}
+	store := NewRetryingENSStore(context.Background(), testRetryConfig(0, 0), fake)
+
+	_, err := store.ResolveRegistryContracts()
+	r.ErrorIs(err, resolveErr)
+	r.Equal(1, fake.calls)
+}
+
+func TestENSRetryRespectsContext(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*50)
+	defer cancel()
+
+	resolveErr := errors.New("rate limited")
+	fake := &fakeENSStore{err: resolveErr}
+	store := NewRetryingENSStore(ctx, testRetryConfig(10, 60), fake)
+
+	begin := time.Now()
+	_, err := store.ResolveRegistryContracts()
+	r.ErrorIs(err, context.DeadlineExceeded)
+	r.Contains(err.Error(), resolveErr.Error())
+	r.Less(int64(time.Since(begin)), int64(time.Second))
+	r.Equal(1, fake.calls)
+}
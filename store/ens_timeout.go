@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+// deadlineFunc makes the context of a call with the deadline of the call.
+type deadlineFunc func(ctx context.Context) (context.Context, context.CancelFunc)
+
+// resolveDeadline makes the deadline of the whole contract resolution from the config.
+func resolveDeadline(cfg config.Config) deadlineFunc {
+	return func(ctx context.Context) (context.Context, context.CancelFunc) {
+		return config.WithResolveTimeout(ctx, cfg)
+	}
+}
+
+// endpointDeadline makes the deadline of a single ENS endpoint call from the config.
+func endpointDeadline(cfg config.Config) deadlineFunc {
+	return func(ctx context.Context) (context.Context, context.CancelFunc) {
+		return config.WithENSEndpointTimeout(ctx, cfg)
+	}
+}
+
+// timeoutOf returns the timeout of the context which was made at the beginning of the call.
+func timeoutOf(ctx context.Context, begin time.Time) time.Duration {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return time.Since(begin).Round(time.Millisecond)
+	}
+	return deadline.Sub(begin).Round(time.Millisecond)
+}
+
+// timeoutENSStore stops the contract resolution after the timeout or when the context is done.
+// The store for each call is made with the context of the call so that the retries and the
+// failover stop with it instead of running in the background.
+type timeoutENSStore struct {
+	ctx      context.Context
+	deadline deadlineFunc
+	urls     []string
+	newStore func(ctx context.Context) ens.ENS
+}
+
+func newTimeoutENSStore(
+	ctx context.Context, deadline deadlineFunc, urls []string, newStore func(ctx context.Context) ens.ENS,
+) *timeoutENSStore {
+	return &timeoutENSStore{ctx: ctx, deadline: deadline, urls: urls, newStore: newStore}
+}
+
+func (store *timeoutENSStore) Resolve(input string) (common.Address, error) {
+	result, err := store.call(func(ensStore ens.ENS) (interface{}, error) {
+		return ensStore.Resolve(input)
+	})
+	if err != nil {
+		return common.Address{}, err
+	}
+	return result.(common.Address), nil
+}
+
+func (store *timeoutENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	result, err := store.call(func(ensStore ens.ENS) (interface{}, error) {
+		return ensStore.ResolveRegistryContracts()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(*registry.RegistryContracts), nil
+}
+
+func (store *timeoutENSStore) call(call func(ensStore ens.ENS) (interface{}, error)) (interface{}, error) {
+	begin := time.Now()
+	ctx, cancel := store.deadline(store.ctx)
+	defer cancel()
+
+	ensStore := store.newStore(ctx)
+	resultCh := make(chan ensResult, 1)
+	go func() {
+		value, err := call(ensStore)
+		resultCh <- ensResult{value: value, err: err}
+	}()
+	select {
+	case result := <-resultCh:
+		return result.value, result.err
+	case <-ctx.Done():
+		if store.ctx.Err() != nil {
+			return nil, fmt.Errorf("ens resolution aborted: %w", store.ctx.Err())
+		}
+		return nil, fmt.Errorf("%w after %s (endpoints: %s)", errENSEndpointTimeout, timeoutOf(ctx, begin), strings.Join(store.urls, ", "))
+	}
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+	"github.com/stretchr/testify/require"
+)
+
+func TestENSResolveTimeout(t *testing.T) {
+	r := require.New(t)
+
+	hanging := &hangingENSStore{release: make(chan struct{})}
+	defer close(hanging.release)
+	var callCtx context.Context
+	store := newTimeoutENSStore(context.Background(), testDeadline(time.Millisecond*50), []string{"http://first", "http://second"},
+		func(ctx context.Context) ens.ENS {
+			callCtx = ctx
+			return hanging
+		},
+	)
+
+	_, err := store.ResolveRegistryContracts()
+	r.ErrorIs(err, errENSEndpointTimeout)
+	r.EqualError(err, "ens resolution timed out after 50ms (endpoints: http://first, http://second)")
+	// the store of the call is stopped with the timeout
+	r.ErrorIs(callCtx.Err(), context.DeadlineExceeded)
+}
+
+func testDeadline(timeout time.Duration) deadlineFunc {
+	return func(ctx context.Context) (context.Context, context.CancelFunc) {
+		return context.WithTimeout(ctx, timeout)
+	}
+}
+
+// returnSignalingENSStore closes the channel when resolving the contracts returns.
+type returnSignalingENSStore struct {
+	ens.ENS
+	returned chan struct{}
+}
+
+func (store *returnSignalingENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	defer close(store.returned)
+	return store.ENS.ResolveRegistryContracts()
+}
+
+func TestENSResolveTimeoutStopsRetries(t *testing.T) {
+	r := require.New(t)
+
+	cfg := testRetryConfig(1000, 1)
+	dialed := newFailoverENSStore(context.Background(), testDeadline(time.Hour), testENSAddress1,
+		ensEndpoint{url: "http://first", store: &fakeENSStore{err: errors.New("bad gateway")}},
+	)
+	returned := make(chan struct{})
+	store := newTimeoutENSStore(context.Background(), testDeadline(time.Millisecond*50), []string{"http://first"},
+		func(ctx context.Context) ens.ENS {
+			return &returnSignalingENSStore{
+				ENS:      NewRetryingENSStore(ctx, cfg, dialed.withContext(ctx)),
+				returned: returned,
+			}
+		},
+	)
+
+	_, err := store.ResolveRegistryContracts()
+	r.ErrorIs(err, errENSEndpointTimeout)
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		r.FailNow("the retries kept running after the timeout")
+	}
+}
+
+func TestENSResolveContextCancel(t *testing.T) {
+	r := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hanging := &hangingENSStore{release: make(chan struct{})}
+	defer close(hanging.release)
+	store := newTimeoutENSStore(ctx, testDeadline(time.Hour), []string{"http://hanging"}, func(ctx context.Context) ens.ENS {
+		return hanging
+	})
+
+	time.AfterFunc(time.Millisecond*50, cancel)
+	begin := time.Now()
+	_, err := store.ResolveRegistryContracts()
+	r.ErrorIs(err, context.Canceled)
+	r.Less(int64(time.Since(begin)), int64(time.Second))
+}
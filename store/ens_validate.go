@@ -0,0 +1,61 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/domain/registry"
+	"github.com/forta-network/forta-core-go/ens"
+)
+
+// validatingENSStore makes sure that the resolved contracts can be used.
+type validatingENSStore struct {
+	ens.ENS
+	ensAddress string
+}
+
+// NewValidatingENSStore wraps the ENS store so that resolving zero addresses fails.
+func NewValidatingENSStore(ensStore ens.ENS, ensAddress string) *validatingENSStore {
+	return &validatingENSStore{ENS: ensStore, ensAddress: ensAddress}
+}
+
+func (store *validatingENSStore) ResolveRegistryContracts() (*registry.RegistryContracts, error) {
+	contracts, err := store.ENS.ResolveRegistryContracts()
+	if err != nil {
+		return nil, err
+	}
+	if err := validateRegistryContracts(contracts); err != nil {
+		return nil, &ContractResolutionError{
+			ENSAddress: store.ensAddress,
+			Stage:      ResolutionStageDecode,
+			Err:        err,
+		}
+	}
+	return contracts, nil
+}
+
+// validateRegistryContracts checks the addresses of the contracts which the node depends on.
+func validateRegistryContracts(contracts *registry.RegistryContracts) error {
+	for _, contract := range []struct {
+		name    string
+		address common.Address
+	}{
+		{name: ens.DispatchContract, address: contracts.Dispatch},
+		{name: ens.AgentRegistryContract, address: contracts.AgentRegistry},
+		{name: ens.ScannerRegistryContract, address: contracts.ScannerRegistry},
+		{name: ens.ScannerNodeVersionContract, address: contracts.ScannerNodeVersion},
+	} {
+		if contract.address == (common.Address{}) {
+			return fmt.Errorf("invalid address for contract '%s': zero address", contract.name)
+		}
+	}
+	return nil
+}
+
+// parseContractAddress parses a configured contract address.
+func parseContractAddress(name, address string) (common.Address, error) {
+	if !common.IsHexAddress(address) {
+		return common.Address{}, fmt.Errorf("invalid address for contract '%s': malformed address '%s'", name, address)
+	}
+	return common.HexToAddress(address), nil
+}
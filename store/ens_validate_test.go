@@ -0,0 +1,50 @@
+package store
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/forta-network/forta-core-go/ens"
+	"github.com/stretchr/testify/require"
+
+	"github.com/forta-network/forta-node/config"
+)
+
+func TestValidateZeroAddress(t *testing.T) {
+	r := require.New(t)
+
+	contracts := testContracts
+	contracts.ScannerNodeVersion = common.Address{}
+	store := NewValidatingENSStore(&fakeENSStore{contracts: &contracts}, testENSAddress1)
+
+	_, err := store.ResolveRegistryContracts()
+	var resolutionErr *ContractResolutionError
+	r.ErrorAs(err, &resolutionErr)
+	r.Equal(ResolutionStageDecode, resolutionErr.Stage)
+	r.Equal(testENSAddress1, resolutionErr.ENSAddress)
+	r.EqualError(resolutionErr.Err, "invalid address for contract 'scanner-node-version.forta.eth': zero address")
+}
+
+func TestValidateValidAddresses(t *testing.T) {
+	r := require.New(t)
+
+	store := NewValidatingENSStore(&fakeENSStore{contracts: &testContracts}, testENSAddress1)
+	contracts, err := store.ResolveRegistryContracts()
+	r.NoError(err)
+	r.Equal(testContracts, *contracts)
+}
+
+func TestOverrideMalformedAddress(t *testing.T) {
+	r := require.New(t)
+
+	cfg := config.Config{FortaDir: t.TempDir()}
+	r.NoError(ioutil.WriteFile(path.Join(cfg.FortaDir, "ens-override.json"), []byte(`{
+		"dispatch.forta.eth": "0x2222222222222222222222222222222222222222",
+		"agents.registries.forta.eth": "0xnot-an-address"
+	}`), 0644))
+
+	_, err := NewENSOverrideStore(cfg)
+	r.EqualError(err, "invalid address for contract '"+ens.AgentRegistryContract+"': malformed address '0xnot-an-address'")
+}
@@ -11,6 +11,7 @@ import (
 	"github.com/ipfs/go-cid"
 	log "github.com/sirupsen/logrus"
 
+	"github.com/forta-network/forta-core-go/ens"
 	"github.com/forta-network/forta-core-go/ethereum"
 	"github.com/forta-network/forta-core-go/manifest"
 	"github.com/forta-network/forta-core-go/registry"
@@ -22,6 +23,9 @@ var (
 	errInvalidBot = errors.New("invalid bot")
 )
 
+// defaultENSAddress is the ENS contract which is used when the config does not specify one.
+const defaultENSAddress = "0x08f42fcc52a9C2F391bF507C4E8688D0b53e1bd7"
+
 type RegistryStore interface {
 	FindAgentGlobally(agentID string) (*config.AgentConfig, error)
 	GetAgentsIfChanged(scanner string) ([]*config.AgentConfig, bool, error)
@@ -260,12 +264,59 @@ func NewPrivateRegistryStore(ctx context.Context, cfg config.Config) (*privateRe
 
 // GetRegistryClient checks the config and returns the suitaable registry.
 func GetRegistryClient(ctx context.Context, cfg config.Config, registryClientCfg registry.ClientConfig) (registry.Client, error) {
+	ensStore, err := GetENSStore(ctx, cfg, registryClientCfg)
+	if err != nil {
+		return nil, err
+	}
+	return registry.NewClientWithENSStore(ctx, registryClientCfg, ensStore)
+}
+
+// GetENSStore checks the config and returns the suitable ENS store.
+func GetENSStore(ctx context.Context, cfg config.Config, registryClientCfg registry.ClientConfig) (ens.ENS, error) {
 	if cfg.ENSConfig.Override {
 		ensStore, err := NewENSOverrideStore(cfg)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create ens override store: %v", err)
 		}
-		return registry.NewClientWithENSStore(ctx, registryClientCfg, ensStore)
+		return newLoggingENSStore(ensStore), nil
+	}
+	overridingStore, err := newContractsOverridingENSStore(cfg.ENSConfig.Contracts, func() (ens.ENS, error) {
+		return dialENSStore(ctx, cfg, registryClientCfg)
+	})
+	if err != nil {
+		return nil, err
+	}
+	var ensStore ens.ENS = overridingStore
+	if len(cfg.ENSConfig.OptionalContracts) > 0 {
+		ensStore = newOptionalContractsENSStore(ensStore, cfg.ENSConfig.OptionalContracts)
+	}
+	ensStore = newLoggingENSStore(ensStore, ensEndpoints(cfg, registryClientCfg)...)
+	if cfg.ENSConfig.AllowUnresolved {
+		return newLenientENSStore(ensStore), nil
+	}
+	return ensStore, nil
+}
+
+// ensEndpoints returns the endpoints which the ENS store is dialed with.
+func ensEndpoints(cfg config.Config, registryClientCfg registry.ClientConfig) []string {
+	if len(cfg.ENSConfig.JsonRpcUrls) > 0 {
+		return cfg.ENSConfig.JsonRpcUrls
+	}
+	return []string{registryClientCfg.JsonRpcUrl}
+}
+
+func dialENSStore(ctx context.Context, cfg config.Config, registryClientCfg registry.ClientConfig) (ens.ENS, error) {
+	ensAddress := registryClientCfg.ENSAddress
+	if len(ensAddress) == 0 {
+		ensAddress = defaultENSAddress
+	}
+	urls := ensEndpoints(cfg, registryClientCfg)
+	dialed := dialFailoverENSStore(ctx, endpointDeadline(cfg), cfg.ENSConfig.ChainID, ensAddress, urls...)
+	var ensStore ens.ENS = newTimeoutENSStore(ctx, resolveDeadline(cfg), urls, func(ctx context.Context) ens.ENS {
+		return NewRetryingENSStore(ctx, cfg, NewValidatingENSStore(dialed.withContext(ctx), ensAddress))
+	})
+	if len(cfg.FortaDir) == 0 {
+		return ensStore, nil
 	}
-	return registry.NewClient(ctx, registryClientCfg)
+	return NewCachingENSStore(cfg, ensStore, ensAddress), nil
 }